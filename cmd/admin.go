@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+
+	"github.com/anyproto/any-sync/app"
+
+	"github.com/anyproto/any-sync-filenode/account"
+	"github.com/anyproto/any-sync-filenode/config"
+	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/redisprovider"
+)
+
+// startAdminApp starts the minimal set of components needed by admin commands
+func startAdminApp(ctx context.Context) (a *app.App, err error) {
+	conf, err := config.Load(*flagConfigFile, flagOverrides)
+	if err != nil {
+		return
+	}
+	a = new(app.App)
+	a.Register(conf).
+		Register(account.New()).
+		Register(store()).
+		Register(redisprovider.New()).
+		Register(index.NewAdmin())
+	if err = a.Start(ctx); err != nil {
+		return nil, err
+	}
+	return
+}
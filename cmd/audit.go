@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anyproto/any-sync/app"
+
+	"github.com/anyproto/any-sync-filenode/index"
+)
+
+// auditCommand handles "audit [-group id] [-space id] [-from time] [-to time]" that prints recorded index mutations as csv
+func auditCommand(args []string) (err error) {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	groupId := fs.String("group", "", "group id")
+	spaceId := fs.String("space", "", "space id")
+	from := fs.String("from", "", "start of the time range, RFC3339 or date")
+	to := fs.String("to", "", "end of the time range, RFC3339 or date")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: audit [-group groupId] [-space spaceId] [-from time] [-to time]")
+	}
+	filter := index.AuditFilter{GroupId: *groupId, SpaceId: *spaceId}
+	if filter.From, err = parseAuditTime(*from); err != nil {
+		return
+	}
+	if filter.To, err = parseAuditTime(*to); err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	a, err := startAdminApp(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = a.Close(ctx)
+	}()
+	idx := app.MustComponent[index.Index](a)
+
+	entries, err := idx.AuditQuery(ctx, filter)
+	if err != nil {
+		return
+	}
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"time", "op", "identity", "groupId", "spaceId", "fileIds", "spaceSizeDelta", "groupSizeDelta", "limit", "status", "sourceSpaceId"})
+	for _, e := range entries {
+		var status string
+		if e.Op == index.AuditOpSpaceSetStatus {
+			status = e.Status.String()
+		}
+		if err = w.Write([]string{
+			e.Time.UTC().Format(time.RFC3339Nano),
+			e.Op,
+			e.Identity,
+			e.GroupId,
+			e.SpaceId,
+			strings.Join(e.FileIds, " "),
+			strconv.FormatInt(e.SpaceSizeDelta, 10),
+			strconv.FormatInt(e.GroupSizeDelta, 10),
+			strconv.FormatUint(e.Limit, 10),
+			status,
+			e.SourceSpaceId,
+		}); err != nil {
+			return
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func parseAuditTime(s string) (t time.Time, err error) {
+	if s == "" {
+		return
+	}
+	if t, err = time.Parse(time.RFC3339, s); err == nil {
+		return
+	}
+	if t, err = time.Parse(time.DateOnly, s); err != nil {
+		return t, fmt.Errorf("invalid time %q: must be RFC3339 or date", s)
+	}
+	return
+}
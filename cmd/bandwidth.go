@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/anyproto/any-sync/app"
+
+	"github.com/anyproto/any-sync-filenode/index"
+)
+
+// bandwidthCommand handles "bandwidth query <groupId> [spaceId]" and "bandwidth export" that print hourly traffic as csv
+func bandwidthCommand(args []string) (err error) {
+	if len(args) == 0 || (args[0] != "query" && args[0] != "export") {
+		return fmt.Errorf("unknown bandwidth command, available: query [-days N] <groupId> [spaceId], export [-days N]")
+	}
+	fs := flag.NewFlagSet("bandwidth "+args[0], flag.ContinueOnError)
+	days := fs.Int("days", 7, "count of days to show")
+	if err = fs.Parse(args[1:]); err != nil {
+		return
+	}
+	if args[0] == "query" && (fs.NArg() == 0 || fs.NArg() > 2) {
+		return fmt.Errorf("usage: bandwidth query [-days N] <groupId> [spaceId]")
+	}
+
+	ctx := context.Background()
+	a, err := startAdminApp(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = a.Close(ctx)
+	}()
+	idx := app.MustComponent[index.Index](a)
+
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"time", "groupId", "spaceId", "uploaded", "downloaded"})
+	write := func(b index.BandwidthBucket) error {
+		return w.Write([]string{
+			b.Time.Format(time.RFC3339),
+			b.GroupId,
+			b.SpaceId,
+			strconv.FormatUint(b.Uploaded, 10),
+			strconv.FormatUint(b.Downloaded, 10),
+		})
+	}
+	if args[0] == "export" {
+		err = idx.BandwidthExport(ctx, *days, write)
+	} else {
+		var buckets []index.BandwidthBucket
+		if buckets, err = idx.BandwidthHistory(ctx, index.Key{GroupId: fs.Arg(0), SpaceId: fs.Arg(1)}, *days); err != nil {
+			return
+		}
+		for _, b := range buckets {
+			if err = write(b); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return
+	}
+	w.Flush()
+	return w.Error()
+}
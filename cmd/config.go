@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/anyproto/any-sync-filenode/config"
+)
+
+type overrideFlags []string
+
+func (o *overrideFlags) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *overrideFlags) Set(val string) error {
+	*o = append(*o, val)
+	return nil
+}
+
+// configCommand handles "config print" that shows the effective config with redacted secrets
+func configCommand(args []string) (err error) {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("unknown config command, available: print")
+	}
+	conf, err := config.Read(*flagConfigFile, flagOverrides)
+	if err != nil {
+		return
+	}
+	data, err := yaml.Marshal(conf.Redacted())
+	if err != nil {
+		return
+	}
+	fmt.Print(string(data))
+	if vErr := conf.Validate(); vErr != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "invalid config:")
+		_, _ = fmt.Fprintln(os.Stderr, vErr)
+		os.Exit(1)
+	}
+	return
+}
@@ -31,8 +31,14 @@ import (
 	"github.com/anyproto/any-sync-filenode/account"
 	"github.com/anyproto/any-sync-filenode/config"
 	"github.com/anyproto/any-sync-filenode/deletelog"
+	"github.com/anyproto/any-sync-filenode/events"
 	"github.com/anyproto/any-sync-filenode/filenode"
+	"github.com/anyproto/any-sync-filenode/gateway"
+	"github.com/anyproto/any-sync-filenode/health"
 	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/metricserver"
+	"github.com/anyproto/any-sync-filenode/notifier"
+	"github.com/anyproto/any-sync-filenode/ratelimit"
 	"github.com/anyproto/any-sync-filenode/redisprovider"
 
 	// import this to keep govvv in go.mod on mod tidy
@@ -45,8 +51,13 @@ var (
 	flagConfigFile = flag.String("c", "etc/any-sync-filenode.yml", "path to config file")
 	flagVersion    = flag.Bool("v", false, "show version and exit")
 	flagHelp       = flag.Bool("h", false, "show help and exit")
+	flagOverrides  overrideFlags
 )
 
+func init() {
+	flag.Var(&flagOverrides, "o", "override config value, e.g. -o s3Store.bucket=files (can be repeated)")
+}
+
 func main() {
 	flag.Parse()
 
@@ -60,6 +71,36 @@ func main() {
 		flag.PrintDefaults()
 		return
 	}
+	if flag.Arg(0) == "config" {
+		if err := configCommand(flag.Args()[1:]); err != nil {
+			log.Fatal("config command error", zap.Error(err))
+		}
+		return
+	}
+	if flag.Arg(0) == "bandwidth" {
+		if err := bandwidthCommand(flag.Args()[1:]); err != nil {
+			log.Fatal("bandwidth command error", zap.Error(err))
+		}
+		return
+	}
+	if flag.Arg(0) == "usage" {
+		if err := usageCommand(flag.Args()[1:]); err != nil {
+			log.Fatal("usage command error", zap.Error(err))
+		}
+		return
+	}
+	if flag.Arg(0) == "audit" {
+		if err := auditCommand(flag.Args()[1:]); err != nil {
+			log.Fatal("audit command error", zap.Error(err))
+		}
+		return
+	}
+	if flag.Arg(0) == "space" {
+		if err := spaceCommand(flag.Args()[1:]); err != nil {
+			log.Fatal("space command error", zap.Error(err))
+		}
+		return
+	}
 
 	if debug, ok := os.LookupEnv("ANYPROF"); ok && debug != "" {
 		go func() {
@@ -71,10 +112,10 @@ func main() {
 	ctx := context.Background()
 	a := new(app.App)
 
-	// open config file
-	conf, err := config.NewFromFile(*flagConfigFile)
+	// load config file, env and flag overrides
+	conf, err := config.Load(*flagConfigFile, flagOverrides)
 	if err != nil {
-		log.Fatal("can't open config file", zap.Error(err))
+		log.Fatal("can't load config", zap.Error(err))
 	}
 
 	// bootstrap components
@@ -105,8 +146,10 @@ func main() {
 }
 
 func Bootstrap(a *app.App) {
-	a.Register(account.New()).
+	a.Register(config.NewReloader()).
+		Register(account.New()).
 		Register(metric.New()).
+		Register(metricserver.New()).
 		Register(nodeconfsource.New()).
 		Register(nodeconfstore.New()).
 		Register(nodeconf.New()).
@@ -118,10 +161,15 @@ func Bootstrap(a *app.App) {
 		Register(acl.New()).
 		Register(store()).
 		Register(redisprovider.New()).
+		Register(notifier.New()).
 		Register(index.New()).
+		Register(events.New()).
 		Register(server.New()).
+		Register(ratelimit.New()).
 		Register(filenode.New()).
+		Register(gateway.New()).
 		Register(deletelog.New()).
 		Register(yamux.New()).
-		Register(quic.New())
+		Register(quic.New()).
+		Register(health.New())
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anyproto/any-sync/app"
+
+	"github.com/anyproto/any-sync-filenode/index"
+)
+
+const spaceCommandUsage = "available: restore <spaceId>, transfer <spaceId> <fromGroupId> <toGroupId>"
+
+// spaceCommand handles "space restore <spaceId>" that returns a soft deleted space back to its group
+// and "space transfer <spaceId> <fromGroupId> <toGroupId>" that moves the space to another group
+func spaceCommand(args []string) (err error) {
+	if len(args) == 0 || !(args[0] == "restore" && len(args) == 2 || args[0] == "transfer" && len(args) == 4) {
+		return fmt.Errorf("unknown space command, %s", spaceCommandUsage)
+	}
+	ctx := context.Background()
+	a, err := startAdminApp(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = a.Close(ctx)
+	}()
+	idx := app.MustComponent[index.Index](a)
+	if args[0] == "transfer" {
+		ok, err := idx.SpaceTransfer(ctx, index.Key{GroupId: args[2], SpaceId: args[1]}, args[3])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("space %s not found in group %s", args[1], args[2])
+		}
+		fmt.Printf("space %s transferred to group %s\n", args[1], args[3])
+		return nil
+	}
+	key, err := idx.SpaceRestore(ctx, args[1])
+	if err != nil {
+		return
+	}
+	fmt.Printf("space %s restored to group %s\n", key.SpaceId, key.GroupId)
+	return
+}
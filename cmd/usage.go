@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/anyproto/any-sync/app"
+
+	"github.com/anyproto/any-sync-filenode/index"
+)
+
+// usageCommand handles "usage <groupId> [spaceId]" that prints daily usage snapshots as csv
+func usageCommand(args []string) (err error) {
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	days := fs.Int("days", 30, "count of days to show")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() == 0 || fs.NArg() > 2 {
+		return fmt.Errorf("usage: usage [-days N] <groupId> [spaceId]")
+	}
+
+	ctx := context.Background()
+	a, err := startAdminApp(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = a.Close(ctx)
+	}()
+	idx := app.MustComponent[index.Index](a)
+
+	snapshots, err := idx.UsageHistory(ctx, index.Key{GroupId: fs.Arg(0), SpaceId: fs.Arg(1)}, *days)
+	if err != nil {
+		return
+	}
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"day", "groupId", "spaceId", "size", "cidCount", "fileCount"})
+	for _, s := range snapshots {
+		if err = w.Write([]string{
+			s.Time.Format(time.DateOnly),
+			s.GroupId,
+			s.SpaceId,
+			strconv.FormatUint(s.Size, 10),
+			strconv.FormatUint(s.CidCount, 10),
+			strconv.FormatUint(uint64(s.FileCount), 10),
+		}); err != nil {
+			return
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,6 @@
+package config
+
+type Audit struct {
+	// FlushPeriodSec is a period of moving audit entries from redis to the index bucket, 1 minute by default
+	FlushPeriodSec int `yaml:"flushPeriodSec"`
+}
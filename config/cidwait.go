@@ -0,0 +1,10 @@
+package config
+
+type CidWait struct {
+	// MaxWaitSec limits the time a client waits for the block on the server side, 5 minutes by default
+	MaxWaitSec int `yaml:"maxWaitSec"`
+	// MaxWaiters is a max count of clients waiting for blocks on the node, 10000 by default
+	MaxWaiters int `yaml:"maxWaiters"`
+	// RecheckSec is a period of checking the block existence while waiting in case the notification was missed, 5 seconds by default
+	RecheckSec int `yaml:"recheckSec"`
+}
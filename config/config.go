@@ -12,6 +12,10 @@ import (
 	"github.com/anyproto/any-sync/nodeconf"
 	"gopkg.in/yaml.v3"
 
+	"github.com/anyproto/any-sync-filenode/events"
+	"github.com/anyproto/any-sync-filenode/health"
+	"github.com/anyproto/any-sync-filenode/notifier"
+	"github.com/anyproto/any-sync-filenode/ratelimit"
 	"github.com/anyproto/any-sync-filenode/redisprovider"
 	"github.com/anyproto/any-sync-filenode/store/s3store"
 )
@@ -45,6 +49,25 @@ type Config struct {
 	CafeMigrateKey           string                 `yaml:"cafeMigrateKey"`
 	DefaultLimit             uint64                 `yaml:"defaultLimit"`
 	PersistTtl               uint                   `yaml:"persistTtl"`
+	PersistCompression       string                 `yaml:"persistCompression"`
+	UsageHistoryDays         int                    `yaml:"usageHistoryDays"`
+	BandwidthHistoryDays     int                    `yaml:"bandwidthHistoryDays"`
+	QuotaWarnThresholds      []uint32               `yaml:"quotaWarnThresholds"`
+	QuotaReservationTtlSec   int                    `yaml:"quotaReservationTtlSec"`
+	Health                   health.Config          `yaml:"health"`
+	DeletionLog              DeletionLog            `yaml:"deletionLog"`
+	UploadJournal            UploadJournal          `yaml:"uploadJournal"`
+	CidWait                  CidWait                `yaml:"cidWait"`
+	Audit                    Audit                  `yaml:"audit"`
+	ReloadIntervalSec        int                    `yaml:"reloadIntervalSec"`
+	RateLimit                ratelimit.Config       `yaml:"rateLimit"`
+	Notifier                 notifier.Config        `yaml:"notifier"`
+	Events                   events.Config          `yaml:"events"`
+	Gateway                  Gateway                `yaml:"gateway"`
+
+	// source and overrides are used to read the config again on reload
+	source    string
+	overrides []string
 }
 
 func (c *Config) Init(a *app.App) (err error) {
@@ -71,14 +94,44 @@ func (c *Config) GetDrpc() rpc.Config {
 	return c.Drpc
 }
 
+// GetMetric returns the config of the common metric component without the address,
+// metrics are served by the metric server, so the address can be changed on reload
 func (c *Config) GetMetric() metric.Config {
-	return c.Metric
+	return metric.Config{}
+}
+
+func (c *Config) GetMetricAddr() string {
+	return c.Metric.Addr
 }
 
 func (c *Config) GetRedis() redisprovider.Config {
 	return c.Redis
 }
 
+func (c *Config) GetDeletionLog() DeletionLog {
+	return c.DeletionLog
+}
+
+func (c *Config) GetHealth() health.Config {
+	return c.Health
+}
+
+func (c *Config) GetRateLimit() ratelimit.Config {
+	return c.RateLimit
+}
+
+func (c *Config) GetNotifier() notifier.Config {
+	return c.Notifier
+}
+
+func (c *Config) GetEvents() events.Config {
+	return c.Events
+}
+
+func (c *Config) GetGateway() Gateway {
+	return c.Gateway
+}
+
 func (c *Config) GetNodeConf() nodeconf.Configuration {
 	return c.Network
 }
@@ -0,0 +1,12 @@
+package config
+
+type DeletionLog struct {
+	// GracePeriodSec is a delay between the deletion record and the actual space deletion, 24 hours by default
+	GracePeriodSec int `yaml:"gracePeriodSec"`
+	// Concurrency is a max number of spaces handled in parallel while processing a page of the log, 10 by default
+	Concurrency int `yaml:"concurrency"`
+	// HardDelete enables removing blocks that are not used by other spaces together with the space
+	HardDelete bool `yaml:"hardDelete"`
+	// RetentionSec is a period while deleted space can be restored, spaces are deleted immediately when it's zero
+	RetentionSec int `yaml:"retentionSec"`
+}
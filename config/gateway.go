@@ -0,0 +1,12 @@
+package config
+
+type Gateway struct {
+	// ListenAddr is an address of the http gateway, the gateway is disabled when it's empty
+	ListenAddr string `yaml:"listenAddr"`
+	// TokenMaxTtlSec is a max lifetime of an access token, tokens expiring later are rejected, 1 day by default
+	TokenMaxTtlSec int `yaml:"tokenMaxTtlSec"`
+	// PresignEnabled allows giving clients direct links to the storage when it supports them (s3)
+	PresignEnabled bool `yaml:"presignEnabled"`
+	// PresignExpirySec is a lifetime of direct links, 5 minutes by default
+	PresignExpirySec int `yaml:"presignExpirySec"`
+}
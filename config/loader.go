@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of environment variables overriding the config file.
+// The variable name is the prefix followed by the uppercased yaml path joined with underscores,
+// e.g. s3Store.bucket is overridden by ANYSYNC_FILENODE_S3STORE_BUCKET
+const EnvPrefix = "ANYSYNC_FILENODE_"
+
+var ErrUnknownField = errors.New("unknown config field")
+
+// Load reads the layered config and validates the result
+func Load(path string, overrides []string) (c *Config, err error) {
+	if c, err = Read(path, overrides); err != nil {
+		return
+	}
+	if err = c.Validate(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Read reads the config file, then applies environment variables and command-line overrides in the form of path=value
+func Read(path string, overrides []string) (c *Config, err error) {
+	if c, err = NewFromFile(path); err != nil {
+		return
+	}
+	if err = c.ApplyEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	if err = c.ApplyOverrides(overrides); err != nil {
+		return nil, err
+	}
+	c.source = path
+	c.overrides = overrides
+	return
+}
+
+// Diff returns yaml paths of fields that differ in the given configs
+func Diff(a, b *Config) (paths []string) {
+	af, bf := a.fields(), b.fields()
+	for i := range af {
+		if !reflect.DeepEqual(af[i].value.Interface(), bf[i].value.Interface()) {
+			paths = append(paths, af[i].path())
+		}
+	}
+	return
+}
+
+func (c *Config) ApplyEnv(environ []string) (err error) {
+	var vars = make(map[string]string)
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, EnvPrefix) {
+			vars[k] = v
+		}
+	}
+	if len(vars) == 0 {
+		return
+	}
+	var errs []error
+	for _, f := range c.fields() {
+		if val, ok := vars[f.envName()]; ok {
+			if e := f.set(val); e != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.envName(), e))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) ApplyOverrides(overrides []string) (err error) {
+	if len(overrides) == 0 {
+		return
+	}
+	fields := c.fields()
+	var errs []error
+	for _, o := range overrides {
+		path, val, _ := strings.Cut(o, "=")
+		var found bool
+		for _, f := range fields {
+			if strings.EqualFold(f.path(), path) {
+				found = true
+				if e := f.set(val); e != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", path, e))
+				}
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownField, path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type configField struct {
+	names []string
+	value reflect.Value
+}
+
+func (f configField) path() string {
+	return strings.Join(f.names, ".")
+}
+
+func (f configField) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.Join(f.names, "_"))
+}
+
+func (f configField) set(val string) (err error) {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(val)
+		return
+	case reflect.Slice:
+		if !strings.HasPrefix(strings.TrimSpace(val), "[") {
+			val = "[" + val + "]"
+		}
+	}
+	ptr := reflect.New(f.value.Type())
+	if err = yaml.Unmarshal([]byte(val), ptr.Interface()); err != nil {
+		return
+	}
+	f.value.Set(ptr.Elem())
+	return
+}
+
+func (c *Config) fields() (fields []configField) {
+	return collectFields(reflect.ValueOf(c).Elem(), nil, fields)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func collectFields(v reflect.Value, names []string, fields []configField) []configField {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, opts, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if tag == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		// fields of inlined structs belong to the parent
+		if fv.Kind() == reflect.Struct && slices.Contains(strings.Split(opts, ","), "inline") {
+			fields = collectFields(fv, names, fields)
+			continue
+		}
+		if tag == "" {
+			tag = strings.ToLower(sf.Name)
+		}
+		fieldNames := append(append([]string{}, names...), tag)
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			fields = collectFields(fv, fieldNames, fields)
+			continue
+		}
+		fields = append(fields, configField{names: fieldNames, value: fv})
+	}
+	return fields
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"net/url"
+)
+
+const redacted = "<redacted>"
+
+// Redacted returns a copy of the config with secrets replaced, it's safe to print or log it
+func (c *Config) Redacted() *Config {
+	r := *c
+	for _, s := range []*string{
+		&r.Account.PeerKey,
+		&r.Account.SigningKey,
+		&r.S3Store.Credentials.AccessKey,
+		&r.S3Store.Credentials.SecretKey,
+		&r.Redis.Password,
+		&r.Redis.Sentinel.Password,
+		&r.CafeMigrateKey,
+	} {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	if r.Redis.Url != "" {
+		if u, err := url.Parse(r.Redis.Url); err == nil {
+			r.Redis.Url = u.Redacted()
+		} else {
+			r.Redis.Url = redacted
+		}
+	}
+	// webhook urls often carry a token in the query
+	for _, s := range []*string{&r.Notifier.WebhookUrl, &r.Events.WebhookUrl} {
+		if *s == "" {
+			continue
+		}
+		if u, err := url.Parse(*s); err == nil {
+			if u.RawQuery != "" {
+				u.RawQuery = redacted
+			}
+			*s = u.Redacted()
+		} else {
+			*s = redacted
+		}
+	}
+	return &r
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/app/logger"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/reload"
+)
+
+const ReloaderCName = "config.reloader"
+
+var log = logger.NewNamed(ReloaderCName)
+
+func NewReloader() app.ComponentRunnable {
+	return new(reloader)
+}
+
+// reloader watches the config file and SIGHUP and pushes changes to the reload.Reloadable components
+type reloader struct {
+	mu   sync.Mutex
+	conf *Config
+	// applied is the config last applied by the component, a component that failed to reload gets the change again on the next reload
+	applied    map[string]*Config
+	components []reload.Reloadable
+	interval   time.Duration
+	modTime    time.Time
+
+	ctxCancel context.CancelFunc
+	done      chan struct{}
+}
+
+func (r *reloader) Init(a *app.App) (err error) {
+	r.conf = a.MustComponent(CName).(*Config)
+	r.applied = make(map[string]*Config)
+	a.IterateComponents(func(c app.Component) {
+		if rc, ok := c.(reload.Reloadable); ok {
+			r.components = append(r.components, rc)
+			r.applied[rc.Name()] = r.conf
+		}
+	})
+	r.interval = time.Duration(r.conf.ReloadIntervalSec) * time.Second
+	if r.interval <= 0 {
+		r.interval = time.Second * 10
+	}
+	return
+}
+
+func (r *reloader) Name() (name string) {
+	return ReloaderCName
+}
+
+func (r *reloader) Run(ctx context.Context) (err error) {
+	if r.conf.source == "" {
+		// config was not read from the file
+		return
+	}
+	if r.modTime, err = fileModTime(r.conf.source); err != nil {
+		return
+	}
+	var loopCtx context.Context
+	loopCtx, r.ctxCancel = context.WithCancel(context.Background())
+	r.done = make(chan struct{})
+	go r.loop(loopCtx)
+	return
+}
+
+func (r *reloader) loop(ctx context.Context) {
+	defer close(r.done)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			log.Info("received SIGHUP, reload config")
+		case <-ticker.C:
+			modTime, err := fileModTime(r.conf.source)
+			if err != nil {
+				log.Warn("can't stat config file", zap.Error(err))
+				continue
+			}
+			if modTime.Equal(r.modTime) {
+				continue
+			}
+			r.modTime = modTime
+		}
+		if _, err := r.Reload(ctx); err != nil {
+			log.Error("config reload error", zap.Error(err))
+		}
+	}
+}
+
+// Reload reads the config again and applies changed fields, it returns fields that require the app restart
+func (r *reloader) Reload(ctx context.Context) (restartRequired []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	newConf, err := Read(r.conf.source, r.conf.overrides)
+	if err != nil {
+		return
+	}
+	if err = newConf.Validate(); err != nil {
+		return
+	}
+	changed := Diff(r.conf, newConf)
+	for _, path := range changed {
+		var covered bool
+		for _, c := range r.components {
+			if reload.Covers(c.ReloadableFields(), path) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			restartRequired = append(restartRequired, path)
+		}
+	}
+	// every component is compared with the config it has applied, so the changes are not lost when another component fails
+	var errs []error
+	for _, c := range r.components {
+		if !slices.ContainsFunc(Diff(r.applied[c.Name()], newConf), func(path string) bool {
+			return reload.Covers(c.ReloadableFields(), path)
+		}) {
+			continue
+		}
+		if e := c.Reload(ctx, newConf); e != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name(), e))
+			continue
+		}
+		r.applied[c.Name()] = newConf
+	}
+	r.conf = newConf
+	if len(restartRequired) != 0 {
+		log.Warn("changed config fields require restart", zap.Strings("fields", restartRequired))
+	}
+	if err = errors.Join(errs...); err != nil {
+		return
+	}
+	if len(changed) != 0 {
+		log.Info("config reloaded", zap.Strings("changed", changed))
+	}
+	return
+}
+
+func (r *reloader) Close(ctx context.Context) (err error) {
+	if r.ctxCancel != nil {
+		r.ctxCancel()
+		<-r.done
+	}
+	return
+}
+
+func fileModTime(path string) (modTime time.Time, err error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	return st.ModTime(), nil
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader_Reload(t *testing.T) {
+	path := writeConfig(t, testYaml)
+	conf, err := Load(path, nil)
+	require.NoError(t, err)
+
+	comp := &testReloadable{}
+	r := NewReloader().(*reloader)
+	a := new(app.App)
+	a.Register(conf).Register(comp).Register(r)
+	require.NoError(t, a.Start(ctx))
+	defer func() {
+		require.NoError(t, a.Close(ctx))
+	}()
+
+	t.Run("no changes", func(t *testing.T) {
+		restart, err := r.Reload(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, restart)
+		assert.Empty(t, comp.reloaded)
+	})
+	t.Run("changes", func(t *testing.T) {
+		updated := strings.Replace(testYaml, "defaultLimit: 1024", "defaultLimit: 2048", 1)
+		updated = strings.Replace(updated, "bucket: files", "bucket: newFiles", 1)
+		writeConfigTo(t, path, updated)
+
+		restart, err := r.Reload(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"s3Store.bucket"}, restart)
+		require.Len(t, comp.reloaded, 1)
+		assert.Equal(t, uint64(2048), comp.reloaded[0].DefaultLimit)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		writeConfigTo(t, path, strings.Replace(testYaml, "peerId: peer", "peerId: \"\"", 1))
+		_, err := r.Reload(ctx)
+		require.Error(t, err)
+		assert.Len(t, comp.reloaded, 1)
+	})
+}
+
+func TestReloader_ReloadFailed(t *testing.T) {
+	path := writeConfig(t, testYaml)
+	conf, err := Load(path, nil)
+	require.NoError(t, err)
+
+	comp := &testReloadable{}
+	failing := &testReloadable{name: "test.failing", fields: []string{"defaultLimit"}, err: errors.New("test error")}
+	r := NewReloader().(*reloader)
+	a := new(app.App)
+	a.Register(conf).Register(comp).Register(failing).Register(r)
+	require.NoError(t, a.Start(ctx))
+	defer func() {
+		require.NoError(t, a.Close(ctx))
+	}()
+
+	writeConfigTo(t, path, strings.Replace(testYaml, "defaultLimit: 1024", "defaultLimit: 2048", 1))
+	_, err = r.Reload(ctx)
+	require.Error(t, err)
+	require.Len(t, comp.reloaded, 1)
+	require.Len(t, failing.reloaded, 1)
+
+	// the succeeded component doesn't get the same change again, the failed one does
+	failing.err = nil
+	_, err = r.Reload(ctx)
+	require.NoError(t, err)
+	assert.Len(t, comp.reloaded, 1)
+	require.Len(t, failing.reloaded, 2)
+	assert.Equal(t, uint64(2048), failing.reloaded[1].DefaultLimit)
+}
+
+type testReloadable struct {
+	name     string
+	fields   []string
+	err      error
+	reloaded []*Config
+}
+
+func (t *testReloadable) Init(a *app.App) (err error) { return }
+
+func (t *testReloadable) Name() string {
+	if t.name != "" {
+		return t.name
+	}
+	return "test.reloadable"
+}
+
+func (t *testReloadable) ReloadableFields() []string {
+	if t.fields != nil {
+		return t.fields
+	}
+	return []string{"defaultLimit", "persistTtl"}
+}
+
+func (t *testReloadable) Reload(ctx context.Context, conf app.Component) error {
+	t.reloaded = append(t.reloaded, conf.(*Config))
+	return t.err
+}
@@ -0,0 +1,8 @@
+package config
+
+type UploadJournal struct {
+	// StaleSec is an age of the journal entry after which its blocks are considered orphaned, 1 hour by default
+	StaleSec int `yaml:"staleSec"`
+	// SweepPeriodSec is a period of the orphaned blocks sweeper, 5 minutes by default
+	SweepPeriodSec int `yaml:"sweepPeriodSec"`
+}
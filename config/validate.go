@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/anyproto/any-sync-filenode/ratelimit"
+)
+
+var persistCompressions = []string{"", "none", "snappy"}
+
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// Validate checks the config and reports all invalid fields at once
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, reason string) {
+		errs = append(errs, &FieldError{Field: field, Reason: reason})
+	}
+	required := func(field, value string) {
+		if value == "" {
+			invalid(field, "is required")
+		}
+	}
+	addr := func(field, value string) {
+		if value == "" {
+			return
+		}
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			invalid(field, err.Error())
+		}
+	}
+
+	required("account.peerId", c.Account.PeerId)
+	required("account.peerKey", c.Account.PeerKey)
+	required("account.signingKey", c.Account.SigningKey)
+
+	if c.FileDevStore.Path == "" {
+		required("s3Store.bucket", c.S3Store.Bucket)
+		required("s3Store.indexBucket", c.S3Store.IndexBucket)
+	}
+	if c.S3Store.MaxThreads < 0 {
+		invalid("s3Store.maxThreads", "must not be negative")
+	}
+
+	if c.Redis.Sentinel.MasterName != "" {
+		if len(c.Redis.Sentinel.Addrs) == 0 {
+			invalid("redis.sentinel.addrs", "is required when masterName is set")
+		}
+	} else if c.Redis.Url != "" {
+		var err error
+		if c.Redis.IsCluster {
+			_, err = redis.ParseClusterURL(c.Redis.Url)
+		} else {
+			_, err = redis.ParseURL(c.Redis.Url)
+		}
+		if err != nil {
+			invalid("redis.url", err.Error())
+		}
+	}
+	if (c.Redis.Tls.CertFile == "") != (c.Redis.Tls.KeyFile == "") {
+		invalid("redis.tls", "certFile and keyFile must be set together")
+	}
+
+	if c.DeletionLog.GracePeriodSec < 0 {
+		invalid("deletionLog.gracePeriodSec", "must not be negative")
+	}
+	if c.DeletionLog.Concurrency < 0 {
+		invalid("deletionLog.concurrency", "must not be negative")
+	}
+	if c.DeletionLog.RetentionSec < 0 {
+		invalid("deletionLog.retentionSec", "must not be negative")
+	}
+	if c.UploadJournal.StaleSec < 0 || c.UploadJournal.SweepPeriodSec < 0 {
+		invalid("uploadJournal", "staleSec and sweepPeriodSec must not be negative")
+	}
+	if c.CidWait.MaxWaitSec < 0 || c.CidWait.MaxWaiters < 0 || c.CidWait.RecheckSec < 0 {
+		invalid("cidWait", "maxWaitSec, maxWaiters and recheckSec must not be negative")
+	}
+	if c.Audit.FlushPeriodSec < 0 {
+		invalid("audit.flushPeriodSec", "must not be negative")
+	}
+	if c.UsageHistoryDays < 0 {
+		invalid("usageHistoryDays", "must not be negative")
+	}
+	if c.BandwidthHistoryDays < 0 {
+		invalid("bandwidthHistoryDays", "must not be negative")
+	}
+	if c.QuotaReservationTtlSec < 0 {
+		invalid("quotaReservationTtlSec", "must not be negative")
+	}
+	for i, threshold := range c.QuotaWarnThresholds {
+		if threshold == 0 || threshold > 100 {
+			invalid("quotaWarnThresholds", "must be between 1 and 100 percents")
+			break
+		}
+		if i > 0 && threshold <= c.QuotaWarnThresholds[i-1] {
+			invalid("quotaWarnThresholds", "must be in ascending order")
+			break
+		}
+	}
+	if c.Notifier.WebhookUrl != "" {
+		if u, err := url.Parse(c.Notifier.WebhookUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("notifier.webhookUrl", "must be an http or https url")
+		}
+	}
+	if c.Notifier.TimeoutSec < 0 || c.Notifier.QueueSize < 0 {
+		invalid("notifier", "timeoutSec and queueSize must not be negative")
+	}
+	if c.Events.WebhookUrl != "" {
+		if u, err := url.Parse(c.Events.WebhookUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("events.webhookUrl", "must be an http or https url")
+		}
+	}
+	if c.Events.TimeoutSec < 0 || c.Events.RetrySec < 0 || c.Events.StreamMaxLen < 0 {
+		invalid("events", "timeoutSec, retrySec and streamMaxLen must not be negative")
+	}
+	if c.Gateway.TokenMaxTtlSec < 0 {
+		invalid("gateway.tokenMaxTtlSec", "must not be negative")
+	}
+	if c.Gateway.PresignExpirySec < 0 {
+		invalid("gateway.presignExpirySec", "must not be negative")
+	}
+	if !slices.Contains(persistCompressions, c.PersistCompression) {
+		invalid("persistCompression", "unknown compression "+c.PersistCompression)
+	}
+	rateLimit := func(field string, l ratelimit.Limit) {
+		if l.Rps < 0 || l.Burst < 0 || l.BytesPerSec < 0 {
+			invalid(field, "must not be negative")
+		}
+	}
+	rateLimit("rateLimit.read", c.RateLimit.Read)
+	rateLimit("rateLimit.write", c.RateLimit.Write)
+	for method, l := range c.RateLimit.Methods {
+		rateLimit("rateLimit.methods."+method, l)
+	}
+	addr("metric.addr", c.Metric.Addr)
+	addr("health.listenAddr", c.Health.ListenAddr)
+	addr("gateway.listenAddr", c.Gateway.ListenAddr)
+	return errors.Join(errs...)
+}
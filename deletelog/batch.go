@@ -0,0 +1,133 @@
+package deletelog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anyproto/any-sync/coordinator/coordinatorproto"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+type batchStat struct {
+	scheduled atomic.Int32
+	cancelled atomic.Int32
+	prepared  atomic.Int32
+}
+
+// processBatch handles a page of the log. Records of the same space are handled sequentially in the log order,
+// different spaces are handled in parallel. All the handlers are idempotent, so the failed batch can be safely retried.
+// A record whose group can't be resolved is parked for a later retry and doesn't fail the batch, the handled record of the space removes the parked one.
+func (d *deleteLog) processBatch(ctx context.Context, recs []*coordinatorproto.DeletionLogRecord, stat *batchStat) (err error) {
+	st := time.Now()
+	var (
+		spaceIds []string
+		bySpace  = make(map[string][]*coordinatorproto.DeletionLogRecord)
+	)
+	for _, rec := range recs {
+		if _, ok := bySpace[rec.SpaceId]; !ok {
+			spaceIds = append(spaceIds, rec.SpaceId)
+		}
+		bySpace[rec.SpaceId] = append(bySpace[rec.SpaceId], rec)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		limiter  = make(chan struct{}, d.concurrency)
+		failed   atomic.Bool
+		errMu    sync.Mutex
+		batchErr error
+	)
+	wg.Add(len(spaceIds))
+	for _, spaceId := range spaceIds {
+		limiter <- struct{}{}
+		go func(spaceRecs []*coordinatorproto.DeletionLogRecord) {
+			defer func() {
+				<-limiter
+				wg.Done()
+			}()
+			fail := func(e error) {
+				failed.Store(true)
+				errMu.Lock()
+				if batchErr == nil {
+					batchErr = e
+				}
+				errMu.Unlock()
+			}
+			var parked bool
+			for _, rec := range spaceRecs {
+				if failed.Load() {
+					return
+				}
+				e := d.handleRecord(ctx, rec, stat)
+				if errors.Is(e, errGroupUnresolved) {
+					e = d.park(ctx, rec)
+					parked = true
+				} else if e == nil {
+					parked = false
+					d.metrics.records.WithLabelValues(rec.Status.String()).Inc()
+				}
+				if e != nil {
+					fail(e)
+					return
+				}
+			}
+			if !parked {
+				if e := d.unpark(ctx, spaceRecs[0].SpaceId); e != nil {
+					fail(e)
+				}
+			}
+		}(bySpace[spaceId])
+	}
+	wg.Wait()
+	d.metrics.batchDuration.Observe(time.Since(st).Seconds())
+	return batchErr
+}
+
+func (d *deleteLog) handleRecord(ctx context.Context, rec *coordinatorproto.DeletionLogRecord, stat *batchStat) (err error) {
+	var key index.Key
+	switch rec.Status {
+	case coordinatorproto.DeletionLogRecordStatus_RemovePrepare:
+		if key, err = d.recordKey(ctx, rec); err != nil {
+			return
+		}
+		if err = d.index.SpaceSetStatus(ctx, key, indexproto.SpaceStatus_SpaceStatusDeletionPending); err != nil {
+			return
+		}
+		stat.prepared.Add(1)
+	case coordinatorproto.DeletionLogRecordStatus_Remove:
+		if key, err = d.recordKey(ctx, rec); err != nil {
+			return
+		}
+		if err = d.index.SpaceSetStatus(ctx, key, indexproto.SpaceStatus_SpaceStatusDeletionPending); err != nil {
+			return
+		}
+		if err = d.schedule(ctx, key, rec.Timestamp); err != nil {
+			return
+		}
+		stat.scheduled.Add(1)
+	case coordinatorproto.DeletionLogRecordStatus_Ok:
+		// the deletion was reverted on the coordinator, the scheduled deletion is found by the space id
+		var ok bool
+		if ok, err = d.cancel(ctx, rec.SpaceId); err != nil {
+			return
+		}
+		if ok {
+			stat.cancelled.Add(1)
+		}
+		if key, err = d.recordKey(ctx, rec); err != nil {
+			return
+		}
+		if err = d.index.SpaceSetStatus(ctx, key, indexproto.SpaceStatus_SpaceStatusOk); err != nil {
+			return
+		}
+	default:
+		log.Warn("unexpected deletion log status", zap.String("spaceId", rec.SpaceId), zap.String("status", rec.Status.String()))
+	}
+	return
+}
@@ -3,18 +3,23 @@ package deletelog
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/anyproto/any-sync/acl"
 	"github.com/anyproto/any-sync/app"
 	"github.com/anyproto/any-sync/app/logger"
 	"github.com/anyproto/any-sync/coordinator/coordinatorclient"
 	"github.com/anyproto/any-sync/coordinator/coordinatorproto"
+	"github.com/anyproto/any-sync/metric"
 	"github.com/anyproto/any-sync/util/periodicsync"
 	"github.com/go-redsync/redsync/v4"
 	"github.com/go-redsync/redsync/v4/redis/goredis/v9"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
+	"github.com/anyproto/any-sync-filenode/config"
 	"github.com/anyproto/any-sync-filenode/index"
 	"github.com/anyproto/any-sync-filenode/redisprovider"
 )
@@ -25,6 +30,13 @@ const lastKey = "deletionLastId.{system}"
 
 const recordsLimit = 1000
 
+const defaultConcurrency = 10
+
+// staleTimeout is the max time since the last successful log check after which the node is not ready
+const staleTimeout = time.Hour * 2
+
+var ErrStale = errors.New("deletion log is stale")
+
 var log = logger.NewNamed(CName)
 
 func New() app.ComponentRunnable {
@@ -36,8 +48,18 @@ type deleteLog struct {
 	coordinatorClient coordinatorclient.CoordinatorClient
 	redsync           *redsync.Redsync
 	ticker            periodicsync.PeriodicSync
+	pendingTicker     periodicsync.PeriodicSync
+	gracePeriod       time.Duration
+	now               func() time.Time
 	index             index.Index
+	acl               acl.AclService
 	disableTicker     bool
+	metric            metric.Metric
+	metrics           *deleteLogMetrics
+	concurrency       int
+	hardDelete        bool
+	retention         time.Duration
+	lastCheck         atomic.Int64
 }
 
 func (d *deleteLog) Init(a *app.App) (err error) {
@@ -45,6 +67,21 @@ func (d *deleteLog) Init(a *app.App) (err error) {
 	d.coordinatorClient = a.MustComponent(coordinatorclient.CName).(coordinatorclient.CoordinatorClient)
 	d.redsync = redsync.New(goredis.NewPool(d.redis))
 	d.index = a.MustComponent(index.CName).(index.Index)
+	d.acl = a.MustComponent(acl.CName).(acl.AclService)
+	conf := a.MustComponent(config.CName).(*config.Config).GetDeletionLog()
+	d.gracePeriod = time.Duration(conf.GracePeriodSec) * time.Second
+	if d.gracePeriod <= 0 {
+		d.gracePeriod = defaultGracePeriod
+	}
+	d.hardDelete = conf.HardDelete
+	d.retention = time.Duration(conf.RetentionSec) * time.Second
+	d.concurrency = conf.Concurrency
+	if d.concurrency <= 0 {
+		d.concurrency = defaultConcurrency
+	}
+	d.now = time.Now
+	d.metric, _ = a.Component(metric.CName).(metric.Metric)
+	d.metrics = newDeleteLogMetrics()
 	return
 }
 
@@ -53,9 +90,15 @@ func (d *deleteLog) Name() (name string) {
 }
 
 func (d *deleteLog) Run(ctx context.Context) (err error) {
+	if d.metric != nil {
+		d.metrics.register(d.metric.Registry())
+	}
+	d.lastCheck.Store(time.Now().Unix())
 	if !d.disableTicker {
 		d.ticker = periodicsync.NewPeriodicSync(60, time.Hour, d.checkLog, log)
 		d.ticker.Run()
+		d.pendingTicker = periodicsync.NewPeriodicSync(60, time.Hour, d.processPending, log)
+		d.pendingTicker.Run()
 	}
 	return
 }
@@ -74,42 +117,83 @@ func (d *deleteLog) checkLog(ctx context.Context) (err error) {
 		return
 	}
 
-	recs, err := d.coordinatorClient.DeletionLog(ctx, lastId, recordsLimit)
-	if err != nil {
-		return
-	}
-	var handledCount, deletedCount int
-	var ok bool
-	for _, rec := range recs {
-		if rec.Status == coordinatorproto.DeletionLogRecordStatus_Remove && rec.FileGroup != "" {
-			ok, err = d.index.SpaceDelete(ctx, index.Key{
-				GroupId: rec.FileGroup,
-				SpaceId: rec.SpaceId,
-			})
-			if err != nil {
-				return
-			}
-			handledCount++
-			if ok {
-				deletedCount++
-			}
+	var (
+		stat  = &batchStat{}
+		total int
+	)
+	for {
+		var recs []*coordinatorproto.DeletionLogRecord
+		if recs, err = d.coordinatorClient.DeletionLog(ctx, lastId, recordsLimit); err != nil {
+			return
+		}
+		if len(recs) == 0 {
+			d.metrics.lag.Set(0)
+			break
 		}
-		if err = d.redis.Set(ctx, lastKey, rec.Id, 0).Err(); err != nil {
+		if err = d.processBatch(ctx, recs, stat); err != nil {
 			return
 		}
+		// move the pointer only when the whole batch is handled
+		lastId = recs[len(recs)-1].Id
+		if err = d.redis.Set(ctx, lastKey, lastId, 0).Err(); err != nil {
+			return
+		}
+		total += len(recs)
+		d.lastCheck.Store(time.Now().Unix())
+		if len(recs) < recordsLimit {
+			d.metrics.lag.Set(0)
+			break
+		}
+		// the log has more records, continue without waiting for the next tick
+		d.metrics.lag.Set(time.Since(time.Unix(recs[len(recs)-1].Timestamp, 0)).Seconds())
+		if _, err = mu.ExtendContext(ctx); err != nil {
+			return
+		}
+	}
+	if err = d.processRetries(ctx); err != nil {
+		return
 	}
+	d.lastCheck.Store(time.Now().Unix())
 	log.Info("processing deletion log",
-		zap.Int("records", len(recs)),
-		zap.Int("handled", handledCount),
-		zap.Int("deleted", deletedCount),
+		zap.Int("records", total),
+		zap.Int32("scheduled", stat.scheduled.Load()),
+		zap.Int32("cancelled", stat.cancelled.Load()),
+		zap.Int32("prepared", stat.prepared.Load()),
 		zap.Duration("dur", time.Since(st)),
 	)
 	return
 }
 
+// recordKey returns the index key of the record space, the group is resolved via acl when the record doesn't contain it.
+// A resolve error is errGroupUnresolved, such records are parked and retried later instead of stalling the log
+func (d *deleteLog) recordKey(ctx context.Context, rec *coordinatorproto.DeletionLogRecord) (key index.Key, err error) {
+	key.SpaceId = rec.SpaceId
+	if rec.FileGroup != "" {
+		key.GroupId = rec.FileGroup
+		return key, nil
+	}
+	ownerPubKey, err := d.acl.OwnerPubKey(ctx, rec.SpaceId)
+	if err != nil {
+		log.Warn("can't resolve file group for deletion log record", zap.String("spaceId", rec.SpaceId), zap.Error(err))
+		return key, fmt.Errorf("%w of space %s: %w", errGroupUnresolved, rec.SpaceId, err)
+	}
+	key.GroupId = ownerPubKey.Account()
+	return key, nil
+}
+
+func (d *deleteLog) HealthCheck(ctx context.Context) (err error) {
+	if time.Since(time.Unix(d.lastCheck.Load(), 0)) > staleTimeout {
+		return ErrStale
+	}
+	return
+}
+
 func (d *deleteLog) Close(ctx context.Context) (err error) {
 	if d.ticker != nil {
 		d.ticker.Close()
 	}
+	if d.pendingTicker != nil {
+		d.pendingTicker.Close()
+	}
 	return
 }
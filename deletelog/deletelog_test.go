@@ -2,18 +2,25 @@ package deletelog
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/anyproto/any-sync/acl"
+	"github.com/anyproto/any-sync/acl/mock_acl"
 	"github.com/anyproto/any-sync/app"
 	"github.com/anyproto/any-sync/coordinator/coordinatorclient"
 	"github.com/anyproto/any-sync/coordinator/coordinatorclient/mock_coordinatorclient"
 	"github.com/anyproto/any-sync/coordinator/coordinatorproto"
+	"github.com/anyproto/any-sync/util/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	"github.com/anyproto/any-sync-filenode/config"
 	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
 	"github.com/anyproto/any-sync-filenode/index/mock_index"
 	"github.com/anyproto/any-sync-filenode/redisprovider/testredisprovider"
 )
@@ -47,16 +54,277 @@ func TestDeleteLog_checkLog(t *testing.T) {
 				FileGroup: "f2",
 			},
 		}, nil)
+		fx.index.EXPECT().SpaceSetStatus(ctx, index.Key{GroupId: "f1", SpaceId: "s1"}, indexproto.SpaceStatus_SpaceStatusOk)
+		fx.index.EXPECT().SpaceSetStatus(ctx, index.Key{GroupId: "f2", SpaceId: "s2"}, indexproto.SpaceStatus_SpaceStatusDeletionPending)
+		require.NoError(t, fx.checkLog(ctx))
+		lastId, err := fx.redis.Get(ctx, lastKey).Result()
+		require.NoError(t, err)
+		assert.Equal(t, "2", lastId)
+
+		// grace period is not over
+		require.NoError(t, fx.processPending(ctx))
+
+		fx.now = func() time.Time {
+			return time.Now().Add(time.Minute * 2)
+		}
 		fx.index.EXPECT().SpaceDelete(ctx, index.Key{
 			GroupId: "f2",
 			SpaceId: "s2",
 		})
+		require.NoError(t, fx.processPending(ctx))
+		pending, err := fx.redis.ZCard(ctx, pendingKey).Result()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+	t.Run("hard delete", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		fx.hardDelete = true
+		key := index.Key{GroupId: "f1", SpaceId: "s1"}
+		require.NoError(t, fx.schedule(ctx, key, time.Now().Unix()))
+		fx.now = func() time.Time {
+			return time.Now().Add(time.Minute * 2)
+		}
+		fx.index.EXPECT().SpacePurge(ctx, key).Return(&indexproto.DeletionReport{SpaceId: "s1"}, nil)
+		require.NoError(t, fx.processPending(ctx))
+	})
+	t.Run("retention", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		fx.retention = time.Hour
+		key := index.Key{GroupId: "f1", SpaceId: "s1"}
+		require.NoError(t, fx.schedule(ctx, key, time.Now().Unix()))
+		fx.now = func() time.Time {
+			return time.Now().Add(time.Minute * 2)
+		}
+		fx.index.EXPECT().SpaceSoftDelete(ctx, key).Return(true, nil)
+		fx.index.EXPECT().DeletedSpaces(ctx, gomock.Any()).Return(nil, nil)
+		require.NoError(t, fx.processPending(ctx))
+
+		// retention is over
+		fx.now = func() time.Time {
+			return time.Now().Add(time.Hour * 2)
+		}
+		fx.index.EXPECT().DeletedSpaces(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, before time.Time) ([]index.Key, error) {
+			assert.True(t, before.After(time.Now()))
+			return []index.Key{key}, nil
+		})
+		fx.index.EXPECT().SpaceDelete(ctx, key).Return(true, nil)
+		require.NoError(t, fx.processPending(ctx))
+	})
+	t.Run("cancel", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		now := time.Now().Unix()
+		fx.coord.EXPECT().DeletionLog(ctx, "", recordsLimit).Return([]*coordinatorproto.DeletionLogRecord{
+			{
+				Id:        "1",
+				SpaceId:   "s1",
+				Status:    coordinatorproto.DeletionLogRecordStatus_Remove,
+				Timestamp: now,
+				FileGroup: "f1",
+			},
+		}, nil)
+		fx.index.EXPECT().SpaceSetStatus(ctx, index.Key{GroupId: "f1", SpaceId: "s1"}, indexproto.SpaceStatus_SpaceStatusDeletionPending)
+		require.NoError(t, fx.checkLog(ctx))
+		fx.coord.EXPECT().DeletionLog(ctx, "1", recordsLimit).Return([]*coordinatorproto.DeletionLogRecord{
+			{
+				Id:        "2",
+				SpaceId:   "s1",
+				Status:    coordinatorproto.DeletionLogRecordStatus_Ok,
+				Timestamp: now + 10,
+				FileGroup: "f1",
+			},
+		}, nil)
+		fx.index.EXPECT().SpaceSetStatus(ctx, index.Key{GroupId: "f1", SpaceId: "s1"}, indexproto.SpaceStatus_SpaceStatusOk)
+		require.NoError(t, fx.checkLog(ctx))
+
+		fx.now = func() time.Time {
+			return time.Now().Add(time.Minute * 2)
+		}
+		require.NoError(t, fx.processPending(ctx))
+	})
+	t.Run("remove prepare", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		fx.coord.EXPECT().DeletionLog(ctx, "", recordsLimit).Return([]*coordinatorproto.DeletionLogRecord{
+			{
+				Id:        "1",
+				SpaceId:   "s1",
+				Status:    coordinatorproto.DeletionLogRecordStatus_RemovePrepare,
+				Timestamp: time.Now().Unix(),
+				FileGroup: "f1",
+			},
+		}, nil)
+		fx.index.EXPECT().SpaceSetStatus(ctx, index.Key{GroupId: "f1", SpaceId: "s1"}, indexproto.SpaceStatus_SpaceStatusDeletionPending)
+		require.NoError(t, fx.checkLog(ctx))
+		pending, err := fx.redis.ZCard(ctx, pendingKey).Result()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+	t.Run("resolve group", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		_, pubKey, err := crypto.GenerateRandomEd25519KeyPair()
+		require.NoError(t, err)
+		_, pubKey2, err := crypto.GenerateRandomEd25519KeyPair()
+		require.NoError(t, err)
+		recs := []*coordinatorproto.DeletionLogRecord{
+			{
+				Id:        "1",
+				SpaceId:   "s1",
+				Status:    coordinatorproto.DeletionLogRecordStatus_Remove,
+				Timestamp: time.Now().Unix(),
+			},
+			{
+				Id:        "2",
+				SpaceId:   "s2",
+				Status:    coordinatorproto.DeletionLogRecordStatus_Remove,
+				Timestamp: time.Now().Unix(),
+			},
+			{
+				Id:        "3",
+				SpaceId:   "s3",
+				Status:    coordinatorproto.DeletionLogRecordStatus_RemovePrepare,
+				Timestamp: time.Now().Unix(),
+				FileGroup: "f3",
+			},
+		}
+
+		// the acl fails for s1, the record is parked and the pointer moves past it
+		fx.coord.EXPECT().DeletionLog(ctx, "", recordsLimit).Return(recs, nil)
+		fx.acl.EXPECT().OwnerPubKey(ctx, "s1").Return(nil, fmt.Errorf("acl is not available"))
+		fx.acl.EXPECT().OwnerPubKey(ctx, "s2").Return(pubKey2, nil)
+		fx.index.EXPECT().SpaceSetStatus(ctx, index.Key{GroupId: pubKey2.Account(), SpaceId: "s2"}, indexproto.SpaceStatus_SpaceStatusDeletionPending)
+		fx.index.EXPECT().SpaceSetStatus(ctx, index.Key{GroupId: "f3", SpaceId: "s3"}, indexproto.SpaceStatus_SpaceStatusDeletionPending)
 		require.NoError(t, fx.checkLog(ctx))
 		lastId, err := fx.redis.Get(ctx, lastKey).Result()
 		require.NoError(t, err)
-		assert.Equal(t, "2", lastId)
+		assert.Equal(t, "3", lastId)
+		parked, err := fx.redis.ZRange(ctx, retryKey, 0, -1).Result()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"s1"}, parked)
+
+		// the retry is not due yet
+		fx.coord.EXPECT().DeletionLog(ctx, "3", recordsLimit).Return(nil, nil).Times(2)
+		require.NoError(t, fx.checkLog(ctx))
+
+		now := time.Now().Add(retryBaseDelay * 2)
+		fx.now = func() time.Time { return now }
+		fx.acl.EXPECT().OwnerPubKey(ctx, "s1").Return(pubKey, nil)
+		fx.index.EXPECT().SpaceSetStatus(ctx, index.Key{GroupId: pubKey.Account(), SpaceId: "s1"}, indexproto.SpaceStatus_SpaceStatusDeletionPending)
+		require.NoError(t, fx.checkLog(ctx))
+
+		groupId, err := fx.redis.HGet(ctx, pendingGroupsKey, "s1").Result()
+		require.NoError(t, err)
+		assert.Equal(t, pubKey.Account(), groupId)
+		groupId, err = fx.redis.HGet(ctx, pendingGroupsKey, "s2").Result()
+		require.NoError(t, err)
+		assert.Equal(t, pubKey2.Account(), groupId)
+		parkedCount, err := fx.redis.ZCard(ctx, retryKey).Result()
+		require.NoError(t, err)
+		assert.Zero(t, parkedCount)
+	})
+	t.Run("dead letter", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		rec := &coordinatorproto.DeletionLogRecord{
+			Id:        "1",
+			SpaceId:   "s1",
+			Status:    coordinatorproto.DeletionLogRecordStatus_RemovePrepare,
+			Timestamp: time.Now().Unix(),
+		}
+		fx.acl.EXPECT().OwnerPubKey(ctx, "s1").Return(nil, fmt.Errorf("space is deleted")).Times(retryMaxAttempts + 1)
+		fx.coord.EXPECT().DeletionLog(ctx, "", recordsLimit).Return([]*coordinatorproto.DeletionLogRecord{rec}, nil)
+		fx.coord.EXPECT().DeletionLog(ctx, "1", recordsLimit).Return(nil, nil).Times(retryMaxAttempts)
+		require.NoError(t, fx.checkLog(ctx))
+		now := time.Now()
+		for range retryMaxAttempts {
+			now = now.Add(retryMaxDelay)
+			fx.now = func() time.Time { return now }
+			require.NoError(t, fx.checkLog(ctx))
+		}
+		parkedCount, err := fx.redis.ZCard(ctx, retryKey).Result()
+		require.NoError(t, err)
+		assert.Zero(t, parkedCount)
+		dead, err := fx.redis.HExists(ctx, deadKey, "s1").Result()
+		require.NoError(t, err)
+		assert.True(t, dead)
 	})
+	t.Run("cancel without group", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		key := index.Key{GroupId: "f1", SpaceId: "s1"}
+		require.NoError(t, fx.schedule(ctx, key, time.Now().Unix()))
+		fx.coord.EXPECT().DeletionLog(ctx, "", recordsLimit).Return([]*coordinatorproto.DeletionLogRecord{
+			{
+				Id:        "1",
+				SpaceId:   "s1",
+				Status:    coordinatorproto.DeletionLogRecordStatus_Ok,
+				Timestamp: time.Now().Unix(),
+			},
+		}, nil)
+		fx.acl.EXPECT().OwnerPubKey(ctx, "s1").Return(nil, fmt.Errorf("acl is not available"))
+		require.NoError(t, fx.checkLog(ctx))
+
+		// the deletion is cancelled even though the status is not updated yet, the record is parked
+		pending, err := fx.redis.ZCard(ctx, pendingKey).Result()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestDeleteLog_checkLogCatchUp(t *testing.T) {
+	newRecords := func(from, count int, status coordinatorproto.DeletionLogRecordStatus) (recs []*coordinatorproto.DeletionLogRecord) {
+		for i := from; i < from+count; i++ {
+			recs = append(recs, &coordinatorproto.DeletionLogRecord{
+				Id:        strconv.Itoa(i),
+				SpaceId:   "s" + strconv.Itoa(i%recordsLimit),
+				Status:    status,
+				Timestamp: time.Now().Unix(),
+				FileGroup: "f1",
+			})
+		}
+		return
+	}
+	t.Run("multi page", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		page1 := newRecords(1, recordsLimit, coordinatorproto.DeletionLogRecordStatus_RemovePrepare)
+		page2 := newRecords(recordsLimit+1, recordsLimit, coordinatorproto.DeletionLogRecordStatus_Remove)
+		page3 := newRecords(recordsLimit*2+1, 10, coordinatorproto.DeletionLogRecordStatus_Ok)
+		gomock.InOrder(
+			fx.coord.EXPECT().DeletionLog(ctx, "", recordsLimit).Return(page1, nil),
+			fx.coord.EXPECT().DeletionLog(ctx, page1[len(page1)-1].Id, recordsLimit).Return(page2, nil),
+			fx.coord.EXPECT().DeletionLog(ctx, page2[len(page2)-1].Id, recordsLimit).Return(page3, nil),
+		)
+		fx.index.EXPECT().SpaceSetStatus(ctx, gomock.Any(), indexproto.SpaceStatus_SpaceStatusDeletionPending).Times(recordsLimit * 2)
+		fx.index.EXPECT().SpaceSetStatus(ctx, gomock.Any(), indexproto.SpaceStatus_SpaceStatusOk).Times(10)
+		require.NoError(t, fx.checkLog(ctx))
+
+		lastId, err := fx.redis.Get(ctx, lastKey).Result()
+		require.NoError(t, err)
+		assert.Equal(t, page3[len(page3)-1].Id, lastId)
+		pending, err := fx.redis.ZCard(ctx, pendingKey).Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(recordsLimit-10), pending)
+	})
+	t.Run("failed batch", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.finish(t)
+		page1 := newRecords(1, recordsLimit, coordinatorproto.DeletionLogRecordStatus_RemovePrepare)
+		page2 := newRecords(recordsLimit+1, 10, coordinatorproto.DeletionLogRecordStatus_RemovePrepare)
+		fx.coord.EXPECT().DeletionLog(ctx, "", recordsLimit).Return(page1, nil)
+		fx.coord.EXPECT().DeletionLog(ctx, page1[len(page1)-1].Id, recordsLimit).Return(page2, nil)
+		fx.index.EXPECT().SpaceSetStatus(ctx, gomock.Any(), gomock.Any()).Times(recordsLimit)
+		fx.index.EXPECT().SpaceSetStatus(ctx, gomock.Any(), gomock.Any()).Return(fmt.Errorf("test error")).MinTimes(1)
+		require.Error(t, fx.checkLog(ctx))
 
+		// pointer stays at the last fully handled batch
+		lastId, err := fx.redis.Get(ctx, lastKey).Result()
+		require.NoError(t, err)
+		assert.Equal(t, page1[len(page1)-1].Id, lastId)
+	})
 }
 
 func newFixture(t *testing.T) *fixture {
@@ -66,6 +334,7 @@ func newFixture(t *testing.T) *fixture {
 		a:         new(app.App),
 		coord:     mock_coordinatorclient.NewMockCoordinatorClient(ctrl),
 		index:     mock_index.NewMockIndex(ctrl),
+		acl:       mock_acl.NewMockAclService(ctrl),
 		deleteLog: New().(*deleteLog),
 	}
 	fx.disableTicker = true
@@ -75,8 +344,17 @@ func newFixture(t *testing.T) *fixture {
 	fx.index.EXPECT().Init(gomock.Any()).AnyTimes()
 	fx.index.EXPECT().Run(gomock.Any()).AnyTimes()
 	fx.index.EXPECT().Close(gomock.Any()).AnyTimes()
+	fx.acl.EXPECT().Name().Return(acl.CName).AnyTimes()
+	fx.acl.EXPECT().Init(gomock.Any()).AnyTimes()
+	fx.acl.EXPECT().Run(gomock.Any()).AnyTimes()
+	fx.acl.EXPECT().Close(gomock.Any()).AnyTimes()
 
-	fx.a.Register(testredisprovider.NewTestRedisProviderNum(7)).Register(fx.coord).Register(fx.index).Register(fx.deleteLog)
+	fx.a.Register(testredisprovider.NewTestRedisProviderNum(7)).
+		Register(fx.coord).
+		Register(fx.index).
+		Register(fx.acl).
+		Register(fx.deleteLog).
+		Register(&config.Config{DeletionLog: config.DeletionLog{GracePeriodSec: 60}})
 	require.NoError(t, fx.a.Start(ctx))
 
 	return fx
@@ -87,6 +365,7 @@ type fixture struct {
 	a     *app.App
 	coord *mock_coordinatorclient.MockCoordinatorClient
 	index *mock_index.MockIndex
+	acl   *mock_acl.MockAclService
 	*deleteLog
 }
 
@@ -0,0 +1,51 @@
+package deletelog
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricNamespace = "filenode"
+	metricSubsystem = "deletelog"
+)
+
+type deleteLogMetrics struct {
+	lag           prometheus.Gauge
+	records       *prometheus.CounterVec
+	batchDuration prometheus.Histogram
+	parked        *prometheus.CounterVec
+}
+
+func newDeleteLogMetrics() *deleteLogMetrics {
+	return &deleteLogMetrics{
+		lag: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "lag_seconds",
+			Help:      "age of the last handled record while the log has unprocessed records",
+		}),
+		records: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "records_total",
+			Help:      "count of handled log records",
+		}, []string{"status"}),
+		batchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "batch_duration_seconds",
+			Help:      "time spent to handle one page of the log",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+		}),
+		parked: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "parked_records_total",
+			Help:      "count of records put aside because their group can't be resolved, by the result",
+		}, []string{"result"}),
+	}
+}
+
+func (m *deleteLogMetrics) register(reg *prometheus.Registry) {
+	reg.MustRegister(m.lag, m.records, m.batchDuration, m.parked)
+}
@@ -0,0 +1,146 @@
+package deletelog
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index"
+)
+
+const (
+	// pendingKey is a sorted set of space ids scheduled for deletion, the score is a deadline
+	pendingKey = "deletionPending.{system}"
+	// pendingGroupsKey is a map spaceId -> groupId for scheduled spaces
+	pendingGroupsKey = "deletionPendingGroups.{system}"
+)
+
+const defaultGracePeriod = time.Hour * 24
+
+// schedule adds the space to the pending queue, the deletion will be executed by processPending after the grace period
+func (d *deleteLog) schedule(ctx context.Context, key index.Key, recTimestamp int64) (err error) {
+	from := time.Unix(recTimestamp, 0)
+	if recTimestamp == 0 {
+		from = d.now()
+	}
+	deadline := from.Add(d.gracePeriod).Unix()
+	_, err = d.redis.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.ZAdd(ctx, pendingKey, redis.Z{Score: float64(deadline), Member: key.SpaceId})
+		tx.HSet(ctx, pendingGroupsKey, key.SpaceId, key.GroupId)
+		return nil
+	})
+	return
+}
+
+// cancel removes the space from the pending queue, ok is true when the space was scheduled
+func (d *deleteLog) cancel(ctx context.Context, spaceId string) (ok bool, err error) {
+	var zRem *redis.IntCmd
+	if _, err = d.redis.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		zRem = tx.ZRem(ctx, pendingKey, spaceId)
+		tx.HDel(ctx, pendingGroupsKey, spaceId)
+		return nil
+	}); err != nil {
+		return
+	}
+	return zRem.Val() > 0, nil
+}
+
+// processPending deletes spaces whose grace period is over and purges soft deleted spaces after the retention period
+func (d *deleteLog) processPending(ctx context.Context) (err error) {
+	mu := d.redsync.NewMutex("_lock:deletionPending", redsync.WithExpiry(time.Minute*10))
+	if err = mu.LockContext(ctx); err != nil {
+		return
+	}
+	defer func() {
+		_, _ = mu.Unlock()
+	}()
+	if err = d.processScheduled(ctx); err != nil {
+		return
+	}
+	return d.processDeleted(ctx)
+}
+
+func (d *deleteLog) processScheduled(ctx context.Context) (err error) {
+	st := time.Now()
+	spaceIds, err := d.redis.ZRangeByScore(ctx, pendingKey, &redis.ZRangeBy{
+		Min: "0",
+		Max: strconv.FormatInt(d.now().Unix(), 10),
+	}).Result()
+	if err != nil || len(spaceIds) == 0 {
+		return
+	}
+	var deletedCount int
+	for _, spaceId := range spaceIds {
+		groupId, gErr := d.redis.HGet(ctx, pendingGroupsKey, spaceId).Result()
+		if gErr != nil && !errors.Is(gErr, redis.Nil) {
+			return gErr
+		}
+		if groupId != "" {
+			ok, dErr := d.removeSpace(ctx, index.Key{GroupId: groupId, SpaceId: spaceId})
+			if dErr != nil {
+				return dErr
+			}
+			if ok {
+				deletedCount++
+			}
+		} else {
+			log.Warn("pending deletion without group", zap.String("spaceId", spaceId))
+		}
+		if _, err = d.cancel(ctx, spaceId); err != nil {
+			return
+		}
+	}
+	log.Info("processing pending deletions",
+		zap.Int("spaces", len(spaceIds)),
+		zap.Int("deleted", deletedCount),
+		zap.Duration("dur", time.Since(st)),
+	)
+	return
+}
+
+// removeSpace soft deletes the space when the retention is enabled, otherwise deletes it immediately
+func (d *deleteLog) removeSpace(ctx context.Context, key index.Key) (ok bool, err error) {
+	if d.retention > 0 {
+		return d.index.SpaceSoftDelete(ctx, key)
+	}
+	return d.deleteSpace(ctx, key)
+}
+
+// processDeleted deletes soft deleted spaces whose retention period is over
+func (d *deleteLog) processDeleted(ctx context.Context) (err error) {
+	if d.retention <= 0 {
+		return
+	}
+	keys, err := d.index.DeletedSpaces(ctx, d.now().Add(-d.retention))
+	if err != nil || len(keys) == 0 {
+		return
+	}
+	var deletedCount int
+	for _, key := range keys {
+		ok, dErr := d.deleteSpace(ctx, key)
+		if dErr != nil {
+			return dErr
+		}
+		if ok {
+			deletedCount++
+		}
+	}
+	log.Info("processing deleted spaces",
+		zap.Int("spaces", len(keys)),
+		zap.Int("deleted", deletedCount),
+	)
+	return
+}
+
+func (d *deleteLog) deleteSpace(ctx context.Context, key index.Key) (ok bool, err error) {
+	if !d.hardDelete {
+		return d.index.SpaceDelete(ctx, key)
+	}
+	report, err := d.index.SpacePurge(ctx, key)
+	return report != nil, err
+}
@@ -0,0 +1,131 @@
+package deletelog
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/anyproto/any-sync/coordinator/coordinatorproto"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// retryKey is a sorted set of space ids whose records can't be handled yet, the score is a time of the next attempt
+	retryKey = "deletionRetry.{system}"
+	// retryRecordsKey is a map spaceId -> the last record of the space waiting for the retry
+	retryRecordsKey = "deletionRetryRecords.{system}"
+	// retryAttemptsKey is a map spaceId -> count of failed attempts
+	retryAttemptsKey = "deletionRetryAttempts.{system}"
+	// deadKey is a map spaceId -> the last record of the space given up after retryMaxAttempts
+	deadKey = "deletionDead.{system}"
+)
+
+const (
+	retryBaseDelay   = time.Minute
+	retryMaxDelay    = time.Hour * 6
+	retryMaxAttempts = 20
+)
+
+// errGroupUnresolved is returned for records whose group can't be resolved, they are parked instead of failing the batch
+var errGroupUnresolved = errors.New("can't resolve file group")
+
+// park puts the record aside for a later retry, so the log pointer can move past it.
+// Only the last record of the space is kept, it supersedes the previous ones
+func (d *deleteLog) park(ctx context.Context, rec *coordinatorproto.DeletionLogRecord) (err error) {
+	data, err := rec.Marshal()
+	if err != nil {
+		return
+	}
+	_, err = d.redis.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.ZAdd(ctx, retryKey, redis.Z{Score: float64(d.now().Add(retryBaseDelay).Unix()), Member: rec.SpaceId})
+		tx.HSet(ctx, retryRecordsKey, rec.SpaceId, data)
+		tx.HDel(ctx, retryAttemptsKey, rec.SpaceId)
+		return nil
+	})
+	if err == nil {
+		d.metrics.parked.WithLabelValues("parked").Inc()
+	}
+	return
+}
+
+// unpark removes the parked record of the space after a newer record of the space has been handled
+func (d *deleteLog) unpark(ctx context.Context, spaceId string) (err error) {
+	_, err = d.redis.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.ZRem(ctx, retryKey, spaceId)
+		tx.HDel(ctx, retryRecordsKey, spaceId)
+		tx.HDel(ctx, retryAttemptsKey, spaceId)
+		return nil
+	})
+	return
+}
+
+// processRetries handles parked records whose retry time has come. The delay doubles with every failed attempt,
+// after retryMaxAttempts the record is moved to the dead letter map and is not retried anymore
+func (d *deleteLog) processRetries(ctx context.Context) (err error) {
+	spaceIds, err := d.redis.ZRangeByScore(ctx, retryKey, &redis.ZRangeBy{
+		Min: "0",
+		Max: strconv.FormatInt(d.now().Unix(), 10),
+	}).Result()
+	if err != nil || len(spaceIds) == 0 {
+		return
+	}
+	stat := &batchStat{}
+	for _, spaceId := range spaceIds {
+		data, gErr := d.redis.HGet(ctx, retryRecordsKey, spaceId).Result()
+		if gErr != nil && !errors.Is(gErr, redis.Nil) {
+			return gErr
+		}
+		rec := &coordinatorproto.DeletionLogRecord{}
+		if data == "" || rec.Unmarshal([]byte(data)) != nil {
+			log.Warn("invalid parked deletion log record", zap.String("spaceId", spaceId))
+			if err = d.unpark(ctx, spaceId); err != nil {
+				return
+			}
+			continue
+		}
+		hErr := d.handleRecord(ctx, rec, stat)
+		if hErr == nil {
+			d.metrics.parked.WithLabelValues("resolved").Inc()
+			d.metrics.records.WithLabelValues(rec.Status.String()).Inc()
+			if err = d.unpark(ctx, spaceId); err != nil {
+				return
+			}
+			continue
+		}
+		if !errors.Is(hErr, errGroupUnresolved) {
+			return hErr
+		}
+		if err = d.retryLater(ctx, rec, data); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (d *deleteLog) retryLater(ctx context.Context, rec *coordinatorproto.DeletionLogRecord, data string) (err error) {
+	attempts, err := d.redis.HIncrBy(ctx, retryAttemptsKey, rec.SpaceId, 1).Result()
+	if err != nil {
+		return
+	}
+	if attempts >= retryMaxAttempts {
+		log.Error("deletion log record is given up", zap.String("spaceId", rec.SpaceId), zap.String("id", rec.Id), zap.Int64("attempts", attempts))
+		_, err = d.redis.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+			tx.HSet(ctx, deadKey, rec.SpaceId, data)
+			tx.ZRem(ctx, retryKey, rec.SpaceId)
+			tx.HDel(ctx, retryRecordsKey, rec.SpaceId)
+			tx.HDel(ctx, retryAttemptsKey, rec.SpaceId)
+			return nil
+		})
+		if err == nil {
+			d.metrics.parked.WithLabelValues("dead").Inc()
+		}
+		return
+	}
+	delay := retryBaseDelay << attempts
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	return d.redis.ZAdd(ctx, retryKey, redis.Z{Score: float64(d.now().Add(delay).Unix()), Member: rec.SpaceId}).Err()
+}
@@ -0,0 +1,16 @@
+package events
+
+import "github.com/anyproto/any-sync-filenode/notifier"
+
+type configSource interface {
+	GetEvents() Config
+}
+
+type Config struct {
+	// WebhookConfig enables the delivery of events to the url with the notifier's webhook sink, events are only published to the stream when it's empty
+	notifier.WebhookConfig `yaml:",inline"`
+	// RetrySec is a delay before an event that wasn't delivered is retried, 1 minute by default
+	RetrySec int `yaml:"retrySec"`
+	// StreamMaxLen is an approximate max count of events kept in the stream, 1000000 by default
+	StreamMaxLen int64 `yaml:"streamMaxLen"`
+}
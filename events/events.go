@@ -0,0 +1,249 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/anyproto/any-sync/accountservice"
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/app/logger"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/notifier"
+	"github.com/anyproto/any-sync-filenode/redisprovider"
+)
+
+const CName = "filenode.events"
+
+var log = logger.NewNamed(CName)
+
+const (
+	// Stream is a redis stream of events, downstream consumers read it with their own consumer groups
+	Stream      = "fileEvents"
+	streamField = "e"
+	// consumerGroup is a group the node delivers events to the sink with
+	consumerGroup = "filenode"
+
+	DefaultStreamMaxLen = 1000000
+	defaultRetrySec     = 60
+	readCount           = 100
+	readBlock           = time.Second * 5
+)
+
+const (
+	TypeFileBound    = "fileBound"
+	TypeFileUnbound  = "fileUnbound"
+	TypeSpaceDeleted = "spaceDeleted"
+	TypeLimitChanged = "limitChanged"
+)
+
+// Event is a change of the index, it's published to the stream as json and delivered with the notifier's sinks.
+// Size and CidCount of fileBound and fileUnbound events are the size and the count of cids bound or unbound by the change
+type Event = notifier.Event
+
+// Sink delivers events, e.g. to a webhook
+type Sink = notifier.Sink
+
+// Marshal sets the id and the time of the event if they are empty and encodes it,
+// the id stays the same when the encoded event is published more than once
+func Marshal(event Event) (data []byte, err error) {
+	if event.Id == "" {
+		var id [16]byte
+		if _, err = rand.Read(id[:]); err != nil {
+			return
+		}
+		event.Id = hex.EncodeToString(id[:])
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	return json.Marshal(event)
+}
+
+// Publish appends the event to the stream, the stream is trimmed to the given max length approximately
+func Publish(ctx context.Context, cl redis.Cmdable, maxLen int64, event Event) (err error) {
+	data, err := Marshal(event)
+	if err != nil {
+		return
+	}
+	return PublishData(ctx, cl, maxLen, data)
+}
+
+// PublishData appends the event encoded with Marshal to the stream
+func PublishData(ctx context.Context, cl redis.Cmdable, maxLen int64, data []byte) (err error) {
+	return cl.XAdd(ctx, &redis.XAddArgs{
+		Stream: Stream,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: []any{streamField, data},
+	}).Err()
+}
+
+func New() Dispatcher {
+	return new(dispatcher)
+}
+
+// NewWithSink creates the dispatcher that delivers events to the given sink instead of the configured one
+func NewWithSink(sink Sink) Dispatcher {
+	return &dispatcher{sink: sink}
+}
+
+// Dispatcher delivers events from the stream to the sink at least once, nodes share the delivery via the consumer group
+type Dispatcher interface {
+	app.ComponentRunnable
+}
+
+type dispatcher struct {
+	cl       redis.UniversalClient
+	sink     Sink
+	consumer string
+	timeout  time.Duration
+	retry    time.Duration
+	done     chan struct{}
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+}
+
+func (d *dispatcher) Init(a *app.App) (err error) {
+	conf := a.MustComponent("config").(configSource).GetEvents()
+	if conf.RetrySec <= 0 {
+		conf.RetrySec = defaultRetrySec
+	}
+	d.timeout = conf.Timeout()
+	d.retry = time.Second * time.Duration(conf.RetrySec)
+	if d.sink == nil && conf.WebhookUrl != "" {
+		d.sink = notifier.NewWebhookSink(conf.WebhookUrl)
+	}
+	d.cl = a.MustComponent(redisprovider.CName).(redisprovider.RedisProvider).Redis()
+	d.consumer = a.MustComponent(accountservice.CName).(accountservice.Service).Account().PeerId
+	d.ctx, d.ctxCancel = context.WithCancel(context.Background())
+	return
+}
+
+func (d *dispatcher) Name() (name string) {
+	return CName
+}
+
+func (d *dispatcher) Run(ctx context.Context) (err error) {
+	if d.sink == nil {
+		return
+	}
+	// the group reads the stream from the beginning, so events published before the first start are delivered too
+	if err = d.cl.XGroupCreateMkStream(ctx, Stream, consumerGroup, "0").Err(); err != nil {
+		if !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return
+		}
+		err = nil
+	}
+	d.done = make(chan struct{})
+	go d.deliverLoop()
+	return
+}
+
+func (d *dispatcher) deliverLoop() {
+	defer close(d.done)
+	var retryDelay time.Duration
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+		if err := d.deliverNext(); err != nil {
+			if d.ctx.Err() != nil {
+				return
+			}
+			log.Warn("events delivery error", zap.Error(err))
+			retryDelay = min(retryDelay*2+time.Second/10, time.Second*10)
+			continue
+		}
+		retryDelay = 0
+	}
+}
+
+// deliverNext delivers events pending longer than the retry interval, then waits for new events and delivers them
+func (d *dispatcher) deliverNext() (err error) {
+	// events of this or another node that weren't acknowledged
+	claimed, _, err := d.cl.XAutoClaim(d.ctx, &redis.XAutoClaimArgs{
+		Stream:   Stream,
+		Group:    consumerGroup,
+		Consumer: d.consumer,
+		MinIdle:  d.retry,
+		Start:    "0-0",
+		Count:    readCount,
+	}).Result()
+	if err != nil {
+		return
+	}
+	if err = d.deliver(claimed); err != nil {
+		return
+	}
+
+	res, err := d.cl.XReadGroup(d.ctx, &redis.XReadGroupArgs{
+		Group:    consumerGroup,
+		Consumer: d.consumer,
+		Streams:  []string{Stream, ">"},
+		Count:    readCount,
+		// don't block longer than the retry interval, so failed events are retried in time
+		Block: min(readBlock, d.retry),
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return
+	}
+	for _, stream := range res {
+		if err = d.deliver(stream.Messages); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// deliver sends events to the sink and acknowledges the delivered ones, failed events stay pending and are retried later
+func (d *dispatcher) deliver(msgs []redis.XMessage) (err error) {
+	for _, msg := range msgs {
+		data, _ := msg.Values[streamField].(string)
+		var event Event
+		if uErr := json.Unmarshal([]byte(data), &event); uErr != nil {
+			log.Warn("invalid event, skipped", zap.String("id", msg.ID), zap.Error(uErr))
+		} else {
+			if event.Id == "" {
+				// events published before ids were added
+				event.Id = msg.ID
+			}
+			ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+			sErr := d.sink.Send(ctx, event)
+			cancel()
+			if sErr != nil {
+				if d.ctx.Err() != nil {
+					return d.ctx.Err()
+				}
+				log.Warn("can't deliver the event, will retry", zap.String("id", msg.ID), zap.String("type", event.Type), zap.Error(sErr))
+				continue
+			}
+		}
+		if err = d.cl.XAck(d.ctx, Stream, consumerGroup, msg.ID).Err(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (d *dispatcher) Close(ctx context.Context) (err error) {
+	if d.ctxCancel != nil {
+		d.ctxCancel()
+	}
+	if d.done != nil {
+		<-d.done
+	}
+	return
+}
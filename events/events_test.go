@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/testutil/accounttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/redisprovider"
+	"github.com/anyproto/any-sync-filenode/redisprovider/testredisprovider"
+)
+
+var ctx = context.Background()
+
+func TestDispatcher_Deliver(t *testing.T) {
+	sink := &testSink{}
+	fx := newFixture(t, sink, Config{})
+	defer fx.finish(t)
+
+	require.NoError(t, Publish(ctx, fx.cl, DefaultStreamMaxLen, Event{Type: TypeFileBound, GroupId: "g1", SpaceId: "s1", FileId: "f1", Size: 10, CidCount: 2}))
+	require.NoError(t, Publish(ctx, fx.cl, DefaultStreamMaxLen, Event{Type: TypeSpaceDeleted, GroupId: "g1", SpaceId: "s1"}))
+
+	assert.Eventually(t, func() bool {
+		return len(sink.delivered()) == 2
+	}, time.Second*10, time.Millisecond*10)
+	events := sink.delivered()
+	assert.Equal(t, TypeFileBound, events[0].Type)
+	assert.Equal(t, uint64(10), events[0].Size)
+	assert.NotEmpty(t, events[0].Id)
+	assert.False(t, events[0].Time.IsZero())
+	assert.Equal(t, TypeSpaceDeleted, events[1].Type)
+
+	// everything is acknowledged
+	assert.Eventually(t, func() bool {
+		pending, err := fx.cl.XPending(ctx, Stream, consumerGroup).Result()
+		require.NoError(t, err)
+		return pending.Count == 0
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	sink := &testSink{failures: 2}
+	fx := newFixture(t, sink, Config{RetrySec: 1})
+	defer fx.finish(t)
+
+	require.NoError(t, Publish(ctx, fx.cl, DefaultStreamMaxLen, Event{Type: TypeLimitChanged, GroupId: "g1", Limit: 100}))
+	assert.Eventually(t, func() bool {
+		return len(sink.delivered()) == 1
+	}, time.Second*10, time.Millisecond*50)
+	assert.Equal(t, 3, sink.attempts())
+	assert.Equal(t, uint64(100), sink.delivered()[0].Limit)
+}
+
+func TestDispatcher_Duplicates(t *testing.T) {
+	sink := &testSink{}
+	fx := newFixture(t, sink, Config{})
+	defer fx.finish(t)
+
+	// an event published again after a failure keeps its id
+	data, err := Marshal(Event{Type: TypeFileBound, GroupId: "g1"})
+	require.NoError(t, err)
+	require.NoError(t, PublishData(ctx, fx.cl, DefaultStreamMaxLen, data))
+	require.NoError(t, PublishData(ctx, fx.cl, DefaultStreamMaxLen, data))
+	assert.Eventually(t, func() bool {
+		return len(sink.delivered()) == 2
+	}, time.Second*10, time.Millisecond*10)
+	events := sink.delivered()
+	assert.NotEmpty(t, events[0].Id)
+	assert.Equal(t, events[0].Id, events[1].Id)
+}
+
+type testSink struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	events   []Event
+}
+
+func (s *testSink) Send(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("sink is not available")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *testSink) delivered() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+func (s *testSink) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func newFixture(t *testing.T, sink Sink, conf Config) *fixture {
+	fx := &fixture{
+		dispatcher: NewWithSink(sink).(*dispatcher),
+		a:          new(app.App),
+	}
+	fx.a.Register(&testConfig{conf: conf}).
+		Register(testredisprovider.NewTestRedisProviderNum(10)).
+		Register(&accounttest.AccountTestService{}).
+		Register(fx.dispatcher)
+	require.NoError(t, fx.a.Start(ctx))
+	fx.cl = fx.a.MustComponent(redisprovider.CName).(redisprovider.RedisProvider).Redis()
+	return fx
+}
+
+type fixture struct {
+	*dispatcher
+	a *app.App
+}
+
+func (fx *fixture) finish(t *testing.T) {
+	require.NoError(t, fx.a.Close(ctx))
+}
+
+type testConfig struct {
+	conf Config
+}
+
+func (c *testConfig) Init(a *app.App) (err error) { return }
+func (c *testConfig) Name() string                { return "config" }
+func (c *testConfig) GetEvents() Config           { return c.conf }
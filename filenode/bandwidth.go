@@ -0,0 +1,15 @@
+package filenode
+
+import (
+	"context"
+
+	"github.com/anyproto/any-sync-filenode/index"
+)
+
+// countDownload adds the size to the downloaded bytes of the space, the group storing the space is resolved by the index on flush
+func (fn *fileNode) countDownload(ctx context.Context, spaceId string, size int) {
+	if spaceId == "" || size == 0 {
+		return
+	}
+	fn.index.BandwidthAdd(index.Key{SpaceId: spaceId}, 0, uint64(size))
+}
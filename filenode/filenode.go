@@ -21,6 +21,7 @@ import (
 
 	"github.com/anyproto/any-sync-filenode/config"
 	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/ratelimit"
 	"github.com/anyproto/any-sync-filenode/store"
 )
 
@@ -37,6 +38,14 @@ func New() Service {
 }
 
 type Service interface {
+	// Get returns the block if it exists on the node, with wait it waits until the block is uploaded
+	Get(ctx context.Context, k cid.Cid, wait bool) (blocks.Block, error)
+	// ReadKey checks that the peer from the context is able to read the space and returns its storage key
+	ReadKey(ctx context.Context, spaceId string) (index.Key, error)
+	// FileCopy binds the file of one space to another space without uploading its blocks again
+	FileCopy(ctx context.Context, srcSpaceId, fileId, dstSpaceId string) error
+	// GetBlocks calls the given func for every block as soon as it exists, without wait it fails on the first missing block
+	GetBlocks(ctx context.Context, cids []cid.Cid, wait bool, onBlock func(b blocks.Block) error) error
 	app.Component
 }
 
@@ -48,6 +57,7 @@ type fileNode struct {
 	nodeConf   nodeconf.Service
 	migrateKey string
 	handler    *rpcHandler
+	rateLimit  ratelimit.RateLimiter
 }
 
 func (fn *fileNode) Init(a *app.App) (err error) {
@@ -58,6 +68,7 @@ func (fn *fileNode) Init(a *app.App) (err error) {
 	fn.metric = a.MustComponent(metric.CName).(metric.Metric)
 	fn.migrateKey = a.MustComponent(config.CName).(*config.Config).CafeMigrateKey
 	fn.nodeConf = a.MustComponent(nodeconf.CName).(nodeconf.Service)
+	fn.rateLimit, _ = a.Component(ratelimit.CName).(ratelimit.RateLimiter)
 	return fileproto.DRPCRegisterFile(a.MustComponent(server.CName).(server.DRPCServer), fn.handler)
 }
 
@@ -68,7 +79,7 @@ func (fn *fileNode) Name() (name string) {
 func (fn *fileNode) Get(ctx context.Context, k cid.Cid, wait bool) (blocks.Block, error) {
 	if wait {
 		if err := fn.index.WaitCidExists(ctx, k); err != nil {
-			return nil, err
+			return nil, waitError(err)
 		}
 	} else {
 		exists, err := fn.index.CidExists(ctx, k)
@@ -82,14 +93,59 @@ func (fn *fileNode) Get(ctx context.Context, k cid.Cid, wait bool) (blocks.Block
 	return fn.store.Get(ctx, k)
 }
 
-func (fn *fileNode) Add(ctx context.Context, spaceId string, fileId string, bs []blocks.Block) error {
+func (fn *fileNode) GetBlocks(ctx context.Context, cids []cid.Cid, wait bool, onBlock func(b blocks.Block) error) error {
+	if !wait {
+		for _, c := range cids {
+			b, err := fn.Get(ctx, c, false)
+			if err != nil {
+				return err
+			}
+			if err = onBlock(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := fn.index.WaitCidsExist(ctx, cids, func(c cid.Cid) error {
+		b, err := fn.store.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		return onBlock(b)
+	})
+	return waitError(err)
+}
+
+// waitError converts errors of waiting for cids to rpc errors
+func waitError(err error) error {
+	if errors.Is(err, index.ErrCidWaitTimeout) {
+		return fileprotoerr.ErrCIDNotFound
+	} else if errors.Is(err, index.ErrTooManyWaiters) {
+		return ratelimit.ErrRateLimited
+	}
+	return err
+}
+
+func (fn *fileNode) Add(ctx context.Context, spaceId string, fileId string, bs []blocks.Block) (err error) {
 	if fileId != "" && fileId == fn.migrateKey {
 		return fn.MigrateCafe(ctx, bs)
 	}
-	storeKey, err := fn.StoreKey(ctx, spaceId, true)
+	// the limits and the status of the space are checked by the reservation
+	storeKey, err := fn.StoreKey(ctx, spaceId)
+	if err != nil {
+		return err
+	}
+	cidSizes := make([]index.CidSize, len(bs))
+	for i, b := range bs {
+		cidSizes[i] = index.CidSize{Cid: b.Cid(), Size: uint64(len(b.RawData()))}
+	}
+	reservation, err := fn.reserve(ctx, storeKey, cidSizes)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		fn.finishReservation(ctx, reservation, err)
+	}()
 	unlock, err := fn.index.BlocksLock(ctx, bs)
 	if err != nil {
 		return err
@@ -99,7 +155,16 @@ func (fn *fileNode) Add(ctx context.Context, spaceId string, fileId string, bs [
 	if err != nil {
 		return err
 	}
+	var journalId string
 	if len(toUpload) > 0 {
+		// journal the blocks, so they will be removed if the bind fails
+		uploadCids := make([]cid.Cid, len(toUpload))
+		for i, b := range toUpload {
+			uploadCids[i] = b.Cid()
+		}
+		if journalId, err = fn.index.UploadJournalAdd(ctx, uploadCids); err != nil {
+			return err
+		}
 		if err = fn.store.Add(ctx, toUpload); err != nil {
 			return err
 		}
@@ -113,13 +178,26 @@ func (fn *fileNode) Add(ctx context.Context, spaceId string, fileId string, bs [
 		return err
 	}
 	defer cidEntries.Release()
-	return fn.index.FileBind(ctx, storeKey, fileId, cidEntries)
+	if err = fn.index.FileBind(ctx, storeKey, fileId, cidEntries); err != nil {
+		return err
+	}
+	if err = fn.index.UploadJournalClear(ctx, journalId); err != nil {
+		// the sweeper will skip the bound blocks
+		log.WarnCtx(ctx, "can't clear the upload journal", zap.String("journalId", journalId), zap.Error(err))
+		err = nil
+	}
+	var uploaded uint64
+	for _, b := range bs {
+		uploaded += uint64(len(b.RawData()))
+	}
+	fn.index.BandwidthAdd(storeKey, uploaded, 0)
+	return nil
 }
 
 func (fn *fileNode) Check(ctx context.Context, spaceId string, cids ...cid.Cid) (result []*fileproto.BlockAvailability, err error) {
 	var storeKey index.Key
 	if spaceId != "" {
-		if storeKey, err = fn.StoreKey(ctx, spaceId, false); err != nil {
+		if storeKey, err = fn.StoreKey(ctx, spaceId); err != nil {
 			return
 		}
 	}
@@ -154,8 +232,43 @@ func (fn *fileNode) Check(ctx context.Context, spaceId string, cids ...cid.Cid)
 	return
 }
 
+// FileCopy binds the cids of the file to the target space under the same file id, blocks in the storage are not touched.
+// The peer must be able to read the source space and write to the target one, the target limit is checked as for uploads
+func (fn *fileNode) FileCopy(ctx context.Context, srcSpaceId, fileId, dstSpaceId string) (err error) {
+	srcKey, err := fn.ReadKey(ctx, srcSpaceId)
+	if err != nil {
+		return err
+	}
+	dstKey, err := fn.StoreKey(ctx, dstSpaceId)
+	if err != nil {
+		return err
+	}
+	cidEntries, err := fn.index.FileCidEntries(ctx, srcKey, fileId)
+	if err != nil {
+		return copyError(err)
+	}
+	defer cidEntries.Release()
+	reservation, err := fn.reserve(ctx, dstKey, cidEntries.Sizes())
+	if err != nil {
+		return err
+	}
+	err = fn.index.FileCopy(ctx, srcKey, dstKey, fileId, cidEntries)
+	fn.finishReservation(ctx, reservation, err)
+	return copyError(err)
+}
+
+// copyError converts index errors of copying a file to rpc errors
+func copyError(err error) error {
+	if errors.Is(err, index.ErrFileNotExists) || errors.Is(err, index.ErrCidsNotExist) {
+		return fileprotoerr.ErrCIDNotFound
+	} else if errors.Is(err, index.ErrSpaceDeleted) || errors.Is(err, index.ErrSpaceReadOnly) {
+		return fileprotoerr.ErrForbidden
+	}
+	return err
+}
+
 func (fn *fileNode) BlocksBind(ctx context.Context, spaceId, fileId string, cids ...cid.Cid) (err error) {
-	storeKey, err := fn.StoreKey(ctx, spaceId, true)
+	storeKey, err := fn.StoreKey(ctx, spaceId)
 	if err != nil {
 		return err
 	}
@@ -164,10 +277,25 @@ func (fn *fileNode) BlocksBind(ctx context.Context, spaceId, fileId string, cids
 		return err
 	}
 	defer cidEntries.Release()
-	return fn.index.FileBind(ctx, storeKey, fileId, cidEntries)
+	reservation, err := fn.reserve(ctx, storeKey, cidEntries.Sizes())
+	if err != nil {
+		return err
+	}
+	err = fn.index.FileBind(ctx, storeKey, fileId, cidEntries)
+	fn.finishReservation(ctx, reservation, err)
+	return err
+}
+
+func (fn *fileNode) StoreKey(ctx context.Context, spaceId string) (storageKey index.Key, err error) {
+	return fn.storeKey(ctx, spaceId, true)
+}
+
+// ReadKey returns the storage key of the space if the peer from the context is able to read it
+func (fn *fileNode) ReadKey(ctx context.Context, spaceId string) (storageKey index.Key, err error) {
+	return fn.storeKey(ctx, spaceId, false)
 }
 
-func (fn *fileNode) StoreKey(ctx context.Context, spaceId string, checkLimit bool) (storageKey index.Key, err error) {
+func (fn *fileNode) storeKey(ctx context.Context, spaceId string, write bool) (storageKey index.Key, err error) {
 	if spaceId == "" {
 		return storageKey, fileprotoerr.ErrForbidden
 	}
@@ -194,30 +322,40 @@ func (fn *fileNode) StoreKey(ctx context.Context, spaceId string, checkLimit boo
 			log.WarnCtx(ctx, "acl permissions error", zap.Error(err))
 			return storageKey, fileprotoerr.ErrForbidden
 		}
-		if !permissions.CanWrite() {
+		if write && !permissions.CanWrite() || permissions.NoPermissions() {
 			return storageKey, fileprotoerr.ErrForbidden
 		}
 	}
+	// the peer has access to the space, so the request can spend its budget
+	if err = ratelimit.AllowSpace(ctx, fn.rateLimit, spaceId); err != nil {
+		return storageKey, err
+	}
 
 	if e := fn.index.Migrate(ctx, storageKey); e != nil {
 		log.WarnCtx(ctx, "space migrate error", zap.String("spaceId", spaceId), zap.Error(e))
 	}
 
-          if checkLimit {
-		if err = fn.index.CheckLimits(ctx, storageKey); err != nil {
-			if errors.Is(err, index.ErrLimitExceed) {
-				return storageKey, fileprotoerr.ErrSpaceLimitExceeded
-			} else {
-				log.WarnCtx(ctx, "check limit error", zap.Error(err))
-				return storageKey, fileprotoerr.ErrUnexpected
-			}
+	if err = fn.index.CheckSpace(ctx, storageKey); err != nil {
+		if errors.Is(err, index.ErrSpaceDeleted) {
+			return storageKey, fileprotoerr.ErrForbidden
+		}
+		log.WarnCtx(ctx, "check space error", zap.Error(err))
+		return storageKey, fileprotoerr.ErrUnexpected
+	}
+
+	// the owner of the space has changed, move the space to the group of the new owner;
+	// it's checked on writes of the owner only, so reads and members don't pay for it and can't start a transfer
+	if write && identity.Account() == storageKey.GroupId {
+		if err = fn.index.CheckSpaceGroup(ctx, storageKey); err != nil {
+			log.WarnCtx(ctx, "check space group error", zap.String("spaceId", spaceId), zap.Error(err))
+			return storageKey, fileprotoerr.ErrUnexpected
 		}
 	}
 	return
 }
 
 func (fn *fileNode) SpaceInfo(ctx context.Context, spaceId string) (info *fileproto.SpaceInfoResponse, err error) {
-	storageKey, err := fn.StoreKey(ctx, spaceId, false)
+	storageKey, err := fn.StoreKey(ctx, spaceId)
 	if err != nil {
 		return nil, err
 	}
@@ -284,7 +422,7 @@ func (fn *fileNode) spaceInfo(ctx context.Context, key index.Key, groupInfo inde
 }
 
 func (fn *fileNode) FilesDelete(ctx context.Context, spaceId string, fileIds []string) (err error) {
-	storeKey, err := fn.StoreKey(ctx, spaceId, false)
+	storeKey, err := fn.StoreKey(ctx, spaceId)
 	if err != nil {
 		return
 	}
@@ -292,7 +430,7 @@ func (fn *fileNode) FilesDelete(ctx context.Context, spaceId string, fileIds []s
 }
 
 func (fn *fileNode) FileInfo(ctx context.Context, spaceId string, fileIds ...string) (info []*fileproto.FileInfo, err error) {
-	storeKey, err := fn.StoreKey(ctx, spaceId, false)
+	storeKey, err := fn.StoreKey(ctx, spaceId)
 	if err != nil {
 		return
 	}
@@ -345,7 +483,7 @@ func (fn *fileNode) AccountLimitSet(ctx context.Context, identity string, limit
 }
 
 func (fn *fileNode) SpaceLimitSet(ctx context.Context, spaceId string, limit uint64) (err error) {
-	storeKey, err := fn.StoreKey(ctx, spaceId, false)
+	storeKey, err := fn.StoreKey(ctx, spaceId)
 	// INFO: when this function is called, SpaceLimitSet it will overwrite the limit
         limit = 1000000000000
         if err != nil {
@@ -355,7 +493,7 @@ func (fn *fileNode) SpaceLimitSet(ctx context.Context, spaceId string, limit uin
 }
 
 func (fn *fileNode) FilesGet(ctx context.Context, spaceId string) (fileIds []string, err error) {
-	storeKey, err := fn.StoreKey(ctx, spaceId, false)
+	storeKey, err := fn.StoreKey(ctx, spaceId)
 	if err != nil {
 		return
 	}
@@ -2,6 +2,7 @@ package filenode
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/anyproto/any-sync/acl"
@@ -10,6 +11,7 @@ import (
 	"github.com/anyproto/any-sync/commonfile/fileblockstore"
 	"github.com/anyproto/any-sync/commonfile/fileproto"
 	"github.com/anyproto/any-sync/commonfile/fileproto/fileprotoerr"
+	"github.com/anyproto/any-sync/commonspace/object/acl/list"
 	"github.com/anyproto/any-sync/metric"
 	"github.com/anyproto/any-sync/net/peer"
 	"github.com/anyproto/any-sync/net/rpc/server"
@@ -40,15 +42,22 @@ func TestFileNode_Add(t *testing.T) {
 		)
 
 		fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
-		fx.index.EXPECT().CheckLimits(ctx, storeKey)
 		fx.index.EXPECT().Migrate(ctx, storeKey)
+		fx.index.EXPECT().CheckSpace(ctx, storeKey)
+		fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
+		reservation := &index.Reservation{Key: storeKey, Id: "r1", Size: uint64(len(b.RawData()))}
+		fx.index.EXPECT().Reserve(ctx, storeKey, []index.CidSize{{Cid: b.Cid(), Size: uint64(len(b.RawData()))}}).Return(reservation, nil)
 		fx.index.EXPECT().BlocksLock(ctx, []blocks.Block{b}).Return(func() {}, nil)
 		fx.index.EXPECT().BlocksGetNonExistent(ctx, []blocks.Block{b}).Return([]blocks.Block{b}, nil)
+		fx.index.EXPECT().UploadJournalAdd(ctx, []cid.Cid{b.Cid()}).Return("j1", nil)
 		fx.store.EXPECT().Add(ctx, []blocks.Block{b})
 		fx.index.EXPECT().BlocksAdd(ctx, []blocks.Block{b})
 		fx.index.EXPECT().CidEntriesByBlocks(ctx, []blocks.Block{b}).Return(&index.CidEntries{}, nil)
 		fx.index.EXPECT().FileBind(ctx, storeKey, fileId, gomock.Any())
+		fx.index.EXPECT().UploadJournalClear(ctx, "j1")
 		fx.index.EXPECT().OnBlockUploaded(ctx, []blocks.Block{b})
+		fx.index.EXPECT().BandwidthAdd(storeKey, uint64(len(b.RawData())), uint64(0))
+		fx.index.EXPECT().ReservationCommit(ctx, reservation)
 
 		resp, err := fx.handler.BlockPush(ctx, &fileproto.BlockPushRequest{
 			SpaceId: storeKey.SpaceId,
@@ -60,7 +69,7 @@ func TestFileNode_Add(t *testing.T) {
 		require.NotNil(t, resp)
 	})
 
-	t.Run("limit exceed", func(t *testing.T) {
+	t.Run("reservation exceeds limit", func(t *testing.T) {
 		fx := newFixture(t)
 		defer fx.Finish(t)
 		var (
@@ -71,7 +80,9 @@ func TestFileNode_Add(t *testing.T) {
 
 		fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
 		fx.index.EXPECT().Migrate(ctx, storeKey)
-		fx.index.EXPECT().CheckLimits(ctx, storeKey).Return(index.ErrLimitExceed)
+		fx.index.EXPECT().CheckSpace(ctx, storeKey)
+		fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
+		fx.index.EXPECT().Reserve(ctx, storeKey, gomock.Any()).Return(nil, index.ErrLimitExceed)
 
 		resp, err := fx.handler.BlockPush(ctx, &fileproto.BlockPushRequest{
 			SpaceId: storeKey.SpaceId,
@@ -82,6 +93,81 @@ func TestFileNode_Add(t *testing.T) {
 		require.EqualError(t, err, fileprotoerr.ErrSpaceLimitExceeded.Error())
 		require.Nil(t, resp)
 	})
+	t.Run("reservation released on failure", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		var (
+			ctx, storeKey = newRandKey()
+			fileId        = testutil.NewRandCid().String()
+			b             = testutil.NewRandBlock(1024)
+		)
+
+		fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, storeKey)
+		fx.index.EXPECT().CheckSpace(ctx, storeKey)
+		fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
+		reservation := &index.Reservation{Key: storeKey, Id: "r1", Size: uint64(len(b.RawData()))}
+		fx.index.EXPECT().Reserve(ctx, storeKey, gomock.Any()).Return(reservation, nil)
+		fx.index.EXPECT().BlocksLock(ctx, []blocks.Block{b}).Return(func() {}, nil)
+		fx.index.EXPECT().BlocksGetNonExistent(ctx, []blocks.Block{b}).Return([]blocks.Block{b}, nil)
+		fx.index.EXPECT().UploadJournalAdd(ctx, []cid.Cid{b.Cid()}).Return("j1", nil)
+		fx.store.EXPECT().Add(ctx, []blocks.Block{b}).Return(errors.New("s3 is down"))
+		fx.index.EXPECT().ReservationRelease(ctx, reservation)
+
+		_, err := fx.handler.BlockPush(ctx, &fileproto.BlockPushRequest{
+			SpaceId: storeKey.SpaceId,
+			FileId:  fileId,
+			Cid:     b.Cid().Bytes(),
+			Data:    b.RawData(),
+		})
+		require.Error(t, err)
+	})
+	t.Run("space read only", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		var (
+			ctx, storeKey = newRandKey()
+			fileId        = testutil.NewRandCid().String()
+			b             = testutil.NewRandBlock(1024)
+		)
+
+		fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, storeKey)
+		fx.index.EXPECT().CheckSpace(ctx, storeKey)
+		fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
+		fx.index.EXPECT().Reserve(ctx, storeKey, gomock.Any()).Return(nil, index.ErrSpaceReadOnly)
+
+		resp, err := fx.handler.BlockPush(ctx, &fileproto.BlockPushRequest{
+			SpaceId: storeKey.SpaceId,
+			FileId:  fileId,
+			Cid:     b.Cid().Bytes(),
+			Data:    b.RawData(),
+		})
+		require.EqualError(t, err, fileprotoerr.ErrForbidden.Error())
+		require.Nil(t, resp)
+	})
+	t.Run("space deleted", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		var (
+			ctx, storeKey = newRandKey()
+			fileId        = testutil.NewRandCid().String()
+			b             = testutil.NewRandBlock(1024)
+		)
+
+		fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, storeKey)
+		fx.index.EXPECT().CheckSpace(ctx, storeKey).Return(index.ErrSpaceDeleted)
+
+		resp, err := fx.handler.BlockPush(ctx, &fileproto.BlockPushRequest{
+			SpaceId: storeKey.SpaceId,
+			FileId:  fileId,
+			Cid:     b.Cid().Bytes(),
+			Data:    b.RawData(),
+		})
+		require.EqualError(t, err, fileprotoerr.ErrForbidden.Error())
+		require.Nil(t, resp)
+	})
 	t.Run("invalid cid", func(t *testing.T) {
 		fx := newFixture(t)
 		defer fx.Finish(t)
@@ -132,6 +218,7 @@ func TestFileNode_Get(t *testing.T) {
 		b := testutil.NewRandBlock(10)
 		fx.index.EXPECT().CidExists(gomock.Any(), b.Cid()).Return(true, nil)
 		fx.store.EXPECT().Get(ctx, b.Cid()).Return(b, nil)
+		fx.index.EXPECT().BandwidthAdd(index.Key{SpaceId: spaceId}, uint64(0), uint64(len(b.RawData())))
 		resp, err := fx.handler.BlockGet(ctx, &fileproto.BlockGetRequest{
 			SpaceId: spaceId,
 			Cid:     b.Cid().Bytes(),
@@ -153,6 +240,73 @@ func TestFileNode_Get(t *testing.T) {
 		require.EqualError(t, err, fileblockstore.ErrCIDNotFound.Error())
 		assert.Nil(t, resp)
 	})
+	t.Run("wait timeout", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		ctx, key := newRandKey()
+		b := testutil.NewRandBlock(10)
+		fx.index.EXPECT().WaitCidExists(gomock.Any(), b.Cid()).Return(index.ErrCidWaitTimeout)
+		resp, err := fx.handler.BlockGet(ctx, &fileproto.BlockGetRequest{
+			SpaceId: key.SpaceId,
+			Cid:     b.Cid().Bytes(),
+			Wait:    true,
+		})
+		require.EqualError(t, err, fileblockstore.ErrCIDNotFound.Error())
+		assert.Nil(t, resp)
+	})
+}
+
+func TestFileNode_GetBlocks(t *testing.T) {
+	t.Run("wait", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		ctx, _ := newRandKey()
+		bs := testutil.NewRandBlocks(2)
+		cids := []cid.Cid{bs[0].Cid(), bs[1].Cid()}
+		fx.index.EXPECT().WaitCidsExist(ctx, cids, gomock.Any()).DoAndReturn(func(ctx context.Context, cids []cid.Cid, onExists func(c cid.Cid) error) error {
+			// blocks are reported in the order of upload
+			require.NoError(t, onExists(cids[1]))
+			return onExists(cids[0])
+		})
+		fx.store.EXPECT().Get(ctx, bs[1].Cid()).Return(bs[1], nil)
+		fx.store.EXPECT().Get(ctx, bs[0].Cid()).Return(bs[0], nil)
+
+		var got []blocks.Block
+		require.NoError(t, fx.GetBlocks(ctx, cids, true, func(b blocks.Block) error {
+			got = append(got, b)
+			return nil
+		}))
+		assert.Equal(t, []blocks.Block{bs[1], bs[0]}, got)
+	})
+	t.Run("wait timeout", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		ctx, _ := newRandKey()
+		bs := testutil.NewRandBlocks(1)
+		fx.index.EXPECT().WaitCidsExist(ctx, []cid.Cid{bs[0].Cid()}, gomock.Any()).Return(index.ErrCidWaitTimeout)
+
+		err := fx.GetBlocks(ctx, []cid.Cid{bs[0].Cid()}, true, func(b blocks.Block) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, fileprotoerr.ErrCIDNotFound)
+	})
+	t.Run("no wait", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		ctx, _ := newRandKey()
+		bs := testutil.NewRandBlocks(2)
+		fx.index.EXPECT().CidExists(ctx, bs[0].Cid()).Return(true, nil)
+		fx.store.EXPECT().Get(ctx, bs[0].Cid()).Return(bs[0], nil)
+		fx.index.EXPECT().CidExists(ctx, bs[1].Cid()).Return(false, nil)
+
+		var got int
+		err := fx.GetBlocks(ctx, []cid.Cid{bs[0].Cid(), bs[1].Cid()}, false, func(b blocks.Block) error {
+			got++
+			return nil
+		})
+		assert.ErrorIs(t, err, fileprotoerr.ErrCIDNotFound)
+		assert.Equal(t, 1, got)
+	})
 }
 
 func TestFileNode_Check(t *testing.T) {
@@ -166,6 +320,8 @@ func TestFileNode_Check(t *testing.T) {
 	}
 	fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
 	fx.index.EXPECT().Migrate(ctx, storeKey)
+	fx.index.EXPECT().CheckSpace(ctx, storeKey)
+	fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
 	fx.index.EXPECT().CidExistsInSpace(ctx, storeKey, testutil.BlocksToKeys(bs)).Return(testutil.BlocksToKeys(bs[:1]), nil)
 	fx.index.EXPECT().CidExists(ctx, bs[1].Cid()).Return(true, nil)
 	fx.index.EXPECT().CidExists(ctx, bs[2].Cid()).Return(false, nil)
@@ -197,9 +353,11 @@ func TestFileNode_BlocksBind(t *testing.T) {
 	}
 
 	fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
-	fx.index.EXPECT().CheckLimits(ctx, storeKey)
 	fx.index.EXPECT().Migrate(ctx, storeKey)
+	fx.index.EXPECT().CheckSpace(ctx, storeKey)
+	fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
 	fx.index.EXPECT().CidEntries(ctx, cids).Return(cidEntries, nil)
+	fx.index.EXPECT().Reserve(ctx, storeKey, gomock.Any())
 	fx.index.EXPECT().FileBind(ctx, storeKey, fileId, cidEntries)
 
 	resp, err := fx.handler.BlocksBind(ctx, &fileproto.BlocksBindRequest{
@@ -212,6 +370,118 @@ func TestFileNode_BlocksBind(t *testing.T) {
 
 }
 
+func TestFileNode_FileCopy(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		var (
+			ctx, dstKey = newRandKey()
+			_, srcKey   = newRandKey()
+			fileId      = testutil.NewRandCid().String()
+			cidEntries  = &index.CidEntries{}
+		)
+		_, srcOwner, _ := crypto.GenerateRandomEd25519KeyPair()
+		srcKey.GroupId = srcOwner.Account()
+
+		fx.aclService.EXPECT().OwnerPubKey(ctx, srcKey.SpaceId).Return(srcOwner, nil)
+		fx.aclService.EXPECT().Permissions(ctx, mustPubKey(ctx), srcKey.SpaceId).Return(list.AclPermissionsReader, nil)
+		fx.index.EXPECT().Migrate(ctx, srcKey)
+		fx.index.EXPECT().CheckSpace(ctx, srcKey)
+		fx.aclService.EXPECT().OwnerPubKey(ctx, dstKey.SpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, dstKey)
+		fx.index.EXPECT().CheckSpace(ctx, dstKey)
+		fx.index.EXPECT().CheckSpaceGroup(ctx, dstKey)
+		fx.index.EXPECT().FileCidEntries(ctx, srcKey, fileId).Return(cidEntries, nil)
+		fx.index.EXPECT().Reserve(ctx, dstKey, gomock.Any())
+		fx.index.EXPECT().FileCopy(ctx, srcKey, dstKey, fileId, cidEntries)
+
+		require.NoError(t, fx.FileCopy(ctx, srcKey.SpaceId, fileId, dstKey.SpaceId))
+	})
+	t.Run("no access to source", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		var (
+			ctx, _    = newRandKey()
+			_, srcKey = newRandKey()
+		)
+		_, srcOwner, _ := crypto.GenerateRandomEd25519KeyPair()
+
+		fx.aclService.EXPECT().OwnerPubKey(ctx, srcKey.SpaceId).Return(srcOwner, nil)
+		fx.aclService.EXPECT().Permissions(ctx, mustPubKey(ctx), srcKey.SpaceId).Return(list.AclPermissionsNone, nil)
+
+		err := fx.FileCopy(ctx, srcKey.SpaceId, "fileId", testutil.NewRandSpaceId())
+		require.ErrorIs(t, err, fileprotoerr.ErrForbidden)
+	})
+	t.Run("target limit exceeded", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		var (
+			ctx, key   = newRandKey()
+			dstSpaceId = testutil.NewRandSpaceId()
+			dstKey     = index.Key{GroupId: key.GroupId, SpaceId: dstSpaceId}
+			cidEntries = &index.CidEntries{}
+		)
+
+		fx.aclService.EXPECT().OwnerPubKey(ctx, key.SpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, key)
+		fx.index.EXPECT().CheckSpace(ctx, key)
+		fx.aclService.EXPECT().OwnerPubKey(ctx, dstSpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, dstKey)
+		fx.index.EXPECT().CheckSpace(ctx, dstKey)
+		fx.index.EXPECT().CheckSpaceGroup(ctx, dstKey)
+		fx.index.EXPECT().FileCidEntries(ctx, key, "fileId").Return(cidEntries, nil)
+		fx.index.EXPECT().Reserve(ctx, dstKey, gomock.Any()).Return(nil, index.ErrLimitExceed)
+
+		err := fx.FileCopy(ctx, key.SpaceId, "fileId", dstSpaceId)
+		require.ErrorIs(t, err, fileprotoerr.ErrSpaceLimitExceeded)
+	})
+	t.Run("file not exists", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		var (
+			ctx, key   = newRandKey()
+			dstSpaceId = testutil.NewRandSpaceId()
+			dstKey     = index.Key{GroupId: key.GroupId, SpaceId: dstSpaceId}
+		)
+
+		fx.aclService.EXPECT().OwnerPubKey(ctx, key.SpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, key)
+		fx.index.EXPECT().CheckSpace(ctx, key)
+		fx.aclService.EXPECT().OwnerPubKey(ctx, dstSpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, dstKey)
+		fx.index.EXPECT().CheckSpace(ctx, dstKey)
+		fx.index.EXPECT().CheckSpaceGroup(ctx, dstKey)
+		fx.index.EXPECT().FileCidEntries(ctx, key, "fileId").Return(nil, index.ErrFileNotExists)
+
+		err := fx.FileCopy(ctx, key.SpaceId, "fileId", dstSpaceId)
+		require.ErrorIs(t, err, fileprotoerr.ErrCIDNotFound)
+	})
+	t.Run("target deleted", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		var (
+			ctx, key   = newRandKey()
+			dstSpaceId = testutil.NewRandSpaceId()
+			dstKey     = index.Key{GroupId: key.GroupId, SpaceId: dstSpaceId}
+			cidEntries = &index.CidEntries{}
+		)
+
+		fx.aclService.EXPECT().OwnerPubKey(ctx, key.SpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, key)
+		fx.index.EXPECT().CheckSpace(ctx, key)
+		fx.aclService.EXPECT().OwnerPubKey(ctx, dstSpaceId).Return(mustPubKey(ctx), nil)
+		fx.index.EXPECT().Migrate(ctx, dstKey)
+		fx.index.EXPECT().CheckSpace(ctx, dstKey)
+		fx.index.EXPECT().CheckSpaceGroup(ctx, dstKey)
+		fx.index.EXPECT().FileCidEntries(ctx, key, "fileId").Return(cidEntries, nil)
+		fx.index.EXPECT().Reserve(ctx, dstKey, gomock.Any())
+		fx.index.EXPECT().FileCopy(ctx, key, dstKey, "fileId", cidEntries).Return(index.ErrSpaceDeleted)
+
+		err := fx.FileCopy(ctx, key.SpaceId, "fileId", dstSpaceId)
+		require.ErrorIs(t, err, fileprotoerr.ErrForbidden)
+	})
+}
+
 func TestFileNode_FileInfo(t *testing.T) {
 	fx := newFixture(t)
 	defer fx.Finish(t)
@@ -223,6 +493,8 @@ func TestFileNode_FileInfo(t *testing.T) {
 	)
 	fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
 	fx.index.EXPECT().Migrate(ctx, storeKey)
+	fx.index.EXPECT().CheckSpace(ctx, storeKey)
+	fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
 	fx.index.EXPECT().FileInfo(ctx, storeKey, fileId1, fileId2).Return([]index.FileInfo{{1, 1}, {2, 2}}, nil)
 
 	resp, err := fx.handler.FilesInfo(ctx, &fileproto.FilesInfoRequest{
@@ -292,6 +564,8 @@ func TestFileNode_SpaceInfo(t *testing.T) {
 	)
 	fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
 	fx.index.EXPECT().Migrate(ctx, storeKey)
+	fx.index.EXPECT().CheckSpace(ctx, storeKey)
+	fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
 
 	fx.index.EXPECT().GroupInfo(ctx, storeKey.GroupId).Return(index.GroupInfo{
 		BytesUsage:   100,
@@ -342,6 +616,8 @@ func TestFileNode_SpaceLimitSet(t *testing.T) {
 
 	fx.aclService.EXPECT().OwnerPubKey(ctx, storeKey.SpaceId).Return(mustPubKey(ctx), nil)
 	fx.index.EXPECT().Migrate(ctx, storeKey)
+	fx.index.EXPECT().CheckSpace(ctx, storeKey)
+	fx.index.EXPECT().CheckSpaceGroup(ctx, storeKey)
 	fx.index.EXPECT().SetSpaceLimit(ctx, storeKey, uint64(12345))
 	require.NoError(t, fx.SpaceLimitSet(ctx, storeKey.SpaceId, 12345))
 }
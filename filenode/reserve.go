@@ -0,0 +1,42 @@
+package filenode
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anyproto/any-sync/commonfile/fileproto/fileprotoerr"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index"
+)
+
+// reserve reserves the size of the upload against the limit of the group or the isolated space
+func (fn *fileNode) reserve(ctx context.Context, storeKey index.Key, cids []index.CidSize) (reservation *index.Reservation, err error) {
+	if reservation, err = fn.index.Reserve(ctx, storeKey, cids); err != nil {
+		if errors.Is(err, index.ErrLimitExceed) {
+			return nil, fileprotoerr.ErrSpaceLimitExceeded
+		} else if errors.Is(err, index.ErrSpaceReadOnly) {
+			return nil, fileprotoerr.ErrForbidden
+		}
+		log.WarnCtx(ctx, "reserve error", zap.Error(err))
+		return nil, fileprotoerr.ErrUnexpected
+	}
+	return
+}
+
+// finishReservation commits the reservation when the upload is bound or releases it otherwise
+func (fn *fileNode) finishReservation(ctx context.Context, reservation *index.Reservation, uploadErr error) {
+	if reservation == nil {
+		return
+	}
+	var err error
+	if uploadErr == nil {
+		err = fn.index.ReservationCommit(ctx, reservation)
+	} else {
+		err = fn.index.ReservationRelease(ctx, reservation)
+	}
+	if err != nil {
+		// the reservation will expire, so the limit is blocked only temporarily
+		log.WarnCtx(ctx, "can't finish the reservation", zap.String("spaceId", reservation.Key.SpaceId), zap.Error(err))
+	}
+}
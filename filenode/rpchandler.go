@@ -10,6 +10,8 @@ import (
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
 	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/ratelimit"
 )
 
 const (
@@ -41,6 +43,10 @@ func (r rpcHandler) BlockGet(ctx context.Context, req *fileproto.BlockGetRequest
 			zap.Error(err),
 		)
 	}()
+	// the access to the space is not checked by blockGet, so only the budget of the peer is charged
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "blockGet", 0); err != nil {
+		return nil, err
+	}
 	resp = &fileproto.BlockGetResponse{
 		Cid: req.Cid,
 	}
@@ -54,6 +60,8 @@ func (r rpcHandler) BlockGet(ctx context.Context, req *fileproto.BlockGetRequest
 	} else {
 		resp.Data = b.RawData()
 	}
+	ratelimit.TakeBytes(ctx, r.f.rateLimit, "blockGet", "", len(resp.Data))
+	r.f.countDownload(ctx, req.SpaceId, len(resp.Data))
 	return resp, nil
 }
 
@@ -71,6 +79,9 @@ func (r rpcHandler) BlockPush(ctx context.Context, req *fileproto.BlockPushReque
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "blockPush", len(req.Data)); err != nil {
+		return nil, err
+	}
 	c, err = cid.Cast(req.Cid)
 	if err != nil {
 		return nil, err
@@ -108,6 +119,9 @@ func (r rpcHandler) BlocksCheck(ctx context.Context, req *fileproto.BlocksCheckR
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "blocksCheck", 0); err != nil {
+		return nil, err
+	}
 	availability, err := r.f.Check(ctx, req.SpaceId, convertCids(req.Cids)...)
 	if err != nil {
 		return nil, err
@@ -129,6 +143,9 @@ func (r rpcHandler) BlocksBind(ctx context.Context, req *fileproto.BlocksBindReq
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "blocksBind", 0); err != nil {
+		return nil, err
+	}
 	if err = r.f.BlocksBind(ctx, req.SpaceId, req.FileId, convertCids(req.Cids)...); err != nil {
 		return nil, err
 	}
@@ -146,6 +163,9 @@ func (r rpcHandler) FilesDelete(ctx context.Context, req *fileproto.FilesDeleteR
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "filesDelete", 0); err != nil {
+		return nil, err
+	}
 	if err = r.f.FilesDelete(ctx, req.SpaceId, req.FileIds); err != nil {
 		return nil, err
 	}
@@ -163,6 +183,9 @@ func (r rpcHandler) FilesInfo(ctx context.Context, req *fileproto.FilesInfoReque
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "filesInfo", 0); err != nil {
+		return nil, err
+	}
 	if len(req.FileIds) > fileInfoReqLimit {
 		err = fileprotoerr.ErrQuerySizeExceeded
 		return
@@ -186,6 +209,9 @@ func (r rpcHandler) FilesGet(req *fileproto.FilesGetRequest, stream fileproto.DR
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "filesGet", 0); err != nil {
+		return
+	}
 
 	fileIds, err := r.f.FilesGet(ctx, req.SpaceId)
 	if err != nil {
@@ -202,14 +228,18 @@ func (r rpcHandler) FilesGet(req *fileproto.FilesGetRequest, stream fileproto.DR
 	return
 }
 
-func (r rpcHandler) Check(ctx context.Context, req *fileproto.CheckRequest) (*fileproto.CheckResponse, error) {
+func (r rpcHandler) Check(ctx context.Context, req *fileproto.CheckRequest) (resp *fileproto.CheckResponse, err error) {
 	st := time.Now()
 	defer func() {
 		r.f.metric.RequestLog(ctx,
 			"file.check",
 			metric.TotalDur(time.Since(st)),
+			zap.Error(err),
 		)
 	}()
+	if _, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "check", 0); err != nil {
+		return nil, err
+	}
 	return &fileproto.CheckResponse{
 		SpaceIds:   nil,
 		AllowWrite: true,
@@ -226,6 +256,9 @@ func (r rpcHandler) SpaceInfo(ctx context.Context, req *fileproto.SpaceInfoReque
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "spaceInfo", 0); err != nil {
+		return nil, err
+	}
 	if resp, err = r.f.SpaceInfo(ctx, req.SpaceId); err != nil {
 		return
 	}
@@ -241,6 +274,9 @@ func (r rpcHandler) AccountInfo(ctx context.Context, req *fileproto.AccountInfoR
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "accountInfo", 0); err != nil {
+		return nil, err
+	}
 	if resp, err = r.f.AccountInfo(ctx); err != nil {
 		return
 	}
@@ -258,6 +294,9 @@ func (r rpcHandler) AccountLimitSet(ctx context.Context, req *fileproto.AccountL
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "accountLimitSet", 0); err != nil {
+		return nil, err
+	}
 	if err = r.f.AccountLimitSet(ctx, req.Identity, req.Limit); err != nil {
 		return
 	}
@@ -275,6 +314,9 @@ func (r rpcHandler) SpaceLimitSet(ctx context.Context, req *fileproto.SpaceLimit
 			zap.Error(err),
 		)
 	}()
+	if ctx, err = ratelimit.AllowPeer(ctx, r.f.rateLimit, "spaceLimitSet", 0); err != nil {
+		return nil, err
+	}
 	if err = r.f.SpaceLimitSet(ctx, req.SpaceId, req.Limit); err != nil {
 		return
 	}
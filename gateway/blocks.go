@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/anyproto/any-sync/commonfile/fileproto/fileprotoerr"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/ratelimit"
+)
+
+type blocksRequest struct {
+	Cids []string `json:"cids"`
+	// Wait waits for the blocks that are not uploaded yet instead of failing
+	Wait bool `json:"wait"`
+}
+
+// handleBlocks streams the blocks of the space as a multipart/mixed response, every part is a block with its cid in the Content-Id header.
+// Blocks are sent as soon as they exist, so with wait the blocks of a file being uploaded are delivered in the order of upload.
+// A failure after the first block ends the stream without the closing boundary
+func (g *gateway) handleBlocks(w http.ResponseWriter, r *http.Request) {
+	ctx, storeKey, ok := g.authorize(w, r, "blockGet")
+	if !ok {
+		return
+	}
+	var req blocksRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	cids, ok := decodeCids(w, req.Cids)
+	if !ok || !g.checkBlocksCids(ctx, w, storeKey, cids, req.Wait) {
+		return
+	}
+
+	var (
+		mw   *multipart.Writer
+		rc   = http.NewResponseController(w)
+		size uint64
+	)
+	start := func() {
+		mw = multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+	}
+	err := g.fileNode.GetBlocks(ctx, cids, req.Wait, func(b blocks.Block) error {
+		if mw == nil {
+			start()
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"application/octet-stream"},
+			"Content-Id":   {b.Cid().String()},
+		})
+		if err != nil {
+			return err
+		}
+		data := b.RawData()
+		if _, err = part.Write(data); err != nil {
+			return err
+		}
+		size += uint64(len(data))
+		ratelimit.TakeBytes(ctx, g.rateLimit, "blockGet", storeKey.SpaceId, len(data))
+		return rc.Flush()
+	})
+	if size != 0 {
+		g.index.BandwidthAdd(storeKey, 0, size)
+	}
+	if err != nil {
+		if mw == nil {
+			writeError(w, err)
+		} else {
+			log.DebugCtx(ctx, "can't stream blocks", zap.Error(err))
+		}
+		return
+	}
+	if mw == nil {
+		start()
+	}
+	if err = mw.Close(); err != nil {
+		log.DebugCtx(ctx, "can't write blocks", zap.Error(err))
+	}
+}
+
+// checkBlocksCids makes sure the cids are bound to the space. With wait the cids that are not uploaded yet are allowed,
+// but existing blocks of other spaces are not. The error response is written when the request is not allowed
+func (g *gateway) checkBlocksCids(ctx context.Context, w http.ResponseWriter, storeKey index.Key, cids []cid.Cid, wait bool) bool {
+	if !wait {
+		return g.checkCids(ctx, w, storeKey, cids)
+	}
+	inSpace, err := g.index.CidExistsInSpace(ctx, storeKey, cids)
+	if err != nil {
+		log.WarnCtx(ctx, "cid exists in space error", zap.Error(err))
+		writeError(w, err)
+		return false
+	}
+	bound := make(map[cid.Cid]struct{}, len(inSpace))
+	for _, k := range inSpace {
+		bound[k] = struct{}{}
+	}
+	for _, k := range cids {
+		if _, ok := bound[k]; ok {
+			continue
+		}
+		exists, err := g.index.CidExists(ctx, k)
+		if err != nil {
+			log.WarnCtx(ctx, "cid exists error", zap.Error(err))
+			writeError(w, err)
+			return false
+		}
+		if exists {
+			writeError(w, fileprotoerr.ErrCIDNotFound)
+			return false
+		}
+	}
+	return true
+}
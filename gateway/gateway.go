@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anyproto/any-sync/accountservice"
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/app/logger"
+	"github.com/anyproto/any-sync/commonfile/fileblockstore"
+	"github.com/anyproto/any-sync/commonfile/fileproto/fileprotoerr"
+	"github.com/anyproto/any-sync/net/peer"
+	"github.com/ipfs/go-cid"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/config"
+	"github.com/anyproto/any-sync-filenode/filenode"
+	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/ratelimit"
+	"github.com/anyproto/any-sync-filenode/store"
+)
+
+const CName = "filenode.gateway"
+
+var log = logger.NewNamed(CName)
+
+const (
+	defaultTokenMaxTtl   = time.Hour * 24
+	defaultPresignExpiry = time.Minute * 5
+	// blocks are addressed by their hashes and never change
+	cacheControl = "private, max-age=31536000, immutable"
+)
+
+type configSource interface {
+	GetGateway() config.Gateway
+}
+
+func New() Gateway {
+	return new(gateway)
+}
+
+// Gateway serves blocks over http for clients authenticated by a token signed with the account key
+type Gateway interface {
+	app.ComponentRunnable
+}
+
+type gateway struct {
+	conf        config.Gateway
+	tokenMaxTtl time.Duration
+	peerId      string
+	fileNode    filenode.Service
+	index       index.Index
+	rateLimit   ratelimit.RateLimiter
+	server      *http.Server
+
+	presigner     store.Presigner
+	presignExpiry time.Duration
+}
+
+func (g *gateway) Init(a *app.App) (err error) {
+	g.conf = a.MustComponent(config.CName).(configSource).GetGateway()
+	if g.tokenMaxTtl = time.Duration(g.conf.TokenMaxTtlSec) * time.Second; g.tokenMaxTtl <= 0 {
+		g.tokenMaxTtl = defaultTokenMaxTtl
+	}
+	if g.conf.PresignEnabled {
+		if g.presignExpiry = time.Duration(g.conf.PresignExpirySec) * time.Second; g.presignExpiry <= 0 {
+			g.presignExpiry = defaultPresignExpiry
+		}
+		// the dev store can't presign, the blocks are proxied by the gateway then
+		g.presigner, _ = a.MustComponent(fileblockstore.CName).(store.Presigner)
+	}
+	g.peerId = a.MustComponent(accountservice.CName).(accountservice.Service).Account().PeerId
+	g.fileNode = a.MustComponent(filenode.CName).(filenode.Service)
+	g.index = a.MustComponent(index.CName).(index.Index)
+	g.rateLimit, _ = a.Component(ratelimit.CName).(ratelimit.RateLimiter)
+	return
+}
+
+func (g *gateway) Name() (name string) {
+	return CName
+}
+
+func (g *gateway) Run(ctx context.Context) (err error) {
+	if g.conf.ListenAddr == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", g.conf.ListenAddr)
+	if err != nil {
+		return
+	}
+	g.server = &http.Server{Handler: g.handler(), ReadHeaderTimeout: time.Second * 10}
+	go func() {
+		if e := g.server.Serve(listener); e != nil && !errors.Is(e, http.ErrServerClosed) {
+			log.Error("gateway server error", zap.Error(e))
+		}
+	}()
+	log.Info("gateway server started", zap.String("addr", listener.Addr().String()))
+	return
+}
+
+func (g *gateway) handler() http.Handler {
+	mux := http.NewServeMux()
+	// GET pattern matches HEAD requests as well
+	mux.HandleFunc("GET /block/{cid}", g.handleBlock)
+	mux.HandleFunc("POST /urls", g.handleUrls)
+	mux.HandleFunc("POST /blocks", g.handleBlocks)
+	return mux
+}
+
+func (g *gateway) handleBlock(w http.ResponseWriter, r *http.Request) {
+	k, err := cid.Decode(r.PathValue("cid"))
+	if err != nil {
+		http.Error(w, "invalid cid", http.StatusBadRequest)
+		return
+	}
+	ctx, storeKey, ok := g.authorize(w, r, "blockGet")
+	if !ok || !g.checkCids(ctx, w, storeKey, []cid.Cid{k}) {
+		return
+	}
+
+	etag := `"` + k.String() + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	b, err := g.fileNode.Get(ctx, k, false)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data := b.RawData()
+	ratelimit.TakeBytes(ctx, g.rateLimit, "blockGet", storeKey.SpaceId, len(data))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err = w.Write(data); err != nil {
+		log.DebugCtx(ctx, "can't write block", zap.Error(err))
+		return
+	}
+	g.index.BandwidthAdd(storeKey, 0, uint64(len(data)))
+}
+
+// authorize checks the token, the rate limits of the method and the access to the space.
+// The error response is written when the request is not allowed
+func (g *gateway) authorize(w http.ResponseWriter, r *http.Request, method string) (ctx context.Context, storeKey index.Key, ok bool) {
+	ctx = r.Context()
+	tokenStr, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "token required", http.StatusUnauthorized)
+		return
+	}
+	t, err := parseToken(tokenStr, g.peerId, g.tokenMaxTtl)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return ctx, storeKey, false
+	}
+	identity, err := t.identity.Marshall()
+	if err != nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return ctx, storeKey, false
+	}
+	ctx = peer.CtxWithIdentity(ctx, identity)
+	// the account of the token is limited as a peer
+	ctx = peer.CtxWithPeerId(ctx, t.identity.Account())
+	// the space is charged by ReadKey after its access is checked
+	if ctx, err = ratelimit.AllowPeer(ctx, g.rateLimit, method, 0); err != nil {
+		writeError(w, err)
+		return ctx, storeKey, false
+	}
+
+	if storeKey, err = g.fileNode.ReadKey(ctx, t.spaceId); err != nil {
+		writeError(w, err)
+		return ctx, storeKey, false
+	}
+	return ctx, storeKey, true
+}
+
+// checkCids makes sure all the cids are bound to the space, the error response is written otherwise
+func (g *gateway) checkCids(ctx context.Context, w http.ResponseWriter, storeKey index.Key, cids []cid.Cid) bool {
+	inSpace, err := g.index.CidExistsInSpace(ctx, storeKey, cids)
+	if err != nil {
+		log.WarnCtx(ctx, "cid exists in space error", zap.Error(err))
+		writeError(w, err)
+		return false
+	}
+	if len(inSpace) != len(cids) {
+		writeError(w, fileprotoerr.ErrCIDNotFound)
+		return false
+	}
+	return true
+}
+
+// writeError converts the rpc errors of the file node to http statuses
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fileprotoerr.ErrForbidden):
+		http.Error(w, "forbidden", http.StatusForbidden)
+	case errors.Is(err, fileprotoerr.ErrCIDNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, ratelimit.ErrRateLimited):
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func (g *gateway) Close(ctx context.Context) (err error) {
+	if g.server != nil {
+		return g.server.Shutdown(ctx)
+	}
+	return
+}
@@ -0,0 +1,378 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anyproto/any-sync/acl"
+	"github.com/anyproto/any-sync/acl/mock_acl"
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/commonspace/object/acl/list"
+	"github.com/anyproto/any-sync/metric"
+	"github.com/anyproto/any-sync/net/rpc/server"
+	"github.com/anyproto/any-sync/nodeconf"
+	"github.com/anyproto/any-sync/nodeconf/mock_nodeconf"
+	"github.com/anyproto/any-sync/testutil/accounttest"
+	"github.com/anyproto/any-sync/util/crypto"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/anyproto/any-sync-filenode/config"
+	"github.com/anyproto/any-sync-filenode/filenode"
+	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/ratelimit"
+	"github.com/anyproto/any-sync-filenode/redisprovider/testredisprovider"
+	"github.com/anyproto/any-sync-filenode/reload"
+	"github.com/anyproto/any-sync-filenode/store"
+	"github.com/anyproto/any-sync-filenode/store/filedevstore"
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+var ctx = context.Background()
+
+func TestGateway_Block(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	ownerKey, ownerPubKey, _ := crypto.GenerateRandomEd25519KeyPair()
+	spaceId := testutil.NewRandSpaceId()
+	fx.acl.EXPECT().OwnerPubKey(gomock.Any(), spaceId).Return(ownerPubKey, nil).AnyTimes()
+	b := fx.addBlock(t, index.Key{GroupId: ownerPubKey.Account(), SpaceId: spaceId})
+
+	ownerToken, err := NewToken(ownerKey, fx.peerId, spaceId, time.Minute)
+	require.NoError(t, err)
+
+	t.Run("get", func(t *testing.T) {
+		resp, data := fx.request(t, http.MethodGet, "/block/"+b.Cid().String(), ownerToken, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, b.RawData(), data)
+		assert.Equal(t, `"`+b.Cid().String()+`"`, resp.Header.Get("ETag"))
+		assert.Equal(t, cacheControl, resp.Header.Get("Cache-Control"))
+	})
+	t.Run("head", func(t *testing.T) {
+		resp, data := fx.request(t, http.MethodHead, "/block/"+b.Cid().String(), ownerToken, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, data)
+		assert.Equal(t, int64(len(b.RawData())), resp.ContentLength)
+	})
+	t.Run("not modified", func(t *testing.T) {
+		resp, _ := fx.request(t, http.MethodGet, "/block/"+b.Cid().String(), ownerToken, `"`+b.Cid().String()+`"`)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	})
+	t.Run("not in space", func(t *testing.T) {
+		resp, _ := fx.request(t, http.MethodGet, "/block/"+testutil.NewRandCid().String(), ownerToken, "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("invalid cid", func(t *testing.T) {
+		resp, _ := fx.request(t, http.MethodGet, "/block/abc", ownerToken, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+	t.Run("no token", func(t *testing.T) {
+		resp, _ := fx.request(t, http.MethodGet, "/block/"+b.Cid().String(), "", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+	t.Run("reader", func(t *testing.T) {
+		readerKey, readerPubKey, _ := crypto.GenerateRandomEd25519KeyPair()
+		fx.acl.EXPECT().Permissions(gomock.Any(), readerPubKey, spaceId).Return(list.AclPermissionsReader, nil)
+		token, err := NewToken(readerKey, fx.peerId, spaceId, time.Minute)
+		require.NoError(t, err)
+		resp, data := fx.request(t, http.MethodGet, "/block/"+b.Cid().String(), token, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, b.RawData(), data)
+	})
+	t.Run("forbidden", func(t *testing.T) {
+		otherKey, otherPubKey, _ := crypto.GenerateRandomEd25519KeyPair()
+		fx.acl.EXPECT().Permissions(gomock.Any(), otherPubKey, spaceId).Return(list.AclPermissionsNone, nil)
+		token, err := NewToken(otherKey, fx.peerId, spaceId, time.Minute)
+		require.NoError(t, err)
+		resp, _ := fx.request(t, http.MethodGet, "/block/"+b.Cid().String(), token, "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+	t.Run("other space", func(t *testing.T) {
+		otherSpaceId := testutil.NewRandSpaceId()
+		fx.acl.EXPECT().OwnerPubKey(gomock.Any(), otherSpaceId).Return(ownerPubKey, nil)
+		token, err := NewToken(ownerKey, fx.peerId, otherSpaceId, time.Minute)
+		require.NoError(t, err)
+		resp, _ := fx.request(t, http.MethodGet, "/block/"+b.Cid().String(), token, "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestGateway_RateLimit(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+	require.NoError(t, fx.a.MustComponent(ratelimit.CName).(reload.Reloadable).Reload(ctx, &config.Config{RateLimit: ratelimit.Config{
+		Enabled: true,
+		Methods: map[string]ratelimit.Limit{"blockGet": {Rps: 0.001, Burst: 1}},
+	}}))
+
+	ownerKey, ownerPubKey, _ := crypto.GenerateRandomEd25519KeyPair()
+	spaceId := testutil.NewRandSpaceId()
+	fx.acl.EXPECT().OwnerPubKey(gomock.Any(), spaceId).Return(ownerPubKey, nil).AnyTimes()
+	b := fx.addBlock(t, index.Key{GroupId: ownerPubKey.Account(), SpaceId: spaceId})
+	token, err := NewToken(ownerKey, fx.peerId, spaceId, time.Minute)
+	require.NoError(t, err)
+
+	resp, _ := fx.request(t, http.MethodGet, "/block/"+b.Cid().String(), token, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = fx.request(t, http.MethodGet, "/block/"+b.Cid().String(), token, "")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestGateway_Urls(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	ownerKey, ownerPubKey, _ := crypto.GenerateRandomEd25519KeyPair()
+	spaceId := testutil.NewRandSpaceId()
+	fx.acl.EXPECT().OwnerPubKey(gomock.Any(), spaceId).Return(ownerPubKey, nil).AnyTimes()
+	key := index.Key{GroupId: ownerPubKey.Account(), SpaceId: spaceId}
+	b1, b2 := fx.addBlock(t, key), fx.addBlock(t, key)
+	token, err := NewToken(ownerKey, fx.peerId, spaceId, time.Minute)
+	require.NoError(t, err)
+
+	t.Run("proxy", func(t *testing.T) {
+		resp, urls := fx.urls(t, token, b1.Cid().String(), b2.Cid().String(), b1.Cid().String())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []blockUrl{
+			{Cid: b1.Cid().String(), Url: "/block/" + b1.Cid().String()},
+			{Cid: b2.Cid().String(), Url: "/block/" + b2.Cid().String()},
+		}, urls)
+	})
+	t.Run("presigned", func(t *testing.T) {
+		fx.presigner = testPresigner{}
+		fx.presignExpiry = time.Minute
+		defer func() { fx.presigner = nil }()
+		resp, urls := fx.urls(t, token, b1.Cid().String())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Len(t, urls, 1)
+		assert.Equal(t, "https://s3/"+b1.Cid().String()+"?ttl=1m0s", urls[0].Url)
+		assert.NotEmpty(t, urls[0].Expire)
+
+		// presigned downloads are counted when the urls are issued
+		buckets, err := fx.index.BandwidthHistory(ctx, key, 1)
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.Equal(t, uint64(len(b1.RawData())), buckets[0].Downloaded)
+	})
+	t.Run("not in space", func(t *testing.T) {
+		resp, _ := fx.urls(t, token, b1.Cid().String(), testutil.NewRandCid().String())
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("no token", func(t *testing.T) {
+		resp, _ := fx.urls(t, "", b1.Cid().String())
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+	t.Run("body is not read without token", func(t *testing.T) {
+		resp, err := http.Post(fx.server.URL+"/urls", "application/json", bytes.NewReader([]byte("{")))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestGateway_Blocks(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	ownerKey, ownerPubKey, _ := crypto.GenerateRandomEd25519KeyPair()
+	spaceId := testutil.NewRandSpaceId()
+	fx.acl.EXPECT().OwnerPubKey(gomock.Any(), spaceId).Return(ownerPubKey, nil).AnyTimes()
+	key := index.Key{GroupId: ownerPubKey.Account(), SpaceId: spaceId}
+	b1, b2 := fx.addBlock(t, key), fx.addBlock(t, key)
+	token, err := NewToken(ownerKey, fx.peerId, spaceId, time.Minute)
+	require.NoError(t, err)
+
+	t.Run("get", func(t *testing.T) {
+		resp, got := fx.blocks(t, token, false, b1.Cid().String(), b2.Cid().String())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, map[string][]byte{
+			b1.Cid().String(): b1.RawData(),
+			b2.Cid().String(): b2.RawData(),
+		}, got)
+	})
+	t.Run("wait", func(t *testing.T) {
+		b3 := testutil.NewRandBlock(1024)
+		go func() {
+			time.Sleep(time.Millisecond * 50)
+			require.NoError(t, fx.store.Add(ctx, []blocks.Block{b3}))
+			require.NoError(t, fx.index.BlocksAdd(ctx, []blocks.Block{b3}))
+			fx.index.OnBlockUploaded(ctx, b3)
+		}()
+		resp, got := fx.blocks(t, token, true, b1.Cid().String(), b3.Cid().String())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, map[string][]byte{
+			b1.Cid().String(): b1.RawData(),
+			b3.Cid().String(): b3.RawData(),
+		}, got)
+	})
+	t.Run("not in space", func(t *testing.T) {
+		resp, _ := fx.blocks(t, token, false, b1.Cid().String(), testutil.NewRandCid().String())
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("wait for a block of other space", func(t *testing.T) {
+		other := fx.addBlock(t, index.Key{GroupId: ownerPubKey.Account(), SpaceId: testutil.NewRandSpaceId()})
+		resp, _ := fx.blocks(t, token, true, other.Cid().String())
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("no token", func(t *testing.T) {
+		resp, _ := fx.blocks(t, "", false, b1.Cid().String())
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+type testPresigner struct{}
+
+func (testPresigner) PresignGet(ctx context.Context, k cid.Cid, ttl time.Duration) (string, error) {
+	return "https://s3/" + k.String() + "?ttl=" + ttl.String(), nil
+}
+
+func newFixture(t *testing.T) *fixture {
+	ctrl := gomock.NewController(t)
+	fx := &fixture{
+		gateway:  New().(*gateway),
+		acl:      mock_acl.NewMockAclService(ctrl),
+		nodeConf: mock_nodeconf.NewMockService(ctrl),
+		store:    filedevstore.New(),
+		ctrl:     ctrl,
+		a:        new(app.App),
+	}
+
+	fx.acl.EXPECT().Name().Return(acl.CName).AnyTimes()
+	fx.acl.EXPECT().Init(gomock.Any()).AnyTimes()
+	fx.acl.EXPECT().Run(gomock.Any()).AnyTimes()
+	fx.acl.EXPECT().Close(gomock.Any()).AnyTimes()
+
+	fx.nodeConf.EXPECT().Name().Return(nodeconf.CName).AnyTimes()
+	fx.nodeConf.EXPECT().Init(gomock.Any()).AnyTimes()
+	fx.nodeConf.EXPECT().Run(gomock.Any()).AnyTimes()
+	fx.nodeConf.EXPECT().Close(gomock.Any()).AnyTimes()
+
+	conf := &config.Config{
+		DefaultLimit: 1024 * 1024,
+		PersistTtl:   3600,
+		FileDevStore: config.FileDevStore{Path: t.TempDir()},
+	}
+	fx.index = index.New()
+
+	fx.a.Register(conf).
+		Register(testredisprovider.NewTestRedisProviderNum(9)).
+		Register(&accounttest.AccountTestService{}).
+		Register(metric.New()).
+		Register(server.New()).
+		Register(fx.store).
+		Register(fx.index).
+		Register(fx.acl).
+		Register(fx.nodeConf).
+		Register(filenode.New()).
+		Register(ratelimit.New()).
+		Register(fx.gateway)
+	require.NoError(t, fx.a.Start(ctx))
+	fx.server = httptest.NewServer(fx.handler())
+	return fx
+}
+
+type fixture struct {
+	*gateway
+	acl      *mock_acl.MockAclService
+	nodeConf *mock_nodeconf.MockService
+	store    store.Store
+	index    index.Index
+	server   *httptest.Server
+	ctrl     *gomock.Controller
+	a        *app.App
+}
+
+func (fx *fixture) addBlock(t *testing.T, key index.Key) blocks.Block {
+	bs := testutil.NewRandBlocks(1)
+	require.NoError(t, fx.store.Add(ctx, bs))
+	require.NoError(t, fx.index.BlocksAdd(ctx, bs))
+	cids, err := fx.index.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.index.FileBind(ctx, key, testutil.NewRandCid().String(), cids))
+	cids.Release()
+	return bs[0]
+}
+
+func (fx *fixture) request(t *testing.T, method, path, token, ifNoneMatch string) (*http.Response, []byte) {
+	req, err := http.NewRequest(method, fx.server.URL+path, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+func (fx *fixture) urls(t *testing.T, token string, cids ...string) (*http.Response, []blockUrl) {
+	body, err := json.Marshal(urlsRequest{Cids: cids})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, fx.server.URL+"/urls", bytes.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var res urlsResponse
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	}
+	return resp, res.Urls
+}
+
+func (fx *fixture) blocks(t *testing.T, token string, wait bool, cids ...string) (*http.Response, map[string][]byte) {
+	body, err := json.Marshal(blocksRequest{Cids: cids, Wait: wait})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, fx.server.URL+"/blocks", bytes.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	res := make(map[string][]byte)
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(part)
+		require.NoError(t, err)
+		res[part.Header.Get("Content-Id")] = data
+	}
+	return resp, res
+}
+
+func (fx *fixture) Finish(t *testing.T) {
+	fx.server.Close()
+	require.NoError(t, fx.a.Close(ctx))
+	fx.ctrl.Finish()
+}
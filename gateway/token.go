@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/anyproto/any-sync/util/crypto"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// tokenSignPrefix separates signatures of tokens from other data signed by the account key
+const tokenSignPrefix = "anysync-filenode-gateway:"
+
+/*
+	Token format: base64url(json(tokenPayload)) + "." + base64url(signature of tokenSignPrefix + payload)
+	The payload is signed by the account key, so the token grants the same access to the space as the account has.
+	The audience is a peer id of the node the token is issued for, other nodes reject it
+*/
+
+type tokenPayload struct {
+	SpaceId  string `json:"spaceId"`
+	Identity []byte `json:"identity"`
+	Audience string `json:"aud"`
+	Expire   int64  `json:"expire"`
+}
+
+type token struct {
+	spaceId  string
+	identity crypto.PubKey
+	expire   time.Time
+}
+
+// NewToken creates a token for reading blocks of the space from the node with the given peer id, it's signed by the given account key and valid for the given ttl
+func NewToken(key crypto.PrivKey, nodePeerId, spaceId string, ttl time.Duration) (string, error) {
+	identity, err := key.GetPublic().Marshall()
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(tokenPayload{
+		SpaceId:  spaceId,
+		Identity: identity,
+		Audience: nodePeerId,
+		Expire:   time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	sig, err := key.Sign(append([]byte(tokenSignPrefix), payload...))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parseToken checks the signature, the audience and the expiration of the token, tokens living longer than maxTtl are rejected
+func parseToken(s, audience string, maxTtl time.Duration) (t token, err error) {
+	payloadStr, sigStr, ok := strings.Cut(s, ".")
+	if !ok {
+		return t, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadStr)
+	if err != nil {
+		return t, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigStr)
+	if err != nil {
+		return t, ErrInvalidToken
+	}
+	var p tokenPayload
+	if err = json.Unmarshal(payload, &p); err != nil {
+		return t, ErrInvalidToken
+	}
+	if p.SpaceId == "" || p.Audience != audience {
+		return t, ErrInvalidToken
+	}
+	identity, err := crypto.UnmarshalEd25519PublicKeyProto(p.Identity)
+	if err != nil {
+		return t, ErrInvalidToken
+	}
+	if ok, err = identity.Verify(append([]byte(tokenSignPrefix), payload...), sig); err != nil || !ok {
+		return t, ErrInvalidToken
+	}
+	expire := time.Unix(p.Expire, 0)
+	now := time.Now()
+	if !expire.After(now) {
+		return t, ErrTokenExpired
+	}
+	if expire.Sub(now) > maxTtl {
+		return t, ErrInvalidToken
+	}
+	return token{spaceId: p.SpaceId, identity: identity, expire: expire}, nil
+}
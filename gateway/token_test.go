@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anyproto/any-sync/util/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken(t *testing.T) {
+	privKey, pubKey, err := crypto.GenerateRandomEd25519KeyPair()
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		s, err := NewToken(privKey, "nodeId", "spaceId", time.Minute)
+		require.NoError(t, err)
+		tk, err := parseToken(s, "nodeId", time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, "spaceId", tk.spaceId)
+		assert.Equal(t, pubKey.Account(), tk.identity.Account())
+	})
+	t.Run("expired", func(t *testing.T) {
+		s, err := NewToken(privKey, "nodeId", "spaceId", -time.Minute)
+		require.NoError(t, err)
+		_, err = parseToken(s, "nodeId", time.Hour)
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+	t.Run("ttl exceeded", func(t *testing.T) {
+		s, err := NewToken(privKey, "nodeId", "spaceId", time.Hour*2)
+		require.NoError(t, err)
+		_, err = parseToken(s, "nodeId", time.Hour)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+	t.Run("wrong signature", func(t *testing.T) {
+		s, err := NewToken(privKey, "nodeId", "spaceId", time.Minute)
+		require.NoError(t, err)
+		other, err := NewToken(privKey, "nodeId", "otherSpaceId", time.Minute)
+		require.NoError(t, err)
+		payload, _, _ := strings.Cut(other, ".")
+		_, sig, _ := strings.Cut(s, ".")
+		_, err = parseToken(payload+"."+sig, "nodeId", time.Hour)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+	t.Run("other node", func(t *testing.T) {
+		s, err := NewToken(privKey, "otherNodeId", "spaceId", time.Minute)
+		require.NoError(t, err)
+		_, err = parseToken(s, "nodeId", time.Hour)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+	t.Run("signed without prefix", func(t *testing.T) {
+		s, err := NewToken(privKey, "nodeId", "spaceId", time.Minute)
+		require.NoError(t, err)
+		payloadStr, _, _ := strings.Cut(s, ".")
+		payload, err := base64.RawURLEncoding.DecodeString(payloadStr)
+		require.NoError(t, err)
+		sig, err := privKey.Sign(payload)
+		require.NoError(t, err)
+		_, err = parseToken(payloadStr+"."+base64.RawURLEncoding.EncodeToString(sig), "nodeId", time.Hour)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+	t.Run("malformed", func(t *testing.T) {
+		for _, s := range []string{"", "abc", "abc.def", "!.!"} {
+			_, err := parseToken(s, "nodeId", time.Hour)
+			assert.ErrorIs(t, err, ErrInvalidToken, s)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index"
+	"github.com/anyproto/any-sync-filenode/ratelimit"
+)
+
+// maxRequestCids is the max number of cids in one request of urls or blocks
+const maxRequestCids = 1000
+
+type urlsRequest struct {
+	Cids []string `json:"cids"`
+}
+
+type blockUrl struct {
+	Cid string `json:"cid"`
+	// Url is a direct link to the storage or a path of the block on the gateway requiring the same token
+	Url string `json:"url"`
+	// Expire is a unix time the direct link expires at, it's empty for gateway paths
+	Expire int64 `json:"expire,omitempty"`
+}
+
+type urlsResponse struct {
+	Urls []blockUrl `json:"urls"`
+}
+
+// handleUrls returns download urls of the blocks bound to the space of the token.
+// When the storage is able to presign and presigning is enabled, the urls point to the storage directly, otherwise to the gateway.
+// Presigned downloads bypass the gateway, so the sizes of the blocks are counted as downloaded when the urls are issued
+func (g *gateway) handleUrls(w http.ResponseWriter, r *http.Request) {
+	ctx, storeKey, ok := g.authorize(w, r, "blockUrls")
+	if !ok {
+		return
+	}
+	var req urlsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	cids, ok := decodeCids(w, req.Cids)
+	if !ok {
+		return
+	}
+	if len(cids) == 0 {
+		writeJson(w, urlsResponse{Urls: []blockUrl{}})
+		return
+	}
+	if !g.checkCids(ctx, w, storeKey, cids) {
+		return
+	}
+
+	resp := urlsResponse{Urls: make([]blockUrl, 0, len(cids))}
+	expire := time.Now().Add(g.presignExpiry)
+	for _, k := range cids {
+		u := blockUrl{Cid: k.String(), Url: "/block/" + k.String()}
+		if g.presigner != nil {
+			url, err := g.presigner.PresignGet(ctx, k, g.presignExpiry)
+			if err != nil {
+				log.WarnCtx(ctx, "presign error", zap.Error(err))
+				writeError(w, err)
+				return
+			}
+			u.Url, u.Expire = url, expire.Unix()
+		}
+		resp.Urls = append(resp.Urls, u)
+	}
+	if g.presigner != nil {
+		if err := g.countPresigned(ctx, storeKey, cids); err != nil {
+			log.WarnCtx(ctx, "can't count presigned downloads", zap.Error(err))
+			writeError(w, err)
+			return
+		}
+	}
+	writeJson(w, resp)
+}
+
+// countPresigned adds the sizes of the blocks to the downloaded bytes and takes them from the traffic budget
+func (g *gateway) countPresigned(ctx context.Context, storeKey index.Key, cids []cid.Cid) (err error) {
+	cidEntries, err := g.index.CidEntries(ctx, cids)
+	if err != nil {
+		return
+	}
+	defer cidEntries.Release()
+	var size uint64
+	for _, c := range cidEntries.Sizes() {
+		size += c.Size
+	}
+	ratelimit.TakeBytes(ctx, g.rateLimit, "blockUrls", storeKey.SpaceId, int(size))
+	g.index.BandwidthAdd(storeKey, 0, size)
+	return
+}
+
+// decodeCids parses the cids of the request skipping duplicates, the error response is written when they are invalid
+func decodeCids(w http.ResponseWriter, strs []string) (cids []cid.Cid, ok bool) {
+	if len(strs) > maxRequestCids {
+		http.Error(w, "too many cids", http.StatusBadRequest)
+		return nil, false
+	}
+	cids = make([]cid.Cid, 0, len(strs))
+	seen := make(map[cid.Cid]struct{}, len(strs))
+	for _, s := range strs {
+		k, err := cid.Decode(s)
+		if err != nil {
+			http.Error(w, "invalid cid", http.StatusBadRequest)
+			return nil, false
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		cids = append(cids, k)
+	}
+	return cids, true
+}
+
+func writeJson(w http.ResponseWriter, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Warn("can't write response", zap.Error(err))
+	}
+}
@@ -0,0 +1,9 @@
+package health
+
+type configSource interface {
+	GetHealth() Config
+}
+
+type Config struct {
+	ListenAddr string `yaml:"listenAddr"`
+}
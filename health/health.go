@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/app/logger"
+	"go.uber.org/zap"
+)
+
+const CName = "filenode.health"
+
+var log = logger.NewNamed(CName)
+
+const checkTimeout = time.Second * 10
+
+// Checker is implemented by components that are able to report their readiness
+type Checker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+func New() Health {
+	return new(health)
+}
+
+type Health interface {
+	// Ready runs all registered checks and returns the errors by component name
+	Ready(ctx context.Context) (errs map[string]error, ok bool)
+	app.ComponentRunnable
+}
+
+type namedChecker struct {
+	name string
+	Checker
+}
+
+type health struct {
+	conf     Config
+	checkers []namedChecker
+	server   *http.Server
+}
+
+func (h *health) Init(a *app.App) (err error) {
+	h.conf = a.MustComponent("config").(configSource).GetHealth()
+	a.IterateComponents(func(c app.Component) {
+		if checker, ok := c.(Checker); ok {
+			h.checkers = append(h.checkers, namedChecker{name: c.Name(), Checker: checker})
+		}
+	})
+	return
+}
+
+func (h *health) Name() (name string) {
+	return CName
+}
+
+func (h *health) Run(ctx context.Context) (err error) {
+	if h.conf.ListenAddr == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", h.conf.ListenAddr)
+	if err != nil {
+		return
+	}
+	h.server = &http.Server{Handler: h.handler()}
+	go func() {
+		if e := h.server.Serve(listener); e != nil && !errors.Is(e, http.ErrServerClosed) {
+			log.Error("health server error", zap.Error(e))
+		}
+	}()
+	log.Info("health server started", zap.String("addr", listener.Addr().String()))
+	return
+}
+
+func (h *health) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", h.handleLive)
+	mux.HandleFunc("/health/ready", h.handleReady)
+	return mux
+}
+
+func (h *health) Ready(ctx context.Context) (errs map[string]error, ok bool) {
+	ok = true
+	errs = make(map[string]error, len(h.checkers))
+	for _, c := range h.checkers {
+		err := c.HealthCheck(ctx)
+		if err != nil {
+			ok = false
+		}
+		errs[c.name] = err
+	}
+	return
+}
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func (h *health) handleLive(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, response{Status: "ok"})
+}
+
+func (h *health) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+	errs, ok := h.Ready(ctx)
+	resp := response{Status: "ok", Checks: make(map[string]string, len(errs))}
+	for name, err := range errs {
+		if err != nil {
+			resp.Checks[name] = err.Error()
+		} else {
+			resp.Checks[name] = "ok"
+		}
+	}
+	status := http.StatusOK
+	if !ok {
+		resp.Status = "fail"
+		status = http.StatusServiceUnavailable
+	}
+	writeResponse(w, status, resp)
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Warn("can't write health response", zap.Error(err))
+	}
+}
+
+func (h *health) Close(ctx context.Context) (err error) {
+	if h.server != nil {
+		return h.server.Shutdown(ctx)
+	}
+	return
+}
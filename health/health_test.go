@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ctx = context.Background()
+
+func TestHealth_Ready(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		fx := newFixture(t, &testChecker{name: "c1"}, &testChecker{name: "c2"})
+		defer fx.finish(t)
+
+		resp, code := fx.get(t, "/health/ready")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ok", resp.Status)
+		assert.Equal(t, map[string]string{"c1": "ok", "c2": "ok"}, resp.Checks)
+	})
+	t.Run("fail", func(t *testing.T) {
+		fx := newFixture(t, &testChecker{name: "c1"}, &testChecker{name: "c2", err: errors.New("redis is down")})
+		defer fx.finish(t)
+
+		resp, code := fx.get(t, "/health/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "fail", resp.Status)
+		assert.Equal(t, "redis is down", resp.Checks["c2"])
+
+		resp, code = fx.get(t, "/health/live")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ok", resp.Status)
+	})
+}
+
+func newFixture(t *testing.T, checkers ...*testChecker) *fixture {
+	fx := &fixture{
+		health: New().(*health),
+		a:      new(app.App),
+	}
+	fx.a.Register(&testConfig{})
+	for _, c := range checkers {
+		fx.a.Register(c)
+	}
+	fx.a.Register(fx.health)
+	require.NoError(t, fx.a.Start(ctx))
+	fx.server = httptest.NewServer(fx.handler())
+	return fx
+}
+
+type fixture struct {
+	*health
+	a      *app.App
+	server *httptest.Server
+}
+
+func (fx *fixture) get(t *testing.T, path string) (resp response, code int) {
+	res, err := http.Get(fx.server.URL + path)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+	return resp, res.StatusCode
+}
+
+func (fx *fixture) finish(t *testing.T) {
+	fx.server.Close()
+	require.NoError(t, fx.a.Close(ctx))
+}
+
+type testChecker struct {
+	name string
+	err  error
+}
+
+func (c *testChecker) Init(a *app.App) (err error) { return }
+func (c *testChecker) Name() string                { return c.name }
+
+func (c *testChecker) HealthCheck(ctx context.Context) error {
+	return c.err
+}
+
+type testConfig struct{}
+
+func (c *testConfig) Init(a *app.App) (err error) { return }
+func (c *testConfig) Name() string                { return "config" }
+func (c *testConfig) GetHealth() Config           { return Config{} }
@@ -0,0 +1,357 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anyproto/any-sync/net/peer"
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+const (
+	auditStream             = "auditStream"
+	auditStreamField        = "e"
+	auditSegmentsKey        = "audit:segments"
+	auditGroupSegmentsKey   = "audit:g:"
+	auditSpaceSegmentsKey   = "audit:s:"
+	auditSegmentPrefix      = "audit/"
+	auditSegmentSize        = 1000
+	defaultAuditFlushPeriod = 60
+)
+
+const (
+	AuditOpFileBind      = "fileBind"
+	AuditOpFileUnbind    = "fileUnbind"
+	AuditOpSpaceDelete   = "spaceDelete"
+	AuditOpSpacePurge    = "spacePurge"
+	AuditOpSetGroupLimit = "setGroupLimit"
+	AuditOpSetSpaceLimit = "setSpaceLimit"
+	AuditOpSpaceTransfer = "spaceTransfer"
+
+	AuditOpSpaceSoftDelete = "spaceSoftDelete"
+	AuditOpSpaceRestore    = "spaceRestore"
+	AuditOpSpaceSetStatus  = "spaceSetStatus"
+	AuditOpFileCopy        = "fileCopy"
+)
+
+/*
+	Audit keys:
+		auditStream: stream of proto(AuditEntry), not flushed entries
+		audit:segments: sorted set, persisted like other index keys
+			{firstMs}-{lastMs}-{lastStreamId} -> score(lastMs)
+		audit:g:{groupId}, audit:s:{spaceId}: the same sorted sets with segments containing entries of the group or the space
+	Flushed segments are stored in the index bucket as audit/{firstMs}-{lastMs}-{lastStreamId}: compressed proto(AuditSegment)
+*/
+
+// ErrAuditRangeRequired is returned when the query is not limited by a group, a space or a time range
+var ErrAuditRangeRequired = errors.New("audit query requires a group, a space or a time range")
+
+// AuditEntry is a record of the index mutation, Identity is empty for changes made by the node itself
+type AuditEntry struct {
+	Op             string
+	Identity       string
+	GroupId        string
+	SpaceId        string
+	FileIds        []string
+	SpaceSizeDelta int64
+	GroupSizeDelta int64
+	Limit          uint64
+	// Status is a new status of the space for status changes
+	Status indexproto.SpaceStatus
+	// SourceSpaceId is a space the files are copied from
+	SourceSpaceId string
+	Time          time.Time
+}
+
+// AuditFilter selects audit entries, empty fields match everything, but at least one of them must be set
+type AuditFilter struct {
+	GroupId string
+	SpaceId string
+	From    time.Time
+	To      time.Time
+}
+
+func (f AuditFilter) match(e *indexproto.AuditEntry) bool {
+	if f.GroupId != "" && e.GroupId != f.GroupId {
+		return false
+	}
+	if f.SpaceId != "" && e.SpaceId != f.SpaceId {
+		return false
+	}
+	if !f.From.IsZero() && e.Timestamp < f.From.UnixMilli() {
+		return false
+	}
+	if !f.To.IsZero() && e.Timestamp > f.To.UnixMilli() {
+		return false
+	}
+	return true
+}
+
+// spaceSizes remembers the sizes of the space and the group before the change to record the deltas
+type spaceSizes struct {
+	space, group uint64
+}
+
+func (e groupSpaceEntry) sizes() spaceSizes {
+	return spaceSizes{space: e.space.Size_, group: e.group.Size_}
+}
+
+// auditSpaceChange records the operation with the size changes of the space and the group made since the given sizes
+func (ri *redisIndex) auditSpaceChange(ctx context.Context, op string, key Key, entry groupSpaceEntry, before spaceSizes, fileIds ...string) {
+	ri.audit(ctx, &indexproto.AuditEntry{
+		Op:             op,
+		GroupId:        key.GroupId,
+		SpaceId:        key.SpaceId,
+		FileIds:        fileIds,
+		SpaceSizeDelta: int64(entry.space.Size_) - int64(before.space),
+		GroupSizeDelta: int64(entry.group.Size_) - int64(before.group),
+	})
+}
+
+// audit appends the entry to the audit stream, the mutation is already done here, so errors are only logged
+func (ri *redisIndex) audit(ctx context.Context, e *indexproto.AuditEntry) {
+	if identity, err := peer.CtxPubKey(ctx); err == nil {
+		e.Identity = identity.Account()
+	}
+	e.Timestamp = time.Now().UnixMilli()
+	data, err := e.Marshal()
+	if err != nil {
+		log.ErrorCtx(ctx, "can't marshal audit entry", zap.Error(err))
+		return
+	}
+	if err = ri.cl.XAdd(ctx, &redis.XAddArgs{
+		Stream: auditStream,
+		Values: []any{auditStreamField, data},
+	}).Err(); err != nil {
+		log.ErrorCtx(ctx, "can't write audit entry", zap.String("op", e.Op), zap.String("spaceId", e.SpaceId), zap.Error(err))
+	}
+}
+
+// FlushAudit moves entries from the audit stream to segments in the index bucket
+func (ri *redisIndex) FlushAudit(ctx context.Context) (err error) {
+	mu := ri.redsync.NewMutex("_lock:audit", redsync.WithExpiry(time.Minute*10))
+	if err = mu.LockContext(ctx); err != nil {
+		return
+	}
+	defer func() {
+		_, _ = mu.Unlock()
+	}()
+
+	st := time.Now()
+	var count int
+	for {
+		msgs, rErr := ri.cl.XRangeN(ctx, auditStream, "-", "+", auditSegmentSize).Result()
+		if rErr != nil {
+			return rErr
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		var (
+			segment = &indexproto.AuditSegment{Entries: make([]*indexproto.AuditEntry, 0, len(msgs))}
+			ids     = make([]string, 0, len(msgs))
+		)
+		for _, msg := range msgs {
+			ids = append(ids, msg.ID)
+			data, _ := msg.Values[auditStreamField].(string)
+			e := &indexproto.AuditEntry{}
+			if uErr := e.Unmarshal([]byte(data)); uErr != nil {
+				log.WarnCtx(ctx, "invalid audit entry", zap.String("id", msg.ID), zap.Error(uErr))
+				continue
+			}
+			e.StreamId = msg.ID
+			segment.Entries = append(segment.Entries, e)
+		}
+		if len(segment.Entries) != 0 {
+			if err = ri.writeAuditSegment(ctx, segment, ids[len(ids)-1]); err != nil {
+				return
+			}
+		}
+		if err = ri.cl.XDel(ctx, auditStream, ids...).Err(); err != nil {
+			return
+		}
+		count += len(segment.Entries)
+		if _, err = mu.ExtendContext(ctx); err != nil {
+			return
+		}
+	}
+	if count != 0 {
+		log.InfoCtx(ctx, "audit flushed", zap.Int("count", count), zap.Duration("dur", time.Since(st)))
+	}
+	return
+}
+
+func (ri *redisIndex) writeAuditSegment(ctx context.Context, segment *indexproto.AuditSegment, lastId string) (err error) {
+	first, last := segment.Entries[0].Timestamp, segment.Entries[0].Timestamp
+	for _, e := range segment.Entries {
+		first, last = min(first, e.Timestamp), max(last, e.Timestamp)
+	}
+	// the stream id makes the name unique, so a segment rewritten after a failed flush replaces the previous one
+	name := fmt.Sprintf("%d-%d-%s", first, last, lastId)
+	data, err := segment.Marshal()
+	if err != nil {
+		return
+	}
+	if err = ri.persistStore.IndexPut(ctx, auditSegmentPrefix+name, encodeDump(ri.persistCodec, data)); err != nil {
+		return
+	}
+	// the segment is also indexed by groups and spaces of its entries, so queries by them don't read all segments
+	segmentKeys := []string{auditSegmentsKey}
+	seen := make(map[string]struct{})
+	addKey := func(k string) {
+		if _, ok := seen[k]; !ok {
+			seen[k] = struct{}{}
+			segmentKeys = append(segmentKeys, k)
+		}
+	}
+	for _, e := range segment.Entries {
+		if e.GroupId != "" {
+			addKey(auditGroupSegmentsKey + e.GroupId)
+		}
+		if e.SpaceId != "" {
+			addKey(auditSpaceSegmentsKey + e.SpaceId)
+		}
+	}
+	for _, k := range segmentKeys {
+		if err = ri.addAuditSegment(ctx, k, name, last); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (ri *redisIndex) addAuditSegment(ctx context.Context, key, name string, last int64) (err error) {
+	_, release, err := ri.AcquireKey(ctx, key)
+	if err != nil {
+		return
+	}
+	defer release()
+	return ri.cl.ZAdd(ctx, key, redis.Z{Score: float64(last), Member: name}).Err()
+}
+
+// AuditQuery returns audit entries matching the filter sorted by time, including entries not flushed yet
+func (ri *redisIndex) AuditQuery(ctx context.Context, filter AuditFilter) (entries []AuditEntry, err error) {
+	segmentsKey := auditSegmentsKey
+	switch {
+	case filter.SpaceId != "":
+		segmentsKey = auditSpaceSegmentsKey + filter.SpaceId
+	case filter.GroupId != "":
+		segmentsKey = auditGroupSegmentsKey + filter.GroupId
+	case filter.From.IsZero() && filter.To.IsZero():
+		return nil, ErrAuditRangeRequired
+	}
+	var res []*indexproto.AuditEntry
+	add := func(e *indexproto.AuditEntry) {
+		if filter.match(e) {
+			res = append(res, e)
+		}
+	}
+
+	// the stream is read before segments, so entries flushed in the meantime are found in segments and skipped in the stream
+	msgs, err := ri.cl.XRange(ctx, auditStream, "-", "+").Result()
+	if err != nil {
+		return
+	}
+	names, err := ri.auditSegments(ctx, segmentsKey, filter)
+	if err != nil {
+		return
+	}
+	flushed := make(map[string]struct{})
+	for _, name := range names {
+		segment, sErr := ri.readAuditSegment(ctx, name)
+		if sErr != nil {
+			return nil, sErr
+		}
+		for _, e := range segment.Entries {
+			flushed[e.StreamId] = struct{}{}
+			add(e)
+		}
+	}
+	for _, msg := range msgs {
+		if _, ok := flushed[msg.ID]; ok {
+			continue
+		}
+		data, _ := msg.Values[auditStreamField].(string)
+		e := &indexproto.AuditEntry{}
+		if uErr := e.Unmarshal([]byte(data)); uErr != nil {
+			log.WarnCtx(ctx, "invalid audit entry", zap.String("id", msg.ID), zap.Error(uErr))
+			continue
+		}
+		add(e)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Timestamp < res[j].Timestamp
+	})
+	entries = make([]AuditEntry, 0, len(res))
+	for _, e := range res {
+		entries = append(entries, AuditEntry{
+			Op:             e.Op,
+			Identity:       e.Identity,
+			GroupId:        e.GroupId,
+			SpaceId:        e.SpaceId,
+			FileIds:        e.FileIds,
+			SpaceSizeDelta: e.SpaceSizeDelta,
+			GroupSizeDelta: e.GroupSizeDelta,
+			Limit:          e.Limit,
+			Status:         e.Status,
+			SourceSpaceId:  e.SourceSpaceId,
+			Time:           time.UnixMilli(e.Timestamp),
+		})
+	}
+	return
+}
+
+// auditSegments returns names of segments from the given set that may contain entries of the filter time range
+func (ri *redisIndex) auditSegments(ctx context.Context, segmentsKey string, filter AuditFilter) (names []string, err error) {
+	_, release, err := ri.AcquireKey(ctx, segmentsKey)
+	if err != nil {
+		return
+	}
+	defer release()
+	minScore := "-inf"
+	if !filter.From.IsZero() {
+		minScore = strconv.FormatInt(filter.From.UnixMilli(), 10)
+	}
+	all, err := ri.cl.ZRangeByScore(ctx, segmentsKey, &redis.ZRangeBy{Min: minScore, Max: "+inf"}).Result()
+	if err != nil {
+		return
+	}
+	for _, name := range all {
+		firstStr, _, _ := strings.Cut(name, "-")
+		first, pErr := strconv.ParseInt(firstStr, 10, 64)
+		if pErr != nil {
+			log.WarnCtx(ctx, "invalid audit segment name", zap.String("name", name))
+			continue
+		}
+		if !filter.To.IsZero() && first > filter.To.UnixMilli() {
+			continue
+		}
+		names = append(names, name)
+	}
+	return
+}
+
+func (ri *redisIndex) readAuditSegment(ctx context.Context, name string) (segment *indexproto.AuditSegment, err error) {
+	data, err := ri.persistStore.IndexGet(ctx, auditSegmentPrefix+name)
+	if err != nil {
+		return
+	}
+	if data, err = decodeDump(data); err != nil {
+		return
+	}
+	segment = &indexproto.AuditSegment{}
+	if err = segment.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return
+}
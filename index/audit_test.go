@@ -0,0 +1,130 @@
+package index
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anyproto/any-sync/net/peer"
+	"github.com/anyproto/any-sync/util/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+func TestRedisIndex_Audit(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	segments := map[string][]byte{}
+	fx.persistStore.EXPECT().IndexPut(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, k string, v []byte) error {
+		segments[k] = v
+		return nil
+	}).AnyTimes()
+	fx.persistStore.EXPECT().IndexGet(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, k string) ([]byte, error) {
+		return segments[k], nil
+	}).AnyTimes()
+
+	_, pubKey, _ := crypto.GenerateRandomEd25519KeyPair()
+	identity, err := pubKey.Marshall()
+	require.NoError(t, err)
+	peerCtx := peer.CtxWithIdentity(ctx, identity)
+
+	key := newRandKey()
+	otherKey := newRandKey()
+	bs := testutil.NewRandBlocks(3)
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+	cids, err := fx.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(peerCtx, key, "fileId", cids))
+	require.NoError(t, fx.FileBind(ctx, otherKey, "otherFileId", cids))
+	cids.Release()
+
+	require.NoError(t, fx.FlushAudit(ctx))
+	assert.Len(t, segments, 1)
+
+	require.NoError(t, fx.FileUnbind(peerCtx, key, "fileId"))
+	require.NoError(t, fx.SetSpaceLimit(ctx, key, 1024))
+
+	t.Run("space", func(t *testing.T) {
+		entries, err := fx.AuditQuery(ctx, AuditFilter{SpaceId: key.SpaceId})
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+
+		assert.Equal(t, AuditOpFileBind, entries[0].Op)
+		assert.Equal(t, pubKey.Account(), entries[0].Identity)
+		assert.Equal(t, []string{"fileId"}, entries[0].FileIds)
+		assert.Positive(t, entries[0].SpaceSizeDelta)
+		assert.Equal(t, entries[0].SpaceSizeDelta, entries[0].GroupSizeDelta)
+
+		assert.Equal(t, AuditOpFileUnbind, entries[1].Op)
+		assert.Equal(t, -entries[0].SpaceSizeDelta, entries[1].SpaceSizeDelta)
+
+		assert.Equal(t, AuditOpSetSpaceLimit, entries[2].Op)
+		assert.Empty(t, entries[2].Identity)
+		assert.Equal(t, uint64(1024), entries[2].Limit)
+	})
+	t.Run("group", func(t *testing.T) {
+		entries, err := fx.AuditQuery(ctx, AuditFilter{GroupId: otherKey.GroupId})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, otherKey.SpaceId, entries[0].SpaceId)
+	})
+	t.Run("time range", func(t *testing.T) {
+		entries, err := fx.AuditQuery(ctx, AuditFilter{From: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		entries, err = fx.AuditQuery(ctx, AuditFilter{To: time.Now().Add(-time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+	t.Run("range required", func(t *testing.T) {
+		_, err := fx.AuditQuery(ctx, AuditFilter{})
+		assert.ErrorIs(t, err, ErrAuditRangeRequired)
+	})
+	t.Run("flush again", func(t *testing.T) {
+		require.NoError(t, fx.FlushAudit(ctx))
+		assert.Len(t, segments, 2)
+		entries, err := fx.AuditQuery(ctx, AuditFilter{SpaceId: key.SpaceId})
+		require.NoError(t, err)
+		assert.Len(t, entries, 3)
+	})
+}
+
+func TestRedisIndex_AuditSpaceOps(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	key := newRandKey()
+	srcKey := newRandKey()
+	bs := testutil.NewRandBlocks(3)
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+	cids, err := fx.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.FileCopy(ctx, srcKey, key, "fileId", cids))
+	cids.Release()
+
+	require.NoError(t, fx.SpaceSetStatus(ctx, key, indexproto.SpaceStatus_SpaceStatusDeletionPending))
+	ok, err := fx.SpaceSoftDelete(ctx, key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, err = fx.SpaceRestore(ctx, key.SpaceId)
+	require.NoError(t, err)
+
+	entries, err := fx.AuditQuery(ctx, AuditFilter{SpaceId: key.SpaceId})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, AuditOpFileCopy, entries[0].Op)
+	assert.Equal(t, srcKey.SpaceId, entries[0].SourceSpaceId)
+	assert.Equal(t, []string{"fileId"}, entries[0].FileIds)
+	assert.Positive(t, entries[0].SpaceSizeDelta)
+
+	assert.Equal(t, AuditOpSpaceSetStatus, entries[1].Op)
+	assert.Equal(t, indexproto.SpaceStatus_SpaceStatusDeletionPending, entries[1].Status)
+	assert.Equal(t, AuditOpSpaceSoftDelete, entries[2].Op)
+	assert.Equal(t, AuditOpSpaceRestore, entries[3].Op)
+}
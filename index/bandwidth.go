@@ -0,0 +1,410 @@
+package index
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	bandwidthFlushPeriodSec = 10
+	bandwidthDayLayout      = "20060102"
+	bandwidthDaysKey        = "bw:days"
+)
+
+// BandwidthBucket is an hourly traffic of a group or a space, SpaceId is empty for groups
+type BandwidthBucket struct {
+	GroupId    string
+	SpaceId    string
+	Time       time.Time
+	Uploaded   uint64
+	Downloaded uint64
+}
+
+type bandwidthField struct {
+	key   string
+	field string
+}
+
+/*
+	Bandwidth keys, all of them are persisted like other index keys:
+		bw:g:{groupId}.{day}: map
+			u:{hour} -> uploaded bytes
+			d:{hour} -> downloaded bytes
+		bw:s:{groupId}/{spaceId}.{day}: map
+		bw:day.{day}: set of group and space keys updated during the day
+		bw:days: sorted set of days with traffic, the score is the day start time
+	Days older than bandwidthHistoryDays are removed with all their keys
+*/
+
+func bandwidthKey(kind, id, day string) string {
+	return "bw:" + kind + ":" + id + "." + day
+}
+
+func bandwidthDayKey(day string) string {
+	return "bw:day." + day
+}
+
+// BandwidthAdd counts the traffic of the space and its group, counters are flushed to redis periodically.
+// The group id can be empty, then the group storing the space is resolved on flush
+func (ri *redisIndex) BandwidthAdd(key Key, uploaded, downloaded uint64) {
+	now := time.Now().UTC()
+	day := now.Format(bandwidthDayLayout)
+	hour := strconv.Itoa(now.Hour())
+	ri.bandwidthMu.Lock()
+	defer ri.bandwidthMu.Unlock()
+	counters, keys := ri.bandwidth, []string{bandwidthKey("g", key.GroupId, day)}
+	if key.GroupId == "" {
+		counters, keys = ri.bandwidthBySpace, []string{key.SpaceId + "." + day}
+	} else if key.SpaceId != "" {
+		keys = append(keys, bandwidthKey("s", key.GroupId+"/"+key.SpaceId, day))
+	}
+	for _, k := range keys {
+		if uploaded != 0 {
+			counters[bandwidthField{key: k, field: "u:" + hour}] += uploaded
+		}
+		if downloaded != 0 {
+			counters[bandwidthField{key: k, field: "d:" + hour}] += downloaded
+		}
+	}
+}
+
+// flushBandwidth moves the collected counters to redis
+func (ri *redisIndex) flushBandwidth(ctx context.Context) (err error) {
+	ri.bandwidthMu.Lock()
+	counters, bySpace := ri.bandwidth, ri.bandwidthBySpace
+	ri.bandwidth = make(map[bandwidthField]uint64)
+	ri.bandwidthBySpace = make(map[bandwidthField]uint64)
+	ri.bandwidthMu.Unlock()
+	if len(counters) == 0 && len(bySpace) == 0 {
+		return
+	}
+	if err = ri.resolveBandwidth(ctx, bySpace, counters); err != nil {
+		ri.returnBandwidth(ri.bandwidthBySpace, bySpace)
+		ri.returnBandwidth(ri.bandwidth, counters)
+		return
+	}
+
+	byKey := make(map[string]map[string]uint64)
+	days := make(map[string]struct{})
+	for f, v := range counters {
+		if byKey[f.key] == nil {
+			byKey[f.key] = make(map[string]uint64)
+		}
+		byKey[f.key][f.field] = v
+		days[f.key[strings.LastIndexByte(f.key, '.')+1:]] = struct{}{}
+	}
+	if err = ri.addBandwidthDays(ctx, days); err != nil {
+		ri.returnBandwidth(ri.bandwidth, counters)
+		return
+	}
+	for k, fields := range byKey {
+		if err = ri.flushBandwidthKey(ctx, k, fields); err != nil {
+			// return the counters back, they will be flushed next time
+			rest := make(map[bandwidthField]uint64)
+			for bk, bFields := range byKey {
+				for field, v := range bFields {
+					rest[bandwidthField{key: bk, field: field}] = v
+				}
+			}
+			ri.returnBandwidth(ri.bandwidth, rest)
+			return
+		}
+		delete(byKey, k)
+	}
+	return
+}
+
+func (ri *redisIndex) returnBandwidth(to, counters map[bandwidthField]uint64) {
+	ri.bandwidthMu.Lock()
+	defer ri.bandwidthMu.Unlock()
+	for f, v := range counters {
+		to[f] += v
+	}
+}
+
+// resolveBandwidth adds the counters collected by space ids to the counters of the groups storing the spaces
+func (ri *redisIndex) resolveBandwidth(ctx context.Context, bySpace, counters map[bandwidthField]uint64) (err error) {
+	if len(bySpace) == 0 {
+		return
+	}
+	var spaceIds []string
+	seen := make(map[string]struct{})
+	for f := range bySpace {
+		spaceId := f.key[:strings.LastIndexByte(f.key, '.')]
+		if _, ok := seen[spaceId]; !ok {
+			seen[spaceId] = struct{}{}
+			spaceIds = append(spaceIds, spaceId)
+		}
+	}
+	res, err := ri.cl.HMGet(ctx, spaceGroupsKey, spaceIds...).Result()
+	if err != nil {
+		return
+	}
+	groupIds := make(map[string]string, len(spaceIds))
+	for i, spaceId := range spaceIds {
+		if groupId, ok := res[i].(string); ok {
+			groupIds[spaceId] = groupId
+		}
+	}
+	for f, v := range bySpace {
+		dot := strings.LastIndexByte(f.key, '.')
+		spaceId, day := f.key[:dot], f.key[dot+1:]
+		groupId, ok := groupIds[spaceId]
+		if !ok {
+			log.DebugCtx(ctx, "unknown space of the bandwidth counter", zap.String("spaceId", spaceId))
+			continue
+		}
+		counters[bandwidthField{key: bandwidthKey("g", groupId, day), field: f.field}] += v
+		counters[bandwidthField{key: bandwidthKey("s", groupId+"/"+spaceId, day), field: f.field}] += v
+	}
+	return
+}
+
+func (ri *redisIndex) addBandwidthDays(ctx context.Context, days map[string]struct{}) (err error) {
+	_, release, err := ri.AcquireKey(ctx, bandwidthDaysKey)
+	if err != nil {
+		return
+	}
+	defer release()
+	members := make([]redis.Z, 0, len(days))
+	for day := range days {
+		dayTime, pErr := time.Parse(bandwidthDayLayout, day)
+		if pErr != nil {
+			return pErr
+		}
+		members = append(members, redis.Z{Score: float64(dayTime.Unix()), Member: day})
+	}
+	return ri.cl.ZAdd(ctx, bandwidthDaysKey, members...).Err()
+}
+
+func (ri *redisIndex) flushBandwidthKey(ctx context.Context, k string, fields map[string]uint64) (err error) {
+	day := k[strings.LastIndexByte(k, '.')+1:]
+	dayKey := bandwidthDayKey(day)
+	_, release, err := ri.AcquireKey(ctx, k)
+	if err != nil {
+		return
+	}
+	defer release()
+	_, dayRelease, err := ri.AcquireKey(ctx, dayKey)
+	if err != nil {
+		return
+	}
+	defer dayRelease()
+	// the keys can be in different slots, both of them are locked, so a plain pipeline is enough
+	_, err = ri.cl.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for field, v := range fields {
+			pipe.HIncrBy(ctx, k, field, int64(v))
+		}
+		pipe.SAdd(ctx, dayKey, k)
+		return nil
+	})
+	return
+}
+
+// trimBandwidth removes the traffic of days older than the history
+func (ri *redisIndex) trimBandwidth(ctx context.Context) (err error) {
+	historyDays := int(ri.bandwidthHistoryDays.Load())
+	if historyDays <= 0 {
+		return
+	}
+	mu := ri.redsync.NewMutex("_lock:bandwidthTrim", redsync.WithExpiry(time.Minute*10))
+	if err = mu.LockContext(ctx); err != nil {
+		return
+	}
+	defer func() {
+		_, _ = mu.Unlock()
+	}()
+
+	border := time.Now().UTC().Truncate(time.Hour*24).AddDate(0, 0, -historyDays+1)
+	_, release, err := ri.AcquireKey(ctx, bandwidthDaysKey)
+	if err != nil {
+		return
+	}
+	days, err := ri.cl.ZRangeByScore(ctx, bandwidthDaysKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(border.Unix(), 10),
+	}).Result()
+	release()
+	if err != nil {
+		return
+	}
+	for _, day := range days {
+		if err = ri.trimBandwidthDay(ctx, day); err != nil {
+			return
+		}
+		if _, err = mu.ExtendContext(ctx); err != nil {
+			return
+		}
+	}
+	if len(days) != 0 {
+		log.InfoCtx(ctx, "bandwidth trimmed", zap.Strings("days", days))
+	}
+	return
+}
+
+// trimBandwidthDay removes the keys of the day, the day is removed from the list of days last, so a failed run is repeated
+func (ri *redisIndex) trimBandwidthDay(ctx context.Context, day string) (err error) {
+	dayKey := bandwidthDayKey(day)
+	_, release, err := ri.AcquireKey(ctx, dayKey)
+	if err != nil {
+		return
+	}
+	keys, err := ri.cl.SMembers(ctx, dayKey).Result()
+	release()
+	if err != nil {
+		return
+	}
+	for _, k := range append(keys, dayKey) {
+		if err = ri.deleteKey(ctx, k); err != nil {
+			return
+		}
+	}
+	_, release, err = ri.AcquireKey(ctx, bandwidthDaysKey)
+	if err != nil {
+		return
+	}
+	defer release()
+	return ri.cl.ZRem(ctx, bandwidthDaysKey, day).Err()
+}
+
+// deleteKey removes the key from redis and from the persistent store
+func (ri *redisIndex) deleteKey(ctx context.Context, k string) (err error) {
+	_, release, err := ri.acquireKey(ctx, k)
+	if err != nil {
+		return
+	}
+	defer release()
+	if err = ri.cl.Del(ctx, k).Err(); err != nil {
+		return
+	}
+	return ri.persistStore.IndexDelete(ctx, k)
+}
+
+// BandwidthHistory returns hourly traffic of the space for the last days, the group traffic is returned when the space id is empty
+func (ri *redisIndex) BandwidthHistory(ctx context.Context, key Key, days int) (buckets []BandwidthBucket, err error) {
+	if err = ri.flushBandwidth(ctx); err != nil {
+		return
+	}
+	kind, id := "g", key.GroupId
+	if key.SpaceId != "" {
+		kind, id = "s", key.GroupId+"/"+key.SpaceId
+	}
+	for _, day := range lastDays(days) {
+		var dayBuckets []BandwidthBucket
+		if dayBuckets, err = ri.bandwidthDay(ctx, bandwidthKey(kind, id, day)); err != nil {
+			return
+		}
+		buckets = append(buckets, dayBuckets...)
+	}
+	return
+}
+
+// BandwidthExport calls the given func for every hourly bucket of all groups and spaces for the last days
+func (ri *redisIndex) BandwidthExport(ctx context.Context, days int, fn func(bucket BandwidthBucket) error) (err error) {
+	if err = ri.flushBandwidth(ctx); err != nil {
+		return
+	}
+	for _, day := range lastDays(days) {
+		dayKey := bandwidthDayKey(day)
+		_, release, aErr := ri.AcquireKey(ctx, dayKey)
+		if aErr != nil {
+			return aErr
+		}
+		keys, sErr := ri.cl.SMembers(ctx, dayKey).Result()
+		release()
+		if sErr != nil {
+			return sErr
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			buckets, bErr := ri.bandwidthDay(ctx, k)
+			if bErr != nil {
+				return bErr
+			}
+			for _, b := range buckets {
+				if err = fn(b); err != nil {
+					return
+				}
+			}
+		}
+	}
+	return
+}
+
+func (ri *redisIndex) bandwidthDay(ctx context.Context, k string) (buckets []BandwidthBucket, err error) {
+	_, release, err := ri.AcquireKey(ctx, k)
+	if err != nil {
+		return
+	}
+	defer release()
+	res, err := ri.cl.HGetAll(ctx, k).Result()
+	if err != nil || len(res) == 0 {
+		return
+	}
+
+	// bw:{kind}:{id}.{day}
+	dot := strings.LastIndexByte(k, '.')
+	dayTime, err := time.Parse(bandwidthDayLayout, k[dot+1:])
+	if err != nil {
+		return
+	}
+	var groupId, spaceId string
+	id := k[len("bw:g:"):dot]
+	if strings.HasPrefix(k, "bw:s:") {
+		groupId, spaceId, _ = strings.Cut(id, "/")
+	} else {
+		groupId = id
+	}
+
+	byHour := make(map[int]*BandwidthBucket)
+	for field, val := range res {
+		direction, hourStr, _ := strings.Cut(field, ":")
+		hour, pErr := strconv.Atoi(hourStr)
+		if pErr != nil {
+			log.WarnCtx(ctx, "invalid bandwidth field", zap.String("key", k), zap.String("field", field))
+			continue
+		}
+		v, _ := strconv.ParseUint(val, 10, 64)
+		b := byHour[hour]
+		if b == nil {
+			b = &BandwidthBucket{
+				GroupId: groupId,
+				SpaceId: spaceId,
+				Time:    dayTime.Add(time.Duration(hour) * time.Hour),
+			}
+			byHour[hour] = b
+		}
+		if direction == "u" {
+			b.Uploaded += v
+		} else {
+			b.Downloaded += v
+		}
+	}
+	for _, b := range byHour {
+		buckets = append(buckets, *b)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Time.Before(buckets[j].Time)
+	})
+	return
+}
+
+// lastDays returns the given count of days up to today in the ascending order
+func lastDays(days int) []string {
+	if days <= 0 {
+		days = 1
+	}
+	now := time.Now().UTC()
+	res := make([]string, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		res = append(res, now.AddDate(0, 0, -i).Format(bandwidthDayLayout))
+	}
+	return res
+}
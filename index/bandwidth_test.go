@@ -0,0 +1,100 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisIndex_Bandwidth(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	key := newRandKey()
+	key2 := Key{GroupId: key.GroupId, SpaceId: newRandKey().SpaceId}
+	fx.BandwidthAdd(key, 100, 0)
+	fx.BandwidthAdd(key, 0, 30)
+	require.NoError(t, fx.flushBandwidth(ctx))
+	fx.BandwidthAdd(key, 50, 0)
+	fx.BandwidthAdd(key2, 10, 20)
+
+	hour := time.Now().UTC().Truncate(time.Hour)
+
+	t.Run("space", func(t *testing.T) {
+		buckets, err := fx.BandwidthHistory(ctx, key, 2)
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.Equal(t, BandwidthBucket{
+			GroupId:    key.GroupId,
+			SpaceId:    key.SpaceId,
+			Time:       hour,
+			Uploaded:   150,
+			Downloaded: 30,
+		}, buckets[0])
+	})
+	t.Run("group", func(t *testing.T) {
+		buckets, err := fx.BandwidthHistory(ctx, Key{GroupId: key.GroupId}, 2)
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+		assert.Equal(t, uint64(160), buckets[0].Uploaded)
+		assert.Equal(t, uint64(50), buckets[0].Downloaded)
+		assert.Empty(t, buckets[0].SpaceId)
+	})
+	t.Run("export", func(t *testing.T) {
+		var buckets []BandwidthBucket
+		require.NoError(t, fx.BandwidthExport(ctx, 1, func(bucket BandwidthBucket) error {
+			buckets = append(buckets, bucket)
+			return nil
+		}))
+		assert.Len(t, buckets, 3)
+	})
+}
+
+func TestRedisIndex_BandwidthBySpace(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	key := newRandKey()
+	require.NoError(t, fx.cl.HSet(ctx, spaceGroupsKey, key.SpaceId, key.GroupId).Err())
+	fx.BandwidthAdd(Key{SpaceId: key.SpaceId}, 0, 30)
+	// the space is unknown, the counter is dropped
+	fx.BandwidthAdd(Key{SpaceId: newRandKey().SpaceId}, 0, 10)
+
+	buckets, err := fx.BandwidthHistory(ctx, key, 1)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	assert.Equal(t, uint64(30), buckets[0].Downloaded)
+	buckets, err = fx.BandwidthHistory(ctx, Key{GroupId: key.GroupId}, 1)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	assert.Equal(t, uint64(30), buckets[0].Downloaded)
+}
+
+func TestRedisIndex_TrimBandwidth(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+	fx.bandwidthHistoryDays.Store(2)
+
+	key := newRandKey()
+	fx.BandwidthAdd(key, 100, 0)
+	require.NoError(t, fx.flushBandwidth(ctx))
+
+	// traffic of three days ago
+	old := time.Now().UTC().AddDate(0, 0, -3)
+	day := old.Format(bandwidthDayLayout)
+	oldKey := bandwidthKey("g", key.GroupId, day)
+	require.NoError(t, fx.flushBandwidthKey(ctx, oldKey, map[string]uint64{"u:1": 10}))
+	require.NoError(t, fx.addBandwidthDays(ctx, map[string]struct{}{day: {}}))
+	fx.persistStore.EXPECT().IndexDelete(ctx, oldKey)
+	fx.persistStore.EXPECT().IndexDelete(ctx, bandwidthDayKey(day))
+
+	require.NoError(t, fx.trimBandwidth(ctx))
+	ex, err := fx.cl.Exists(ctx, oldKey, bandwidthDayKey(day)).Result()
+	require.NoError(t, err)
+	assert.Zero(t, ex)
+	days, err := fx.cl.ZRange(ctx, bandwidthDaysKey, 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{time.Now().UTC().Format(bandwidthDayLayout)}, days)
+}
@@ -5,6 +5,9 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/events"
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
 )
 
 func (ri *redisIndex) FileBind(ctx context.Context, key Key, fileId string, cids *CidEntries) (err error) {
@@ -14,7 +17,36 @@ func (ri *redisIndex) FileBind(ctx context.Context, key Key, fileId string, cids
 	}
 	defer release()
 
-	return ri.fileBind(ctx, key, fileId, cids, entry)
+	before := entry.sizes()
+	if err = ri.fileBind(ctx, key, fileId, cids, entry); err != nil {
+		return
+	}
+	ri.auditSpaceChange(ctx, AuditOpFileBind, key, entry, before, fileId)
+	return
+}
+
+// FileCopy binds the cids of the file from the source space to the space of the key, it's recorded in the audit as a copy
+func (ri *redisIndex) FileCopy(ctx context.Context, srcKey, key Key, fileId string, cids *CidEntries) (err error) {
+	entry, release, err := ri.AcquireSpace(ctx, key)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	before := entry.sizes()
+	if err = ri.fileBind(ctx, key, fileId, cids, entry); err != nil {
+		return
+	}
+	ri.audit(ctx, &indexproto.AuditEntry{
+		Op:             AuditOpFileCopy,
+		GroupId:        key.GroupId,
+		SpaceId:        key.SpaceId,
+		FileIds:        []string{fileId},
+		SpaceSizeDelta: int64(entry.space.Size_) - int64(before.space),
+		GroupSizeDelta: int64(entry.group.Size_) - int64(before.group),
+		SourceSpaceId:  srcKey.SpaceId,
+	})
+	return
 }
 
 func (ri *redisIndex) fileBind(ctx context.Context, key Key, fileId string, cids *CidEntries, entry groupSpaceEntry) (err error) {
@@ -30,12 +62,16 @@ func (ri *redisIndex) fileBind(ctx context.Context, key Key, fileId string, cids
 	isolatedSpace := entry.space.Limit != 0
 
 	// make a list of indexes of non-exists cids
-	var newFileCidIdx = make([]int, 0, len(cids.entries))
+	var (
+		newFileCidIdx = make([]int, 0, len(cids.entries))
+		boundSize     uint64
+	)
 	for i, c := range cids.entries {
 		if !fileInfo.Exists(c.Cid.String()) {
 			newFileCidIdx = append(newFileCidIdx, i)
 			fileInfo.Cids = append(fileInfo.Cids, c.Cid.String())
 			fileInfo.Size_ += c.Size_
+			boundSize += c.Size_
 		}
 	}
 
@@ -91,7 +127,22 @@ func (ri *redisIndex) fileBind(ctx context.Context, key Key, fileId string, cids
 	if isNewFile {
 		entry.space.FileCount++
 	}
+	quotaWarning := ri.updateQuotaWarnLevel(entry.group)
 
+	if err = ri.markSpaceChanged(ctx, key); err != nil {
+		return
+	}
+	// the event has only cids bound by this call, so a file uploaded block by block is counted once
+	outbox, err := ri.newEvents(ctx, key.GroupId, events.Event{
+		Type:     events.TypeFileBound,
+		SpaceId:  key.SpaceId,
+		FileId:   fileId,
+		Size:     boundSize,
+		CidCount: uint64(len(newFileCidIdx)),
+	})
+	if err != nil {
+		return
+	}
 	// make group and space updates in one tx
 	_, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
 		// increment cid refs
@@ -106,8 +157,15 @@ func (ri *redisIndex) fileBind(ctx context.Context, key Key, fileId string, cids
 		entry.space.Save(ctx, key, tx)
 		entry.group.Save(ctx, tx)
 		fileInfo.Save(ctx, key, fileId, tx)
+		outbox.write(ctx, tx)
 		return nil
 	})
+	if err == nil {
+		ri.publishEvents(ctx, outbox)
+		if quotaWarning != 0 {
+			ri.notifyQuotaWarning(ctx, entry.group, quotaWarning)
+		}
+	}
 
 	// update cids
 	for _, idx := range affectedCidIdx {
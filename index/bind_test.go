@@ -68,8 +68,8 @@ func TestRedisIndex_Bind(t *testing.T) {
 		assert.Equal(t, GroupInfo{
 			BytesUsage:   sumSize,
 			CidsCount:    uint64(len(bs)),
-			AccountLimit: fx.defaultLimit,
-			Limit:        fx.defaultLimit,
+			AccountLimit: fx.defaultLimit.Load(),
+			Limit:        fx.defaultLimit.Load(),
 			SpaceIds:     []string{key.SpaceId},
 		}, groupInfo)
 
@@ -100,3 +100,38 @@ func TestRedisIndex_Bind(t *testing.T) {
 		assert.Equal(t, sumSize, fInfo[0].BytesUsage)
 	})
 }
+
+func TestRedisIndex_FileCidEntries(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+	bs := testutil.NewRandBlocks(3)
+	key := newRandKey()
+	fileId := testutil.NewRandCid().String()
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+	cids, err := fx.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(ctx, key, fileId, cids))
+	cids.Release()
+
+	t.Run("copy to other space", func(t *testing.T) {
+		entries, err := fx.FileCidEntries(ctx, key, fileId)
+		require.NoError(t, err)
+		assert.Len(t, entries.entries, len(bs))
+		dstKey := Key{GroupId: key.GroupId, SpaceId: testutil.NewRandSpaceId()}
+		require.NoError(t, fx.FileBind(ctx, dstKey, fileId, entries))
+		entries.Release()
+
+		srcInfo, err := fx.SpaceInfo(ctx, key)
+		require.NoError(t, err)
+		dstInfo, err := fx.SpaceInfo(ctx, dstKey)
+		require.NoError(t, err)
+		assert.Equal(t, srcInfo, dstInfo)
+		groupInfo, err := fx.GroupInfo(ctx, key.GroupId)
+		require.NoError(t, err)
+		assert.Equal(t, srcInfo.BytesUsage, groupInfo.BytesUsage)
+	})
+	t.Run("not exists", func(t *testing.T) {
+		_, err := fx.FileCidEntries(ctx, key, testutil.NewRandCid().String())
+		assert.ErrorIs(t, err, ErrFileNotExists)
+	})
+}
@@ -0,0 +1,67 @@
+package index
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/golang/snappy"
+)
+
+var ErrUnknownCompression = errors.New("unknown compression")
+
+const (
+	compressionNone   = "none"
+	compressionSnappy = "snappy"
+)
+
+const (
+	dumpCodecNone byte = iota
+	dumpCodecSnappy
+)
+
+// dumpMagic prefixes compressed dumps in the persistent store.
+// A redis DUMP payload starts with the RDB value type which is never 0xff,
+// so objects persisted before compression was enabled are read as raw dumps.
+var dumpMagic = []byte{0xff, 'f', 'n', 'z'}
+
+func dumpCodecByName(name string) (codec byte, err error) {
+	switch name {
+	case "", compressionNone:
+		return dumpCodecNone, nil
+	case compressionSnappy:
+		return dumpCodecSnappy, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCompression, name)
+	}
+}
+
+func encodeDump(codec byte, dump []byte) []byte {
+	switch codec {
+	case dumpCodecSnappy:
+		hLen := len(dumpMagic) + 1
+		res := make([]byte, hLen+snappy.MaxEncodedLen(len(dump)))
+		copy(res, dumpMagic)
+		res[len(dumpMagic)] = codec
+		encoded := snappy.Encode(res[hLen:], dump)
+		return res[:hLen+len(encoded)]
+	default:
+		return dump
+	}
+}
+
+func decodeDump(data []byte) (dump []byte, err error) {
+	if len(data) <= len(dumpMagic) || !bytes.HasPrefix(data, dumpMagic) {
+		// legacy uncompressed dump
+		return data, nil
+	}
+	payload := data[len(dumpMagic)+1:]
+	switch codec := data[len(dumpMagic)]; codec {
+	case dumpCodecNone:
+		return payload, nil
+	case dumpCodecSnappy:
+		return snappy.Decode(nil, payload)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownCompression, codec)
+	}
+}
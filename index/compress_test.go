@@ -0,0 +1,45 @@
+package index
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpCodec(t *testing.T) {
+	dump := bytes.Repeat([]byte("c:bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"), 100)
+
+	t.Run("none", func(t *testing.T) {
+		codec, err := dumpCodecByName("")
+		require.NoError(t, err)
+		data := encodeDump(codec, dump)
+		assert.Equal(t, dump, data)
+		res, err := decodeDump(data)
+		require.NoError(t, err)
+		assert.Equal(t, dump, res)
+	})
+	t.Run("snappy", func(t *testing.T) {
+		codec, err := dumpCodecByName(compressionSnappy)
+		require.NoError(t, err)
+		data := encodeDump(codec, dump)
+		assert.True(t, bytes.HasPrefix(data, dumpMagic))
+		assert.Less(t, len(data), len(dump))
+		res, err := decodeDump(data)
+		require.NoError(t, err)
+		assert.Equal(t, dump, res)
+	})
+	t.Run("legacy", func(t *testing.T) {
+		legacy := append([]byte{0x04}, dump...)
+		res, err := decodeDump(legacy)
+		require.NoError(t, err)
+		assert.Equal(t, legacy, res)
+	})
+	t.Run("unknown", func(t *testing.T) {
+		_, err := dumpCodecByName("lz4")
+		assert.ErrorIs(t, err, ErrUnknownCompression)
+		_, err = decodeDump(append(append([]byte{}, dumpMagic...), 42, 1, 2, 3))
+		assert.ErrorIs(t, err, ErrUnknownCompression)
+	})
+}
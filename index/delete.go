@@ -6,6 +6,9 @@ import (
 	"strings"
 
 	"github.com/redis/go-redis/v9"
+
+	"github.com/anyproto/any-sync-filenode/events"
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
 )
 
 func (ri *redisIndex) SpaceDelete(ctx context.Context, key Key) (ok bool, err error) {
@@ -15,12 +18,19 @@ func (ri *redisIndex) SpaceDelete(ctx context.Context, key Key) (ok bool, err er
 	}
 	defer release()
 
-	return ri.spaceDelete(ctx, key, entry)
+	before := entry.sizes()
+	fileIds, ok, err := ri.spaceDelete(ctx, key, entry, nil)
+	if ok || len(fileIds) != 0 {
+		ri.auditSpaceChange(ctx, AuditOpSpaceDelete, key, entry, before, fileIds...)
+	}
+	return
 }
 
-func (ri *redisIndex) spaceDelete(ctx context.Context, key Key, entry groupSpaceEntry) (ok bool, err error) {
+// spaceDelete unbinds all the files of the space and removes it from the group, the unbound file ids are returned even on error
+func (ri *redisIndex) spaceDelete(ctx context.Context, key Key, entry groupSpaceEntry, purge *purgeStat) (fileIds []string, ok bool, err error) {
 	if !entry.spaceExists {
-		return false, nil
+		// the space could be left in the deleted set by a half applied deletion
+		return nil, false, ri.unmarkDeleted(ctx, key.SpaceId)
 	}
 	sk := spaceKey(key)
 
@@ -30,31 +40,53 @@ func (ri *redisIndex) spaceDelete(ctx context.Context, key Key, entry groupSpace
 	}
 	for _, k := range keys {
 		if strings.HasPrefix(k, "f:") {
-			if err = ri.fileUnbind(ctx, key, entry, k[2:]); err != nil {
+			if err = ri.fileUnbind(ctx, key, entry, k[2:], purge); err != nil {
 				return
 			}
+			fileIds = append(fileIds, k[2:])
 		}
 	}
 
-	if !slices.Contains(entry.group.SpaceIds, key.SpaceId) {
-		return false, nil
+	isDeleted := entry.space.Status == indexproto.SpaceStatus_SpaceStatusDeleted
+	if isDeleted {
+		entry.group.DeletedSpaceIds = slices.DeleteFunc(entry.group.DeletedSpaceIds, func(spaceId string) bool {
+			return spaceId == key.SpaceId
+		})
+	} else {
+		if !slices.Contains(entry.group.SpaceIds, key.SpaceId) {
+			return fileIds, false, nil
+		}
+		entry.group.SpaceIds = slices.DeleteFunc(entry.group.SpaceIds, func(spaceId string) bool {
+			return spaceId == key.SpaceId
+		})
 	}
-	entry.group.SpaceIds = slices.DeleteFunc(entry.group.SpaceIds, func(spaceId string) bool {
-		return spaceId == key.SpaceId
-	})
 
 	// in case of isolated space - return limit to the group
 	if entry.space.Limit != 0 {
 		entry.group.Limit += entry.space.Limit
 	}
 
+	if err = ri.markUsageChanged(ctx, Key{GroupId: key.GroupId}); err != nil {
+		return
+	}
+	outbox, err := ri.newEvents(ctx, key.GroupId, events.Event{Type: events.TypeSpaceDeleted, SpaceId: key.SpaceId})
+	if err != nil {
+		return
+	}
 	_, err = ri.cl.Pipelined(ctx, func(pipe redis.Pipeliner) error {
 		entry.group.Save(ctx, pipe)
+		outbox.write(ctx, pipe)
 		pipe.Del(ctx, sk)
+		pipe.HDel(ctx, spaceGroupsKey, key.SpaceId)
 		return nil
 	})
 	if err != nil {
 		return
 	}
-	return true, nil
+	// a soft delete could be half applied with the Ok status, so the deleted set is cleaned up anyway
+	if err = ri.unmarkDeleted(ctx, key.SpaceId); err != nil {
+		return
+	}
+	ri.publishEvents(ctx, outbox)
+	return fileIds, true, nil
 }
@@ -1,11 +1,16 @@
 package index
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"github.com/ipfs/go-cid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
 	"github.com/anyproto/any-sync-filenode/testutil"
 )
 
@@ -64,3 +69,109 @@ func TestRedisIndex_SpaceDelete(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, ok)
 }
+
+func TestRedisIndex_SpacePurge(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+	key := newRandKey()
+	otherKey := newRandKey()
+
+	// space not exists
+	report, err := fx.SpacePurge(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, report)
+
+	bs := testutil.NewRandBlocks(5)
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+
+	// the space uses all the blocks, the other space uses the first two
+	cids, err := fx.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(ctx, key, testutil.NewRandCid().String(), cids))
+	cids.Release()
+	cids, err = fx.CidEntriesByBlocks(ctx, bs[:2])
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(ctx, otherKey, testutil.NewRandCid().String(), cids))
+	cids.Release()
+
+	var (
+		expectedDeleted []cid.Cid
+		expectedBytes   uint64
+	)
+	for _, b := range bs[2:] {
+		expectedDeleted = append(expectedDeleted, b.Cid())
+		expectedBytes += uint64(len(b.RawData()))
+	}
+	fx.persistStore.EXPECT().DeleteMany(ctx, gomock.InAnyOrder(expectedDeleted))
+	var reportData []byte
+	fx.persistStore.EXPECT().IndexPut(ctx, gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key string, value []byte) error {
+		assert.True(t, strings.HasPrefix(key, "deletionReport:"))
+		reportData = value
+		return nil
+	})
+
+	report, err = fx.SpacePurge(ctx, key)
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, key.SpaceId, report.SpaceId)
+	assert.Equal(t, uint32(1), report.FileCount)
+	assert.Equal(t, uint64(3), report.CidCount)
+	assert.Equal(t, expectedBytes, report.BytesFreed)
+
+	// report is signed by the node
+	signed := &indexproto.SignedDeletionReport{}
+	require.NoError(t, signed.Unmarshal(reportData))
+	signKey := fx.account.Account().SignKey
+	ok, err := signKey.GetPublic().Verify(signed.Report, signed.Signature)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, signKey.GetPublic().Account(), signed.Identity)
+
+	// deleted cids are removed from the index, shared cids stay
+	for _, b := range bs[2:] {
+		ex, err := fx.CheckKey(ctx, cidKey(b.Cid()))
+		require.NoError(t, err)
+		assert.False(t, ex)
+	}
+	cids, err = fx.CidEntriesByBlocks(ctx, bs[:2])
+	require.NoError(t, err)
+	for _, e := range cids.entries {
+		assert.Equal(t, int32(1), e.Refs)
+	}
+	cids.Release()
+}
+
+func TestRedisIndex_SpacePurgeUploading(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+	key := newRandKey()
+
+	bs := testutil.NewRandBlocks(2)
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+	cids, err := fx.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(ctx, key, testutil.NewRandCid().String(), cids))
+	cids.Release()
+
+	// the second block is being uploaded by another file, it's not removed and left to the sweeper
+	unlock, err := fx.BlocksLock(ctx, bs[1:])
+	require.NoError(t, err)
+	defer unlock()
+	fx.persistStore.EXPECT().DeleteMany(ctx, []cid.Cid{bs[0].Cid()})
+	fx.persistStore.EXPECT().IndexPut(ctx, gomock.Any(), gomock.Any())
+
+	report, err := fx.SpacePurge(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), report.CidCount)
+
+	cids, err = fx.CidEntriesByBlocks(ctx, bs[1:])
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), cids.entries[0].Refs)
+	cids.Release()
+	journalIds, err := fx.cl.ZRange(ctx, uploadJournalKey, 0, -1).Result()
+	require.NoError(t, err)
+	require.Len(t, journalIds, 1)
+	journaled, err := fx.cl.SMembers(ctx, uploadJournalEntryKey(journalIds[0])).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{bs[1].Cid().String()}, journaled)
+}
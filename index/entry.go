@@ -98,6 +98,9 @@ func (f *groupEntry) Save(ctx context.Context, cl redis.Cmdable) {
 }
 
 func (f *groupEntry) AddSpaceId(spaceId string) {
+	if slices.Contains(f.DeletedSpaceIds, spaceId) {
+		return
+	}
 	if !slices.Contains(f.SpaceIds, spaceId) {
 		f.SpaceIds = append(f.SpaceIds, spaceId)
 	}
@@ -116,8 +119,8 @@ func (ri *redisIndex) getGroupEntry(ctx context.Context, key Key) (entry *groupE
 				CreateTime:   now,
 				UpdateTime:   now,
 				Size_:        0,
-				Limit:        ri.defaultLimit,
-				AccountLimit: ri.defaultLimit,
+				Limit:        ri.defaultLimit.Load(),
+				AccountLimit: ri.defaultLimit.Load(),
 			},
 		}, nil
 	}
@@ -127,8 +130,8 @@ func (ri *redisIndex) getGroupEntry(ctx context.Context, key Key) (entry *groupE
 	}
 	groupEntryProto.GroupId = key.GroupId
 	if groupEntryProto.AccountLimit == 0 {
-		groupEntryProto.Limit = ri.defaultLimit
-		groupEntryProto.AccountLimit = ri.defaultLimit
+		groupEntryProto.Limit = ri.defaultLimit.Load()
+		groupEntryProto.AccountLimit = ri.defaultLimit.Load()
 	}
 	return &groupEntry{GroupEntry: groupEntryProto}, nil
 }
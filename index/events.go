@@ -0,0 +1,153 @@
+package index
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/OneOfOne/xxhash"
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/events"
+)
+
+/*
+	Event keys:
+		eventsPending.{system}: sorted set of groups that may have not published events, groupId -> score(ms of the last change)
+		ev:{groupId}.{hash}: list of encoded events of the group waiting for the publishing, it's in the slot of the group keys
+	Events are written to the outbox in the transaction of the change and moved to the stream right after it,
+	events left in the outbox after a failure are published by the background flush, so every change is published at least once
+*/
+
+const (
+	eventsPendingKey = "eventsPending.{system}"
+	// eventsPendingDelay is a time after the change the background flush starts publishing events of the group,
+	// the change publishes its own events in the meantime
+	eventsPendingDelay      = time.Minute
+	eventsFlushPeriodSec    = 60
+	eventsOutboxReadCount   = 100
+	eventsPendingReadCount  = 1000
+	eventsOutboxKeyPrefix   = "ev:"
+	eventsPendingLockExpiry = time.Minute * 10
+)
+
+// removePendingIfSame removes the group from the pending set only if it wasn't changed since it was read
+// KEYS[1] - pending set; ARGV[1] - group id, ARGV[2] - the score read before
+var removePendingIfSame = redis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if score and tonumber(score) == tonumber(ARGV[2]) then
+	return redis.call("ZREM", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+func eventsOutboxKey(groupId string) string {
+	hash := strconv.FormatUint(uint64(xxhash.ChecksumString32(groupId)), 36)
+	return eventsOutboxKeyPrefix + groupId + ".{" + hash + "}"
+}
+
+// eventOutbox holds encoded events of one change of the group
+type eventOutbox struct {
+	groupId string
+	data    [][]byte
+}
+
+// newEvents encodes events of the group and marks the group as pending, it's called before the change and outside of its transaction
+func (ri *redisIndex) newEvents(ctx context.Context, groupId string, evs ...events.Event) (outbox *eventOutbox, err error) {
+	outbox = &eventOutbox{groupId: groupId, data: make([][]byte, 0, len(evs))}
+	for _, ev := range evs {
+		ev.GroupId = groupId
+		data, mErr := events.Marshal(ev)
+		if mErr != nil {
+			return nil, mErr
+		}
+		outbox.data = append(outbox.data, data)
+	}
+	if err = ri.cl.ZAdd(ctx, eventsPendingKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: groupId}).Err(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// write adds events to the outbox in the transaction of the change
+func (o *eventOutbox) write(ctx context.Context, tx redis.Pipeliner) {
+	for _, data := range o.data {
+		tx.RPush(ctx, eventsOutboxKey(o.groupId), data)
+	}
+}
+
+// publishEvents moves events of the committed change to the stream, the change is already done here,
+// so errors are only logged, events left in the outbox are published by the background flush
+func (ri *redisIndex) publishEvents(ctx context.Context, outbox *eventOutbox) {
+	for _, data := range outbox.data {
+		if err := ri.publishOutboxEvent(ctx, outbox.groupId, data); err != nil {
+			log.WarnCtx(ctx, "can't publish event, will retry", zap.String("groupId", outbox.groupId), zap.Error(err))
+			return
+		}
+	}
+}
+
+func (ri *redisIndex) publishOutboxEvent(ctx context.Context, groupId string, data []byte) (err error) {
+	if err = events.PublishData(ctx, ri.cl, ri.eventsStreamMaxLen, data); err != nil {
+		return
+	}
+	return ri.cl.LRem(ctx, eventsOutboxKey(groupId), 1, data).Err()
+}
+
+// FlushEvents publishes events left in outboxes of groups changed earlier than eventsPendingDelay ago
+func (ri *redisIndex) FlushEvents(ctx context.Context) (err error) {
+	mu := ri.redsync.NewMutex("_lock:eventsFlush", redsync.WithExpiry(eventsPendingLockExpiry))
+	if err = mu.LockContext(ctx); err != nil {
+		return
+	}
+	defer func() {
+		_, _ = mu.Unlock()
+	}()
+
+	border := time.Now().Add(-eventsPendingDelay).UnixMilli()
+	pending, err := ri.cl.ZRangeByScoreWithScores(ctx, eventsPendingKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(border, 10),
+		Count: eventsPendingReadCount,
+	}).Result()
+	if err != nil {
+		return
+	}
+	var count int
+	for _, z := range pending {
+		groupId, _ := z.Member.(string)
+		n, fErr := ri.flushOutbox(ctx, groupId)
+		if fErr != nil {
+			return fErr
+		}
+		count += n
+		if err = removePendingIfSame.Run(ctx, ri.cl, []string{eventsPendingKey}, groupId, strconv.FormatInt(int64(z.Score), 10)).Err(); err != nil {
+			return
+		}
+	}
+	if count != 0 {
+		log.InfoCtx(ctx, "not published events flushed", zap.Int("count", count))
+	}
+	return
+}
+
+func (ri *redisIndex) flushOutbox(ctx context.Context, groupId string) (count int, err error) {
+	key := eventsOutboxKey(groupId)
+	for {
+		items, rErr := ri.cl.LRange(ctx, key, 0, eventsOutboxReadCount-1).Result()
+		if rErr != nil {
+			return count, rErr
+		}
+		if len(items) == 0 {
+			return
+		}
+		for _, data := range items {
+			if err = ri.publishOutboxEvent(ctx, groupId, []byte(data)); err != nil {
+				return
+			}
+			count++
+		}
+	}
+}
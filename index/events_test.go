@@ -0,0 +1,114 @@
+package index
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/events"
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+func TestRedisIndex_Events(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	key := newRandKey()
+	bs := testutil.NewRandBlocks(3)
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+	cids, err := fx.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(ctx, key, "fileId", cids))
+	// nothing new is bound - no event
+	require.NoError(t, fx.FileBind(ctx, key, "fileId", cids))
+	cids.Release()
+	require.NoError(t, fx.FileUnbind(ctx, key, "fileId"))
+	require.NoError(t, fx.SetGroupLimit(ctx, key.GroupId, 2048))
+	ok, err := fx.SpaceDelete(ctx, key)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	published := streamEvents(t, fx)
+	var types []string
+	for _, e := range published {
+		types = append(types, e.Type)
+		assert.NotEmpty(t, e.Id)
+	}
+	assert.Equal(t, []string{events.TypeFileBound, events.TypeFileUnbound, events.TypeLimitChanged, events.TypeSpaceDeleted}, types)
+	assert.Equal(t, "fileId", published[0].FileId)
+	assert.Equal(t, uint64(3), published[0].CidCount)
+	assert.NotZero(t, published[0].Size)
+	assert.Equal(t, published[0].Size, published[1].Size)
+	assert.Equal(t, uint64(2048), published[2].Limit)
+	assert.Equal(t, key.SpaceId, published[3].SpaceId)
+}
+
+func TestRedisIndex_EventsBoundDelta(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	key := newRandKey()
+	bs := testutil.NewRandBlocks(2)
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+	for _, b := range bs {
+		cids, err := fx.CidEntriesByBlocks(ctx, []blocks.Block{b})
+		require.NoError(t, err)
+		require.NoError(t, fx.FileBind(ctx, key, "fileId", cids))
+		cids.Release()
+	}
+
+	published := streamEvents(t, fx)
+	require.Len(t, published, 2)
+	for i, e := range published {
+		assert.Equal(t, events.TypeFileBound, e.Type)
+		assert.Equal(t, uint64(1), e.CidCount)
+		assert.Equal(t, uint64(len(bs[i].RawData())), e.Size)
+	}
+}
+
+func TestRedisIndex_FlushEvents(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	// the change is committed, but the event wasn't published
+	groupId := newRandKey().GroupId
+	data, err := events.Marshal(events.Event{Type: events.TypeLimitChanged, GroupId: groupId, Limit: 1})
+	require.NoError(t, err)
+	require.NoError(t, fx.cl.RPush(ctx, eventsOutboxKey(groupId), data).Err())
+	changed := time.Now().Add(-eventsPendingDelay * 2).UnixMilli()
+	require.NoError(t, fx.cl.ZAdd(ctx, eventsPendingKey, redis.Z{Score: float64(changed), Member: groupId}).Err())
+
+	// a recent change is left to the change itself
+	recentGroupId := newRandKey().GroupId
+	_, err = fx.newEvents(ctx, recentGroupId, events.Event{Type: events.TypeLimitChanged})
+	require.NoError(t, err)
+
+	require.NoError(t, fx.FlushEvents(ctx))
+	published := streamEvents(t, fx)
+	require.Len(t, published, 1)
+	assert.Equal(t, groupId, published[0].GroupId)
+
+	n, err := fx.cl.LLen(ctx, eventsOutboxKey(groupId)).Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	_, err = fx.cl.ZScore(ctx, eventsPendingKey, groupId).Result()
+	assert.ErrorIs(t, err, redis.Nil)
+	_, err = fx.cl.ZScore(ctx, eventsPendingKey, recentGroupId).Result()
+	assert.NoError(t, err)
+}
+
+func streamEvents(t *testing.T, fx *fixture) (published []events.Event) {
+	msgs, err := fx.cl.XRange(ctx, events.Stream, "-", "+").Result()
+	require.NoError(t, err)
+	for _, msg := range msgs {
+		var e events.Event
+		require.NoError(t, json.Unmarshal([]byte(msg.Values["e"].(string)), &e))
+		published = append(published, e)
+	}
+	return
+}
@@ -7,19 +7,26 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/OneOfOne/xxhash"
+	"github.com/anyproto/any-sync/accountservice"
 	"github.com/anyproto/any-sync/app"
 	"github.com/anyproto/any-sync/app/logger"
+	"github.com/anyproto/any-sync/metric"
 	"github.com/anyproto/any-sync/util/periodicsync"
 	"github.com/go-redsync/redsync/v4"
 	"github.com/go-redsync/redsync/v4/redis/goredis/v9"
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 
 	"github.com/anyproto/any-sync-filenode/config"
+	"github.com/anyproto/any-sync-filenode/events"
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+	"github.com/anyproto/any-sync-filenode/notifier"
 	"github.com/anyproto/any-sync-filenode/redisprovider"
 	"github.com/anyproto/any-sync-filenode/store/s3store"
 )
@@ -29,14 +36,22 @@ const CName = "filenode.index"
 var log = logger.NewNamed(CName)
 
 var (
-	ErrCidsNotExist = errors.New("cids not exist")
+	ErrCidsNotExist  = errors.New("cids not exist")
+	ErrFileNotExists = errors.New("file not exists")
+	ErrPersistStale  = errors.New("persist loop is stale")
 )
 
+// persistStaleTimeout is the max time since the last finished persist loop after which the node is not ready
+const persistStaleTimeout = time.Minute * 30
+
 type Index interface {
 	FileBind(ctx context.Context, key Key, fileId string, cidEntries *CidEntries) (err error)
+	FileCopy(ctx context.Context, srcKey, key Key, fileId string, cidEntries *CidEntries) (err error)
 	FileUnbind(ctx context.Context, kye Key, fileIds ...string) (err error)
 	FileInfo(ctx context.Context, key Key, fileIds ...string) (fileInfo []FileInfo, err error)
 	FilesList(ctx context.Context, key Key) (fileIds []string, err error)
+	// FileCidEntries returns locked entries of the file cids, e.g. to bind the same cids to another space
+	FileCidEntries(ctx context.Context, key Key, fileId string) (entries *CidEntries, err error)
 
 	GroupInfo(ctx context.Context, groupId string) (info GroupInfo, err error)
 	SpaceInfo(ctx context.Context, key Key) (info SpaceInfo, err error)
@@ -47,6 +62,8 @@ type Index interface {
 	OnBlockUploaded(ctx context.Context, bs ...blocks.Block)
 
 	WaitCidExists(ctx context.Context, c cid.Cid) (err error)
+	// WaitCidsExist waits for all the cids with one subscription and reports every cid as soon as it exists
+	WaitCidsExist(ctx context.Context, cids []cid.Cid, onExists func(c cid.Cid) error) (err error)
 	CidExists(ctx context.Context, c cid.Cid) (ok bool, err error)
 	CidEntries(ctx context.Context, cids []cid.Cid) (entries *CidEntries, err error)
 	CidEntriesByBlocks(ctx context.Context, bs []blocks.Block) (entries *CidEntries, err error)
@@ -55,10 +72,43 @@ type Index interface {
 	SetGroupLimit(ctx context.Context, groupId string, limit uint64) (err error)
 	SetSpaceLimit(ctx context.Context, key Key, limit uint64) (err error)
 	CheckLimits(ctx context.Context, key Key) error
+	// Reserve reserves the size of not bound cids against the limit until the upload is finished
+	Reserve(ctx context.Context, key Key, cids []CidSize) (reservation *Reservation, err error)
+	ReservationCommit(ctx context.Context, reservation *Reservation) (err error)
+	ReservationRelease(ctx context.Context, reservation *Reservation) (err error)
+
+	// UploadJournalAdd records cids written to the store by the upload until it's bound
+	UploadJournalAdd(ctx context.Context, cids []cid.Cid) (journalId string, err error)
+	UploadJournalClear(ctx context.Context, journalId string) (err error)
 
 	Migrate(ctx context.Context, key Key) error
 
 	SpaceDelete(ctx context.Context, key Key) (ok bool, err error)
+	SpaceSetStatus(ctx context.Context, key Key, status indexproto.SpaceStatus) (err error)
+	// SpacePurge deletes the space and all blocks that are not used by other spaces
+	SpacePurge(ctx context.Context, key Key) (report *indexproto.DeletionReport, err error)
+	// SpaceSoftDelete hides the space and rejects requests to it, the data is kept until the space is restored or deleted
+	SpaceSoftDelete(ctx context.Context, key Key) (ok bool, err error)
+	SpaceRestore(ctx context.Context, spaceId string) (key Key, err error)
+	CheckSpace(ctx context.Context, key Key) (err error)
+	// CheckSpaceGroup transfers the space to the group of the key when it's stored in another group, e.g. the owner has changed
+	CheckSpaceGroup(ctx context.Context, key Key) (err error)
+	// SpaceTransfer moves the space from the group of the key to the given group
+	SpaceTransfer(ctx context.Context, key Key, groupId string) (ok bool, err error)
+	DeletedSpaces(ctx context.Context, before time.Time) (keys []Key, err error)
+
+	// BandwidthAdd counts uploaded and downloaded bytes of the space
+	BandwidthAdd(key Key, uploaded, downloaded uint64)
+	BandwidthHistory(ctx context.Context, key Key, days int) (buckets []BandwidthBucket, err error)
+	BandwidthExport(ctx context.Context, days int, fn func(bucket BandwidthBucket) error) (err error)
+
+	// SnapshotUsage records today's usage of changed groups and spaces, it runs periodically
+	SnapshotUsage(ctx context.Context) (err error)
+	UsageHistory(ctx context.Context, key Key, days int) (snapshots []UsageSnapshot, err error)
+
+	// FlushAudit moves recorded index mutations to the index bucket, it runs periodically
+	FlushAudit(ctx context.Context) (err error)
+	AuditQuery(ctx context.Context, filter AuditFilter) (entries []AuditEntry, err error)
 	app.ComponentRunnable
 }
 
@@ -66,6 +116,11 @@ func New() Index {
 	return &redisIndex{}
 }
 
+// NewAdmin returns the index without background jobs: persisting, usage snapshots, sweeping and flushes are left to the running nodes
+func NewAdmin() Index {
+	return &redisIndex{noBackground: true}
+}
+
 type Key struct {
 	GroupId string
 	SpaceId string
@@ -105,6 +160,9 @@ type FileInfo struct {
 				f:{fileId}: proto(FileEntry)
 				c:{cidId} -> int(refCount)
 				info: proto(SpaceEntry)
+		SPACES:
+			spaceGroups.{system}: map
+				{spaceId} -> groupId the space is stored in
 
 */
 
@@ -112,12 +170,45 @@ type redisIndex struct {
 	cl           redis.UniversalClient
 	redsync      *redsync.Redsync
 	persistStore persistentStore
-	persistTtl   time.Duration
+	account      accountservice.Service
+	notifier     notifier.Notifier
+	persistTtl   atomic.Int64
+	persistCodec byte
 	ticker       periodicsync.PeriodicSync
-	defaultLimit uint64
+	bwTicker     periodicsync.PeriodicSync
+	usageTicker  periodicsync.PeriodicSync
+	sweepTicker  periodicsync.PeriodicSync
+	auditTicker  periodicsync.PeriodicSync
+	eventsTicker periodicsync.PeriodicSync
+	defaultLimit atomic.Uint64
+	metric       metric.Metric
+	metrics      *indexMetrics
+	lastPersist  atomic.Int64
+	noBackground bool
+	backfillDone chan struct{}
+
+	// usageHistoryDays is a count of days usage snapshots are kept, 0 means forever
+	usageHistoryDays atomic.Int64
+	// bandwidthHistoryDays is a count of days the traffic is kept, 0 means forever
+	bandwidthHistoryDays atomic.Int64
+	// quotaWarnThresholds are group usage thresholds in percents of the limit, ascending
+	quotaWarnThresholds atomic.Pointer[[]uint32]
+	reservationTtl      atomic.Int64
+	journalStale        atomic.Int64
+	journalSweepPeriod  int
+	auditFlushPeriod    int
+	eventsStreamMaxLen  int64
+
+	bandwidthMu      sync.Mutex
+	bandwidth        map[bandwidthField]uint64
+	bandwidthBySpace map[bandwidthField]uint64
 
 	cidSubscriptionsMu sync.Mutex
-	cidSubscriptions   map[string]map[chan struct{}]struct{}
+	cidSubscriptions   map[string]map[*cidWaiter]struct{}
+	cidWaiters         int
+	cidWaitMax         atomic.Int64
+	cidWaitMaxWaiters  atomic.Int64
+	cidWaitRecheck     atomic.Int64
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -126,18 +217,29 @@ type redisIndex struct {
 func (ri *redisIndex) Init(a *app.App) (err error) {
 	ri.cl = a.MustComponent(redisprovider.CName).(redisprovider.RedisProvider).Redis()
 	ri.persistStore = a.MustComponent(s3store.CName).(persistentStore)
+	ri.account = a.MustComponent(accountservice.CName).(accountservice.Service)
 	ri.redsync = redsync.New(goredis.NewPool(ri.cl))
 	conf := app.MustComponent[*config.Config](a)
 
-	ri.persistTtl = time.Second * time.Duration(conf.PersistTtl)
-	if ri.persistTtl == 0 {
-		ri.persistTtl = time.Hour
+	ri.applyConfig(conf)
+	if ri.journalSweepPeriod = conf.UploadJournal.SweepPeriodSec; ri.journalSweepPeriod <= 0 {
+		ri.journalSweepPeriod = defaultJournalSweepPeriod
+	}
+	if ri.eventsStreamMaxLen = conf.Events.StreamMaxLen; ri.eventsStreamMaxLen <= 0 {
+		ri.eventsStreamMaxLen = events.DefaultStreamMaxLen
+	}
+	if ri.auditFlushPeriod = conf.Audit.FlushPeriodSec; ri.auditFlushPeriod <= 0 {
+		ri.auditFlushPeriod = defaultAuditFlushPeriod
 	}
-	ri.defaultLimit = conf.DefaultLimit
-	if ri.defaultLimit == 0 {
-		ri.defaultLimit = 1 << 30
+	if ri.persistCodec, err = dumpCodecByName(conf.PersistCompression); err != nil {
+		return
 	}
-	ri.cidSubscriptions = make(map[string]map[chan struct{}]struct{})
+	ri.cidSubscriptions = make(map[string]map[*cidWaiter]struct{})
+	ri.bandwidth = make(map[bandwidthField]uint64)
+	ri.bandwidthBySpace = make(map[bandwidthField]uint64)
+	ri.metric, _ = a.Component(metric.CName).(metric.Metric)
+	ri.notifier, _ = a.Component(notifier.CName).(notifier.Notifier)
+	ri.metrics = newIndexMetrics()
 	ri.ctx, ri.ctxCancel = context.WithCancel(context.Background())
 	return
 }
@@ -146,13 +248,98 @@ func (ri *redisIndex) Name() (name string) {
 	return CName
 }
 
+func (ri *redisIndex) ReloadableFields() []string {
+	return []string{"defaultLimit", "persistTtl", "usageHistoryDays", "bandwidthHistoryDays", "quotaWarnThresholds", "quotaReservationTtlSec", "uploadJournal.staleSec", "cidWait"}
+}
+
+func (ri *redisIndex) Reload(ctx context.Context, conf app.Component) (err error) {
+	ri.applyConfig(conf.(*config.Config))
+	return
+}
+
+func (ri *redisIndex) applyConfig(conf *config.Config) {
+	persistTtl := time.Second * time.Duration(conf.PersistTtl)
+	if persistTtl == 0 {
+		persistTtl = time.Hour
+	}
+	ri.persistTtl.Store(int64(persistTtl))
+	defaultLimit := conf.DefaultLimit
+	if defaultLimit == 0 {
+		defaultLimit = 1 << 30
+	}
+	ri.defaultLimit.Store(defaultLimit)
+	ri.usageHistoryDays.Store(int64(conf.UsageHistoryDays))
+	ri.bandwidthHistoryDays.Store(int64(conf.BandwidthHistoryDays))
+	thresholds := conf.QuotaWarnThresholds
+	ri.quotaWarnThresholds.Store(&thresholds)
+	reservationTtl := time.Second * time.Duration(conf.QuotaReservationTtlSec)
+	if reservationTtl == 0 {
+		reservationTtl = defaultReservationTtl
+	}
+	ri.reservationTtl.Store(int64(reservationTtl))
+	journalStale := time.Second * time.Duration(conf.UploadJournal.StaleSec)
+	if journalStale == 0 {
+		journalStale = defaultJournalStale
+	}
+	ri.journalStale.Store(int64(journalStale))
+	cidWaitMax := time.Second * time.Duration(conf.CidWait.MaxWaitSec)
+	if cidWaitMax == 0 {
+		cidWaitMax = defaultCidWaitMax
+	}
+	ri.cidWaitMax.Store(int64(cidWaitMax))
+	cidWaitMaxWaiters := conf.CidWait.MaxWaiters
+	if cidWaitMaxWaiters == 0 {
+		cidWaitMaxWaiters = defaultCidWaitMaxWaiters
+	}
+	ri.cidWaitMaxWaiters.Store(int64(cidWaitMaxWaiters))
+	cidWaitRecheck := time.Second * time.Duration(conf.CidWait.RecheckSec)
+	if cidWaitRecheck == 0 {
+		cidWaitRecheck = defaultCidWaitRecheck
+	}
+	ri.cidWaitRecheck.Store(int64(cidWaitRecheck))
+}
+
 func (ri *redisIndex) Run(ctx context.Context) (err error) {
+	if ri.noBackground {
+		return
+	}
+	if ri.metric != nil {
+		ri.registerMetrics(ri.metric.Registry())
+	}
+	ri.lastPersist.Store(time.Now().Unix())
 	ri.ticker = periodicsync.NewPeriodicSync(60, time.Minute*10, func(ctx context.Context) error {
 		ri.PersistKeys(ctx)
 		return nil
 	}, log)
 	ri.ticker.Run()
-	go ri.subscription(ctx)
+	ri.bwTicker = periodicsync.NewPeriodicSync(bandwidthFlushPeriodSec, time.Minute, ri.flushBandwidth, log)
+	ri.bwTicker.Run()
+	ri.usageTicker = periodicsync.NewPeriodicSync(usageSnapshotPeriodSec, time.Minute*10, func(ctx context.Context) error {
+		return errors.Join(ri.SnapshotUsage(ctx), ri.trimBandwidth(ctx))
+	}, log)
+	ri.usageTicker.Run()
+	ri.sweepTicker = periodicsync.NewPeriodicSync(ri.journalSweepPeriod, time.Minute*10, ri.SweepUploadJournal, log)
+	ri.sweepTicker.Run()
+	ri.auditTicker = periodicsync.NewPeriodicSync(ri.auditFlushPeriod, time.Minute*10, ri.FlushAudit, log)
+	ri.auditTicker.Run()
+	ri.eventsTicker = periodicsync.NewPeriodicSync(eventsFlushPeriodSec, time.Minute*10, ri.FlushEvents, log)
+	ri.eventsTicker.Run()
+	go ri.subscription(ri.ctx)
+	ri.backfillDone = make(chan struct{})
+	go func() {
+		defer close(ri.backfillDone)
+		// a failed backfill is started again on the next start
+		if bErr := ri.BackfillSpaceGroups(ri.ctx); bErr != nil && ri.ctx.Err() == nil {
+			log.Warn("space groups backfill error", zap.Error(bErr))
+		}
+	}()
+	return
+}
+
+func (ri *redisIndex) HealthCheck(ctx context.Context) (err error) {
+	if time.Since(time.Unix(ri.lastPersist.Load(), 0)) > persistStaleTimeout {
+		return ErrPersistStale
+	}
 	return
 }
 
@@ -176,6 +363,22 @@ func (ri *redisIndex) FileInfo(ctx context.Context, key Key, fileIds ...string)
 	return
 }
 
+func (ri *redisIndex) FileCidEntries(ctx context.Context, key Key, fileId string) (entries *CidEntries, err error) {
+	_, release, err := ri.AcquireKey(ctx, spaceKey(key))
+	if err != nil {
+		return
+	}
+	defer release()
+	fEntry, isNew, err := ri.getFileEntry(ctx, key, fileId)
+	if err != nil {
+		return
+	}
+	if isNew {
+		return nil, ErrFileNotExists
+	}
+	return ri.CidEntriesByString(ctx, fEntry.Cids)
+}
+
 func (ri *redisIndex) FilesList(ctx context.Context, key Key) (fileIds []string, err error) {
 	sk := spaceKey(key)
 	_, release, err := ri.AcquireKey(ctx, sk)
@@ -271,9 +474,30 @@ func (ri *redisIndex) Close(ctx context.Context) error {
 	if ri.ticker != nil {
 		ri.ticker.Close()
 	}
+	if ri.bwTicker != nil {
+		ri.bwTicker.Close()
+		if err := ri.flushBandwidth(ctx); err != nil {
+			log.Warn("can't flush bandwidth counters", zap.Error(err))
+		}
+	}
+	if ri.usageTicker != nil {
+		ri.usageTicker.Close()
+	}
+	if ri.sweepTicker != nil {
+		ri.sweepTicker.Close()
+	}
+	if ri.auditTicker != nil {
+		ri.auditTicker.Close()
+	}
+	if ri.eventsTicker != nil {
+		ri.eventsTicker.Close()
+	}
 	if ri.ctxCancel != nil {
 		ri.ctxCancel()
 	}
+	if ri.backfillDone != nil {
+		<-ri.backfillDone
+	}
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/testutil/accounttest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -62,10 +63,11 @@ func newFixtureConfig(t *testing.T, conf *config.Config) (fx *fixture) {
 	}
 	fx.persistStore.EXPECT().Name().Return(s3store.CName).AnyTimes()
 	fx.persistStore.EXPECT().Init(gomock.Any()).AnyTimes()
+	fx.persistStore.EXPECT().IndexList(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	if conf == nil {
 		conf = &config.Config{DefaultLimit: 1024, PersistTtl: 3600}
 	}
-	fx.a.Register(testredisprovider.NewTestRedisProvider()).Register(fx.redisIndex).Register(fx.persistStore).Register(conf)
+	fx.a.Register(testredisprovider.NewTestRedisProvider()).Register(&accounttest.AccountTestService{}).Register(fx.redisIndex).Register(fx.persistStore).Register(conf)
 	require.NoError(t, fx.a.Start(ctx))
 	return
 }
@@ -22,6 +22,36 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
+type SpaceStatus int32
+
+const (
+	SpaceStatus_SpaceStatusOk SpaceStatus = 0
+	// space is going to be deleted, writes are rejected
+	SpaceStatus_SpaceStatusDeletionPending SpaceStatus = 1
+	// space is deleted but can be restored until the retention period is over
+	SpaceStatus_SpaceStatusDeleted SpaceStatus = 2
+)
+
+var SpaceStatus_name = map[int32]string{
+	0: "SpaceStatusOk",
+	1: "SpaceStatusDeletionPending",
+	2: "SpaceStatusDeleted",
+}
+
+var SpaceStatus_value = map[string]int32{
+	"SpaceStatusOk":              0,
+	"SpaceStatusDeletionPending": 1,
+	"SpaceStatusDeleted":         2,
+}
+
+func (x SpaceStatus) String() string {
+	return proto.EnumName(SpaceStatus_name, int32(x))
+}
+
+func (SpaceStatus) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_f1f29953df8d243b, []int{0}
+}
+
 type CidEntry struct {
 	Size_      uint64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
 	CreateTime int64  `protobuf:"varint,2,opt,name=createTime,proto3" json:"createTime,omitempty"`
@@ -151,6 +181,10 @@ type GroupEntry struct {
 	SpaceIds     []string `protobuf:"bytes,6,rep,name=spaceIds,proto3" json:"spaceIds,omitempty"`
 	Limit        uint64   `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
 	AccountLimit uint64   `protobuf:"varint,8,opt,name=accountLimit,proto3" json:"accountLimit,omitempty"`
+	// soft deleted spaces, hidden from spaceIds until restored or purged
+	DeletedSpaceIds []string `protobuf:"bytes,9,rep,name=deletedSpaceIds,proto3" json:"deletedSpaceIds,omitempty"`
+	// the highest crossed quota warning threshold in percents, 0 when the usage is below all thresholds
+	QuotaWarnLevel uint32 `protobuf:"varint,10,opt,name=quotaWarnLevel,proto3" json:"quotaWarnLevel,omitempty"`
 }
 
 func (m *GroupEntry) Reset()         { *m = GroupEntry{} }
@@ -242,14 +276,30 @@ func (m *GroupEntry) GetAccountLimit() uint64 {
 	return 0
 }
 
+func (m *GroupEntry) GetDeletedSpaceIds() []string {
+	if m != nil {
+		return m.DeletedSpaceIds
+	}
+	return nil
+}
+
+func (m *GroupEntry) GetQuotaWarnLevel() uint32 {
+	if m != nil {
+		return m.QuotaWarnLevel
+	}
+	return 0
+}
+
 type SpaceEntry struct {
-	GroupId    string `protobuf:"bytes,1,opt,name=groupId,proto3" json:"groupId,omitempty"`
-	CreateTime int64  `protobuf:"varint,2,opt,name=createTime,proto3" json:"createTime,omitempty"`
-	UpdateTime int64  `protobuf:"varint,3,opt,name=updateTime,proto3" json:"updateTime,omitempty"`
-	Size_      uint64 `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
-	FileCount  uint32 `protobuf:"varint,5,opt,name=fileCount,proto3" json:"fileCount,omitempty"`
-	CidCount   uint64 `protobuf:"varint,6,opt,name=cidCount,proto3" json:"cidCount,omitempty"`
-	Limit      uint64 `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	GroupId    string      `protobuf:"bytes,1,opt,name=groupId,proto3" json:"groupId,omitempty"`
+	CreateTime int64       `protobuf:"varint,2,opt,name=createTime,proto3" json:"createTime,omitempty"`
+	UpdateTime int64       `protobuf:"varint,3,opt,name=updateTime,proto3" json:"updateTime,omitempty"`
+	Size_      uint64      `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	FileCount  uint32      `protobuf:"varint,5,opt,name=fileCount,proto3" json:"fileCount,omitempty"`
+	CidCount   uint64      `protobuf:"varint,6,opt,name=cidCount,proto3" json:"cidCount,omitempty"`
+	Limit      uint64      `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	Status     SpaceStatus `protobuf:"varint,8,opt,name=status,proto3,enum=fileIndexProto.SpaceStatus" json:"status,omitempty"`
+	DeleteTime int64       `protobuf:"varint,9,opt,name=deleteTime,proto3" json:"deleteTime,omitempty"`
 }
 
 func (m *SpaceEntry) Reset()         { *m = SpaceEntry{} }
@@ -334,6 +384,20 @@ func (m *SpaceEntry) GetLimit() uint64 {
 	return 0
 }
 
+func (m *SpaceEntry) GetStatus() SpaceStatus {
+	if m != nil {
+		return m.Status
+	}
+	return SpaceStatus_SpaceStatusOk
+}
+
+func (m *SpaceEntry) GetDeleteTime() int64 {
+	if m != nil {
+		return m.DeleteTime
+	}
+	return 0
+}
+
 type FileEntry struct {
 	Cids       []string `protobuf:"bytes,1,rep,name=cids,proto3" json:"cids,omitempty"`
 	Size_      uint64   `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
@@ -402,232 +466,492 @@ func (m *FileEntry) GetUpdateTime() int64 {
 	return 0
 }
 
-func init() {
-	proto.RegisterType((*CidEntry)(nil), "fileIndexProto.CidEntry")
-	proto.RegisterType((*CidList)(nil), "fileIndexProto.CidList")
-	proto.RegisterType((*GroupEntry)(nil), "fileIndexProto.GroupEntry")
-	proto.RegisterType((*SpaceEntry)(nil), "fileIndexProto.SpaceEntry")
-	proto.RegisterType((*FileEntry)(nil), "fileIndexProto.FileEntry")
+type DeletionReport struct {
+	SpaceId    string `protobuf:"bytes,1,opt,name=spaceId,proto3" json:"spaceId,omitempty"`
+	GroupId    string `protobuf:"bytes,2,opt,name=groupId,proto3" json:"groupId,omitempty"`
+	FileCount  uint32 `protobuf:"varint,3,opt,name=fileCount,proto3" json:"fileCount,omitempty"`
+	CidCount   uint64 `protobuf:"varint,4,opt,name=cidCount,proto3" json:"cidCount,omitempty"`
+	BytesFreed uint64 `protobuf:"varint,5,opt,name=bytesFreed,proto3" json:"bytesFreed,omitempty"`
+	Timestamp  int64  `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 }
 
-func init() {
-	proto.RegisterFile("index/indexproto/protos/index.proto", fileDescriptor_f1f29953df8d243b)
+func (m *DeletionReport) Reset()         { *m = DeletionReport{} }
+func (m *DeletionReport) String() string { return proto.CompactTextString(m) }
+func (*DeletionReport) ProtoMessage()    {}
+func (*DeletionReport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f1f29953df8d243b, []int{5}
 }
-
-var fileDescriptor_f1f29953df8d243b = []byte{
-	// 372 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x53, 0xcd, 0x4a, 0xf3, 0x40,
-	0x14, 0xed, 0x34, 0x69, 0xd3, 0x5c, 0xda, 0x8f, 0x8f, 0xc1, 0xc5, 0x20, 0x3a, 0x84, 0xb8, 0x09,
-	0x2e, 0xec, 0xc2, 0x37, 0xb0, 0xa8, 0x14, 0xba, 0x90, 0xd1, 0x95, 0xbb, 0x9a, 0x99, 0xca, 0x40,
-	0x9b, 0x84, 0x4c, 0x2a, 0xea, 0x33, 0xb8, 0xf0, 0xb1, 0xc4, 0x55, 0x97, 0x2e, 0xa5, 0xdd, 0xfa,
-	0x10, 0x32, 0x37, 0x34, 0xfd, 0x41, 0x71, 0x23, 0xb8, 0x99, 0xdc, 0x73, 0x0e, 0x77, 0x38, 0xe7,
-	0xce, 0x0d, 0x1c, 0xe8, 0x44, 0xaa, 0xfb, 0x2e, 0x9e, 0x59, 0x9e, 0x16, 0x69, 0x17, 0x4f, 0x53,
-	0x32, 0x47, 0x08, 0xe8, 0xbf, 0x91, 0x1e, 0xab, 0xbe, 0x25, 0x2e, 0x2c, 0x0e, 0x9f, 0x08, 0xb4,
-	0x7a, 0x5a, 0x9e, 0x26, 0x45, 0xfe, 0x40, 0x29, 0xb8, 0x46, 0x3f, 0x2a, 0x46, 0x02, 0x12, 0xb9,
-	0x02, 0x6b, 0xca, 0x01, 0xe2, 0x5c, 0x0d, 0x0b, 0x75, 0xa5, 0x27, 0x8a, 0xd5, 0x03, 0x12, 0x39,
-	0x62, 0x8d, 0xb1, 0xfa, 0x34, 0x93, 0x4b, 0xdd, 0x29, 0xf5, 0x15, 0x63, 0xef, 0xcc, 0xd5, 0xc8,
-	0x30, 0x37, 0x20, 0x51, 0x43, 0x60, 0x4d, 0x19, 0x78, 0x77, 0x2a, 0x37, 0x3a, 0x4d, 0x58, 0x23,
-	0x20, 0x51, 0x47, 0x2c, 0x61, 0xb8, 0x0f, 0x5e, 0x4f, 0xcb, 0x81, 0x36, 0x85, 0x6d, 0x8c, 0xb5,
-	0x34, 0x8c, 0x04, 0x4e, 0xd4, 0x16, 0x58, 0x87, 0x1f, 0x04, 0xe0, 0x3c, 0x4f, 0xa7, 0x59, 0xe9,
-	0x97, 0x81, 0x77, 0x6b, 0x51, 0x5f, 0xa2, 0x65, 0x5f, 0x2c, 0xe1, 0x6f, 0xb8, 0xc6, 0x49, 0xb8,
-	0x6b, 0x93, 0xd8, 0x85, 0x56, 0xac, 0x65, 0x2f, 0x9d, 0x26, 0x05, 0xda, 0x76, 0x45, 0x85, 0xad,
-	0x66, 0xb2, 0x61, 0xac, 0xfa, 0xd2, 0xb0, 0x66, 0xe0, 0x44, 0xbe, 0xa8, 0x30, 0xdd, 0x81, 0xc6,
-	0x58, 0x4f, 0x74, 0xc1, 0x3c, 0x6c, 0x2a, 0x01, 0x0d, 0xa1, 0x3d, 0x8c, 0x63, 0xdb, 0x3c, 0x40,
-	0xb1, 0x85, 0xe2, 0x06, 0x17, 0xbe, 0x12, 0x80, 0x4b, 0x7b, 0xcd, 0x5f, 0xc4, 0xdd, 0x03, 0xdf,
-	0xee, 0xca, 0x2a, 0x6f, 0x47, 0xac, 0x88, 0x8d, 0x61, 0x34, 0xb7, 0x86, 0xf1, 0x65, 0xe0, 0xd0,
-	0x80, 0x7f, 0xa6, 0xc7, 0xaa, 0xda, 0xb4, 0xea, 0x71, 0xfd, 0xf2, 0x71, 0x2b, 0x13, 0xf5, 0x6f,
-	0xb7, 0xcf, 0xf9, 0x21, 0x98, 0xbb, 0x1d, 0xec, 0xe4, 0xf0, 0x65, 0xce, 0xc9, 0x6c, 0xce, 0xc9,
-	0xfb, 0x9c, 0x93, 0xe7, 0x05, 0xaf, 0xcd, 0x16, 0xbc, 0xf6, 0xb6, 0xe0, 0xb5, 0xeb, 0xff, 0xdb,
-	0x7f, 0xcb, 0x4d, 0x13, 0x3f, 0xc7, 0x9f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x64, 0x5b, 0xf6, 0x08,
-	0x48, 0x03, 0x00, 0x00,
+func (m *DeletionReport) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
-
-func (m *CidEntry) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func (m *DeletionReport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DeletionReport.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	return dAtA[:n], nil
 }
-
-func (m *CidEntry) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *DeletionReport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeletionReport.Merge(m, src)
+}
+func (m *DeletionReport) XXX_Size() int {
+	return m.Size()
+}
+func (m *DeletionReport) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeletionReport.DiscardUnknown(m)
 }
 
-func (m *CidEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.Version != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.Version))
-		i--
-		dAtA[i] = 0x28
+var xxx_messageInfo_DeletionReport proto.InternalMessageInfo
+
+func (m *DeletionReport) GetSpaceId() string {
+	if m != nil {
+		return m.SpaceId
 	}
-	if m.Refs != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.Refs))
-		i--
-		dAtA[i] = 0x20
+	return ""
+}
+
+func (m *DeletionReport) GetGroupId() string {
+	if m != nil {
+		return m.GroupId
 	}
-	if m.UpdateTime != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.UpdateTime))
-		i--
-		dAtA[i] = 0x18
+	return ""
+}
+
+func (m *DeletionReport) GetFileCount() uint32 {
+	if m != nil {
+		return m.FileCount
 	}
-	if m.CreateTime != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.CreateTime))
-		i--
-		dAtA[i] = 0x10
+	return 0
+}
+
+func (m *DeletionReport) GetCidCount() uint64 {
+	if m != nil {
+		return m.CidCount
 	}
-	if m.Size_ != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
-		i--
-		dAtA[i] = 0x8
+	return 0
+}
+
+func (m *DeletionReport) GetBytesFreed() uint64 {
+	if m != nil {
+		return m.BytesFreed
 	}
-	return len(dAtA) - i, nil
+	return 0
 }
 
-func (m *CidList) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func (m *DeletionReport) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *CidList) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+type SignedDeletionReport struct {
+	// marshaled DeletionReport
+	Report []byte `protobuf:"bytes,1,opt,name=report,proto3" json:"report,omitempty"`
+	// signature of the report made by the node sign key
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	// account of the node that signed the report
+	Identity string `protobuf:"bytes,3,opt,name=identity,proto3" json:"identity,omitempty"`
 }
 
-func (m *CidList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if len(m.Cids) > 0 {
-		for iNdEx := len(m.Cids) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Cids[iNdEx])
-			copy(dAtA[i:], m.Cids[iNdEx])
-			i = encodeVarintIndex(dAtA, i, uint64(len(m.Cids[iNdEx])))
-			i--
-			dAtA[i] = 0xa
+func (m *SignedDeletionReport) Reset()         { *m = SignedDeletionReport{} }
+func (m *SignedDeletionReport) String() string { return proto.CompactTextString(m) }
+func (*SignedDeletionReport) ProtoMessage()    {}
+func (*SignedDeletionReport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f1f29953df8d243b, []int{6}
+}
+func (m *SignedDeletionReport) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SignedDeletionReport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SignedDeletionReport.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
+		return b[:n], nil
 	}
-	return len(dAtA) - i, nil
+}
+func (m *SignedDeletionReport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignedDeletionReport.Merge(m, src)
+}
+func (m *SignedDeletionReport) XXX_Size() int {
+	return m.Size()
+}
+func (m *SignedDeletionReport) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignedDeletionReport.DiscardUnknown(m)
 }
 
-func (m *GroupEntry) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_SignedDeletionReport proto.InternalMessageInfo
+
+func (m *SignedDeletionReport) GetReport() []byte {
+	if m != nil {
+		return m.Report
 	}
-	return dAtA[:n], nil
+	return nil
 }
 
-func (m *GroupEntry) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *SignedDeletionReport) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
 }
 
-func (m *GroupEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.AccountLimit != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.AccountLimit))
-		i--
-		dAtA[i] = 0x40
-	}
-	if m.Limit != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.Limit))
-		i--
-		dAtA[i] = 0x38
+func (m *SignedDeletionReport) GetIdentity() string {
+	if m != nil {
+		return m.Identity
 	}
-	if len(m.SpaceIds) > 0 {
-		for iNdEx := len(m.SpaceIds) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.SpaceIds[iNdEx])
-			copy(dAtA[i:], m.SpaceIds[iNdEx])
-			i = encodeVarintIndex(dAtA, i, uint64(len(m.SpaceIds[iNdEx])))
-			i--
-			dAtA[i] = 0x32
+	return ""
+}
+
+type UsageSnapshot struct {
+	Size_     uint64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	CidCount  uint64 `protobuf:"varint,2,opt,name=cidCount,proto3" json:"cidCount,omitempty"`
+	FileCount uint32 `protobuf:"varint,3,opt,name=fileCount,proto3" json:"fileCount,omitempty"`
+}
+
+func (m *UsageSnapshot) Reset()         { *m = UsageSnapshot{} }
+func (m *UsageSnapshot) String() string { return proto.CompactTextString(m) }
+func (*UsageSnapshot) ProtoMessage()    {}
+func (*UsageSnapshot) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f1f29953df8d243b, []int{7}
+}
+func (m *UsageSnapshot) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *UsageSnapshot) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_UsageSnapshot.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
+		return b[:n], nil
 	}
-	if m.CidCount != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.CidCount))
-		i--
-		dAtA[i] = 0x28
-	}
-	if m.Size_ != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
-		i--
-		dAtA[i] = 0x20
-	}
-	if m.UpdateTime != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.UpdateTime))
-		i--
-		dAtA[i] = 0x18
-	}
-	if m.CreateTime != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.CreateTime))
-		i--
-		dAtA[i] = 0x10
-	}
-	if len(m.GroupId) > 0 {
-		i -= len(m.GroupId)
-		copy(dAtA[i:], m.GroupId)
-		i = encodeVarintIndex(dAtA, i, uint64(len(m.GroupId)))
-		i--
-		dAtA[i] = 0xa
-	}
-	return len(dAtA) - i, nil
+}
+func (m *UsageSnapshot) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UsageSnapshot.Merge(m, src)
+}
+func (m *UsageSnapshot) XXX_Size() int {
+	return m.Size()
+}
+func (m *UsageSnapshot) XXX_DiscardUnknown() {
+	xxx_messageInfo_UsageSnapshot.DiscardUnknown(m)
 }
 
-func (m *SpaceEntry) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_UsageSnapshot proto.InternalMessageInfo
+
+func (m *UsageSnapshot) GetSize_() uint64 {
+	if m != nil {
+		return m.Size_
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *SpaceEntry) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *UsageSnapshot) GetCidCount() uint64 {
+	if m != nil {
+		return m.CidCount
+	}
+	return 0
 }
 
-func (m *SpaceEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *UsageSnapshot) GetFileCount() uint32 {
+	if m != nil {
+		return m.FileCount
+	}
+	return 0
+}
+
+type AuditEntry struct {
+	Op string `protobuf:"bytes,1,opt,name=op,proto3" json:"op,omitempty"`
+	// account of the peer made the change, empty for changes made by the node itself
+	Identity       string   `protobuf:"bytes,2,opt,name=identity,proto3" json:"identity,omitempty"`
+	GroupId        string   `protobuf:"bytes,3,opt,name=groupId,proto3" json:"groupId,omitempty"`
+	SpaceId        string   `protobuf:"bytes,4,opt,name=spaceId,proto3" json:"spaceId,omitempty"`
+	FileIds        []string `protobuf:"bytes,5,rep,name=fileIds,proto3" json:"fileIds,omitempty"`
+	SpaceSizeDelta int64    `protobuf:"varint,6,opt,name=spaceSizeDelta,proto3" json:"spaceSizeDelta,omitempty"`
+	GroupSizeDelta int64    `protobuf:"varint,7,opt,name=groupSizeDelta,proto3" json:"groupSizeDelta,omitempty"`
+	// new limit for limit changes
+	Limit uint64 `protobuf:"varint,8,opt,name=limit,proto3" json:"limit,omitempty"`
+	// unix time in milliseconds
+	Timestamp int64 `protobuf:"varint,9,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// id of the entry in the audit stream, it's set when the entry is flushed to a segment
+	StreamId string `protobuf:"bytes,10,opt,name=streamId,proto3" json:"streamId,omitempty"`
+	// new status for status changes
+	Status SpaceStatus `protobuf:"varint,11,opt,name=status,proto3,enum=fileIndexProto.SpaceStatus" json:"status,omitempty"`
+	// source space for copied files
+	SourceSpaceId string `protobuf:"bytes,12,opt,name=sourceSpaceId,proto3" json:"sourceSpaceId,omitempty"`
+}
+
+func (m *AuditEntry) Reset()         { *m = AuditEntry{} }
+func (m *AuditEntry) String() string { return proto.CompactTextString(m) }
+func (*AuditEntry) ProtoMessage()    {}
+func (*AuditEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f1f29953df8d243b, []int{8}
+}
+func (m *AuditEntry) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *AuditEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_AuditEntry.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *AuditEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEntry.Merge(m, src)
+}
+func (m *AuditEntry) XXX_Size() int {
+	return m.Size()
+}
+func (m *AuditEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEntry proto.InternalMessageInfo
+
+func (m *AuditEntry) GetOp() string {
+	if m != nil {
+		return m.Op
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetIdentity() string {
+	if m != nil {
+		return m.Identity
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetGroupId() string {
+	if m != nil {
+		return m.GroupId
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetSpaceId() string {
+	if m != nil {
+		return m.SpaceId
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetFileIds() []string {
+	if m != nil {
+		return m.FileIds
+	}
+	return nil
+}
+
+func (m *AuditEntry) GetSpaceSizeDelta() int64 {
+	if m != nil {
+		return m.SpaceSizeDelta
+	}
+	return 0
+}
+
+func (m *AuditEntry) GetGroupSizeDelta() int64 {
+	if m != nil {
+		return m.GroupSizeDelta
+	}
+	return 0
+}
+
+func (m *AuditEntry) GetLimit() uint64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *AuditEntry) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *AuditEntry) GetStreamId() string {
+	if m != nil {
+		return m.StreamId
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetStatus() SpaceStatus {
+	if m != nil {
+		return m.Status
+	}
+	return SpaceStatus_SpaceStatusOk
+}
+
+func (m *AuditEntry) GetSourceSpaceId() string {
+	if m != nil {
+		return m.SourceSpaceId
+	}
+	return ""
+}
+
+type AuditSegment struct {
+	Entries []*AuditEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+}
+
+func (m *AuditSegment) Reset()         { *m = AuditSegment{} }
+func (m *AuditSegment) String() string { return proto.CompactTextString(m) }
+func (*AuditSegment) ProtoMessage()    {}
+func (*AuditSegment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f1f29953df8d243b, []int{9}
+}
+func (m *AuditSegment) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *AuditSegment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_AuditSegment.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *AuditSegment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditSegment.Merge(m, src)
+}
+func (m *AuditSegment) XXX_Size() int {
+	return m.Size()
+}
+func (m *AuditSegment) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditSegment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditSegment proto.InternalMessageInfo
+
+func (m *AuditSegment) GetEntries() []*AuditEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("fileIndexProto.SpaceStatus", SpaceStatus_name, SpaceStatus_value)
+	proto.RegisterType((*CidEntry)(nil), "fileIndexProto.CidEntry")
+	proto.RegisterType((*CidList)(nil), "fileIndexProto.CidList")
+	proto.RegisterType((*GroupEntry)(nil), "fileIndexProto.GroupEntry")
+	proto.RegisterType((*SpaceEntry)(nil), "fileIndexProto.SpaceEntry")
+	proto.RegisterType((*FileEntry)(nil), "fileIndexProto.FileEntry")
+	proto.RegisterType((*DeletionReport)(nil), "fileIndexProto.DeletionReport")
+	proto.RegisterType((*SignedDeletionReport)(nil), "fileIndexProto.SignedDeletionReport")
+	proto.RegisterType((*UsageSnapshot)(nil), "fileIndexProto.UsageSnapshot")
+	proto.RegisterType((*AuditEntry)(nil), "fileIndexProto.AuditEntry")
+	proto.RegisterType((*AuditSegment)(nil), "fileIndexProto.AuditSegment")
+}
+
+func init() {
+	proto.RegisterFile("index/indexproto/protos/index.proto", fileDescriptor_f1f29953df8d243b)
+}
+
+var fileDescriptor_f1f29953df8d243b = []byte{
+	// 760 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x55, 0xcd, 0x6e, 0xeb, 0x44,
+	0x14, 0x8e, 0x7f, 0xf2, 0xe3, 0xd3, 0x24, 0x04, 0xab, 0xaa, 0xac, 0x02, 0x56, 0x64, 0x10, 0x8a,
+	0xba, 0x68, 0xa5, 0x96, 0x17, 0x80, 0x96, 0xa2, 0x48, 0x95, 0xa8, 0x26, 0x20, 0x10, 0x12, 0x0b,
+	0x37, 0x73, 0x9a, 0x8e, 0x48, 0x6c, 0xe3, 0x19, 0x57, 0xb4, 0x3b, 0xf6, 0x2c, 0x78, 0x03, 0x5e,
+	0x04, 0xb1, 0x66, 0xd9, 0x25, 0x4b, 0xd4, 0xbe, 0x08, 0x9a, 0x63, 0x3b, 0xfe, 0xa1, 0xb7, 0xf7,
+	0x2e, 0xae, 0x74, 0x37, 0xe9, 0x7c, 0xdf, 0x9c, 0x19, 0x9f, 0xf3, 0x7d, 0xe7, 0x4c, 0xe1, 0x63,
+	0x11, 0x71, 0xfc, 0xe5, 0x88, 0x7e, 0x93, 0x34, 0x56, 0xf1, 0x11, 0xfd, 0xca, 0x9c, 0x39, 0x24,
+	0xe0, 0x8e, 0xaf, 0xc5, 0x1a, 0xe7, 0x9a, 0xb8, 0xd4, 0x38, 0xf8, 0xcd, 0x80, 0xc1, 0xa9, 0xe0,
+	0x5f, 0x46, 0x2a, 0xbd, 0x73, 0x5d, 0xb0, 0xa5, 0xb8, 0x47, 0xcf, 0x98, 0x1a, 0x33, 0x9b, 0xd1,
+	0xda, 0xf5, 0x01, 0x96, 0x29, 0x86, 0x0a, 0xbf, 0x11, 0x1b, 0xf4, 0xcc, 0xa9, 0x31, 0xb3, 0x58,
+	0x8d, 0xd1, 0xfb, 0x59, 0xc2, 0xcb, 0x7d, 0x2b, 0xdf, 0xaf, 0x18, 0x7d, 0x67, 0x8a, 0xd7, 0xd2,
+	0xb3, 0xa7, 0xc6, 0xac, 0xcb, 0x68, 0xed, 0x7a, 0xd0, 0xbf, 0xc5, 0x54, 0x8a, 0x38, 0xf2, 0xba,
+	0x53, 0x63, 0x36, 0x62, 0x25, 0x0c, 0x3e, 0x82, 0xfe, 0xa9, 0xe0, 0x17, 0x42, 0x2a, 0x7d, 0x70,
+	0x29, 0xb8, 0xf4, 0x8c, 0xa9, 0x35, 0x1b, 0x32, 0x5a, 0x07, 0x7f, 0x99, 0x00, 0x5f, 0xa5, 0x71,
+	0x96, 0xe4, 0xf9, 0x7a, 0xd0, 0x5f, 0x69, 0x34, 0xe7, 0x94, 0xb2, 0xc3, 0x4a, 0xf8, 0x36, 0xb2,
+	0x26, 0x25, 0xec, 0x9a, 0x12, 0xfb, 0x30, 0x58, 0x0a, 0x7e, 0x1a, 0x67, 0x91, 0xa2, 0xb4, 0x6d,
+	0xb6, 0xc5, 0x7a, 0x4f, 0x26, 0xe1, 0x12, 0xe7, 0x5c, 0x7a, 0xbd, 0xa9, 0x35, 0x73, 0xd8, 0x16,
+	0xbb, 0xbb, 0xd0, 0x5d, 0x8b, 0x8d, 0x50, 0x5e, 0x9f, 0x0e, 0xe5, 0xc0, 0x0d, 0x60, 0x18, 0x2e,
+	0x97, 0xfa, 0xf0, 0x05, 0x6d, 0x0e, 0x68, 0xb3, 0xc1, 0xb9, 0x33, 0x78, 0x8f, 0xe3, 0x1a, 0x15,
+	0xf2, 0x45, 0x79, 0xb9, 0x43, 0x97, 0xb7, 0x69, 0xf7, 0x53, 0x18, 0xff, 0x9c, 0xc5, 0x2a, 0xfc,
+	0x2e, 0x4c, 0xa3, 0x0b, 0xbc, 0xc5, 0xb5, 0x07, 0x24, 0x6c, 0x8b, 0x0d, 0xfe, 0x30, 0x01, 0xe8,
+	0xd0, 0xbb, 0x10, 0xf0, 0x43, 0x70, 0x74, 0xf7, 0x55, 0x0a, 0x8e, 0x58, 0x45, 0x34, 0xe4, 0xed,
+	0xb5, 0xe4, 0x7d, 0x5e, 0xc2, 0x13, 0xe8, 0x49, 0x15, 0xaa, 0x4c, 0x92, 0x78, 0xe3, 0xe3, 0x0f,
+	0x0e, 0x9b, 0xcd, 0x7d, 0x48, 0x95, 0x2e, 0x28, 0x84, 0x15, 0xa1, 0x3a, 0xf1, 0x5c, 0x3c, 0x4a,
+	0xdc, 0xc9, 0x13, 0xaf, 0x98, 0x40, 0x82, 0x73, 0x2e, 0xd6, 0xb8, 0x1d, 0x88, 0x6d, 0x0f, 0x3a,
+	0x79, 0x0f, 0x6e, 0x2b, 0x33, 0x5f, 0x39, 0x24, 0xd6, 0x6b, 0xd4, 0xb2, 0xdb, 0x6a, 0x05, 0x7f,
+	0x1a, 0x30, 0x3e, 0xd3, 0x39, 0x88, 0x38, 0x62, 0x98, 0xc4, 0xa9, 0xd2, 0xd6, 0x14, 0x1d, 0x54,
+	0x5a, 0x53, 0xc0, 0xba, 0x69, 0x66, 0xd3, 0xb4, 0x86, 0xc0, 0xd6, 0x4b, 0x02, 0xdb, 0x2d, 0x81,
+	0x7d, 0x80, 0xab, 0x3b, 0x85, 0xf2, 0x3c, 0x45, 0xe4, 0x45, 0x77, 0xd7, 0x18, 0x7d, 0xb3, 0x12,
+	0x1b, 0x94, 0x2a, 0xdc, 0x24, 0xe4, 0x8e, 0xc5, 0x2a, 0x22, 0xb8, 0x81, 0xdd, 0x85, 0x58, 0x45,
+	0xc8, 0x5b, 0x35, 0xec, 0x41, 0x2f, 0xa5, 0x15, 0x95, 0x30, 0x64, 0x05, 0xd2, 0xb7, 0x49, 0xb1,
+	0x8a, 0x42, 0x95, 0xa5, 0xb9, 0x8e, 0x43, 0x56, 0x11, 0x3a, 0x4f, 0xc1, 0x31, 0x52, 0x42, 0xdd,
+	0x51, 0x11, 0x0e, 0xdb, 0xe2, 0xe0, 0x47, 0x18, 0x7d, 0x2b, 0xc3, 0x15, 0x2e, 0xa2, 0x30, 0x91,
+	0x37, 0xb1, 0x7a, 0xf6, 0xc9, 0xaa, 0x17, 0x6a, 0xb6, 0x0a, 0x7d, 0x51, 0xa2, 0xe0, 0x57, 0x0b,
+	0xe0, 0xf3, 0x8c, 0x0b, 0x95, 0xdb, 0x3f, 0x06, 0x33, 0x4e, 0x0a, 0xf9, 0xcd, 0x38, 0x69, 0x64,
+	0x66, 0x36, 0x33, 0xab, 0xbb, 0x62, 0x35, 0x5d, 0xa9, 0x39, 0x69, 0xff, 0xcf, 0x49, 0xea, 0x58,
+	0x2e, 0xbd, 0x2e, 0x75, 0x58, 0x09, 0xf5, 0x3c, 0x53, 0xd0, 0x42, 0xdc, 0xe3, 0x19, 0xae, 0x55,
+	0x58, 0x88, 0xde, 0x62, 0x75, 0x1c, 0x7d, 0xa6, 0x8a, 0xeb, 0xe7, 0x71, 0x4d, 0xb6, 0x1a, 0xa0,
+	0x41, 0x7d, 0x80, 0x1a, 0xae, 0x3a, 0x2d, 0x57, 0xe9, 0x4d, 0x53, 0x29, 0x86, 0x9b, 0x39, 0xa7,
+	0xd7, 0xc4, 0x61, 0x5b, 0x5c, 0x1b, 0xbd, 0x9d, 0x37, 0x1f, 0xbd, 0x4f, 0x60, 0x24, 0xe3, 0x2c,
+	0x5d, 0x62, 0xf1, 0x6c, 0x79, 0x43, 0xba, 0xb5, 0x49, 0x06, 0x67, 0x30, 0x24, 0x0b, 0x16, 0xb8,
+	0xda, 0x60, 0xa4, 0xdc, 0xcf, 0xa0, 0x8f, 0x91, 0x4a, 0x05, 0xe6, 0x63, 0xb8, 0x73, 0xbc, 0xdf,
+	0xfe, 0x56, 0xe5, 0x18, 0x2b, 0x43, 0x0f, 0xbe, 0x87, 0x9d, 0x5a, 0x0a, 0xee, 0xfb, 0x30, 0xaa,
+	0xc1, 0xaf, 0x7f, 0x9a, 0x74, 0x5c, 0x1f, 0xf6, 0x6b, 0x54, 0xd9, 0xb9, 0x97, 0x18, 0x71, 0x11,
+	0xad, 0x26, 0x86, 0xbb, 0x07, 0x6e, 0x7b, 0x1f, 0xf9, 0xc4, 0xfc, 0xe2, 0xe0, 0xef, 0x47, 0xdf,
+	0x78, 0x78, 0xf4, 0x8d, 0x7f, 0x1f, 0x7d, 0xe3, 0xf7, 0x27, 0xbf, 0xf3, 0xf0, 0xe4, 0x77, 0xfe,
+	0x79, 0xf2, 0x3b, 0x3f, 0x4c, 0xda, 0xff, 0x80, 0xaf, 0x7a, 0xf4, 0xe7, 0xe4, 0xbf, 0x01, 0x00,
+	0x24, 0xb4, 0x2d, 0x55, 0x9b, 0x07, 0x00, 0x00,
+}
+
+func (m *CidEntry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *CidEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *CidEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if m.Limit != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.Limit))
-		i--
-		dAtA[i] = 0x38
-	}
-	if m.CidCount != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.CidCount))
-		i--
-		dAtA[i] = 0x30
-	}
-	if m.FileCount != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.FileCount))
+	if m.Version != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Version))
 		i--
 		dAtA[i] = 0x28
 	}
-	if m.Size_ != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
+	if m.Refs != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Refs))
 		i--
 		dAtA[i] = 0x20
 	}
@@ -641,17 +965,15 @@ func (m *SpaceEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x10
 	}
-	if len(m.GroupId) > 0 {
-		i -= len(m.GroupId)
-		copy(dAtA[i:], m.GroupId)
-		i = encodeVarintIndex(dAtA, i, uint64(len(m.GroupId)))
+	if m.Size_ != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
 		i--
-		dAtA[i] = 0xa
+		dAtA[i] = 0x8
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *FileEntry) Marshal() (dAtA []byte, err error) {
+func (m *CidList) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -661,31 +983,16 @@ func (m *FileEntry) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *FileEntry) MarshalTo(dAtA []byte) (int, error) {
+func (m *CidList) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *FileEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *CidList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if m.UpdateTime != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.UpdateTime))
-		i--
-		dAtA[i] = 0x20
-	}
-	if m.CreateTime != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.CreateTime))
-		i--
-		dAtA[i] = 0x18
-	}
-	if m.Size_ != 0 {
-		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
-		i--
-		dAtA[i] = 0x10
-	}
 	if len(m.Cids) > 0 {
 		for iNdEx := len(m.Cids) - 1; iNdEx >= 0; iNdEx-- {
 			i -= len(m.Cids[iNdEx])
@@ -698,155 +1005,1661 @@ func (m *FileEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func encodeVarintIndex(dAtA []byte, offset int, v uint64) int {
-	offset -= sovIndex(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *GroupEntry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return base
+	return dAtA[:n], nil
 }
-func (m *CidEntry) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if m.Size_ != 0 {
-		n += 1 + sovIndex(uint64(m.Size_))
-	}
-	if m.CreateTime != 0 {
-		n += 1 + sovIndex(uint64(m.CreateTime))
-	}
-	if m.UpdateTime != 0 {
-		n += 1 + sovIndex(uint64(m.UpdateTime))
-	}
-	if m.Refs != 0 {
-		n += 1 + sovIndex(uint64(m.Refs))
-	}
-	if m.Version != 0 {
-		n += 1 + sovIndex(uint64(m.Version))
-	}
-	return n
+
+func (m *GroupEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *CidList) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+func (m *GroupEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.Cids) > 0 {
-		for _, b := range m.Cids {
-			l = len(b)
-			n += 1 + l + sovIndex(uint64(l))
+	if m.QuotaWarnLevel != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.QuotaWarnLevel))
+		i--
+		dAtA[i] = 0x50
+	}
+	if len(m.DeletedSpaceIds) > 0 {
+		for iNdEx := len(m.DeletedSpaceIds) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.DeletedSpaceIds[iNdEx])
+			copy(dAtA[i:], m.DeletedSpaceIds[iNdEx])
+			i = encodeVarintIndex(dAtA, i, uint64(len(m.DeletedSpaceIds[iNdEx])))
+			i--
+			dAtA[i] = 0x4a
 		}
 	}
-	return n
-}
-
-func (m *GroupEntry) Size() (n int) {
-	if m == nil {
-		return 0
+	if m.AccountLimit != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.AccountLimit))
+		i--
+		dAtA[i] = 0x40
 	}
-	var l int
-	_ = l
-	l = len(m.GroupId)
-	if l > 0 {
-		n += 1 + l + sovIndex(uint64(l))
+	if m.Limit != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Limit))
+		i--
+		dAtA[i] = 0x38
 	}
-	if m.CreateTime != 0 {
-		n += 1 + sovIndex(uint64(m.CreateTime))
+	if len(m.SpaceIds) > 0 {
+		for iNdEx := len(m.SpaceIds) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.SpaceIds[iNdEx])
+			copy(dAtA[i:], m.SpaceIds[iNdEx])
+			i = encodeVarintIndex(dAtA, i, uint64(len(m.SpaceIds[iNdEx])))
+			i--
+			dAtA[i] = 0x32
+		}
 	}
-	if m.UpdateTime != 0 {
-		n += 1 + sovIndex(uint64(m.UpdateTime))
+	if m.CidCount != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.CidCount))
+		i--
+		dAtA[i] = 0x28
 	}
 	if m.Size_ != 0 {
-		n += 1 + sovIndex(uint64(m.Size_))
-	}
-	if m.CidCount != 0 {
-		n += 1 + sovIndex(uint64(m.CidCount))
+		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
+		i--
+		dAtA[i] = 0x20
 	}
-	if len(m.SpaceIds) > 0 {
-		for _, s := range m.SpaceIds {
-			l = len(s)
-			n += 1 + l + sovIndex(uint64(l))
-		}
+	if m.UpdateTime != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.UpdateTime))
+		i--
+		dAtA[i] = 0x18
 	}
-	if m.Limit != 0 {
-		n += 1 + sovIndex(uint64(m.Limit))
+	if m.CreateTime != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.CreateTime))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.AccountLimit != 0 {
-		n += 1 + sovIndex(uint64(m.AccountLimit))
+	if len(m.GroupId) > 0 {
+		i -= len(m.GroupId)
+		copy(dAtA[i:], m.GroupId)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.GroupId)))
+		i--
+		dAtA[i] = 0xa
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *SpaceEntry) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *SpaceEntry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *SpaceEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SpaceEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.GroupId)
-	if l > 0 {
-		n += 1 + l + sovIndex(uint64(l))
+	if m.DeleteTime != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.DeleteTime))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.Status != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Status))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.Limit != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Limit))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.CidCount != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.CidCount))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.FileCount != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.FileCount))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Size_ != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.UpdateTime != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.UpdateTime))
+		i--
+		dAtA[i] = 0x18
 	}
 	if m.CreateTime != 0 {
-		n += 1 + sovIndex(uint64(m.CreateTime))
+		i = encodeVarintIndex(dAtA, i, uint64(m.CreateTime))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.GroupId) > 0 {
+		i -= len(m.GroupId)
+		copy(dAtA[i:], m.GroupId)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.GroupId)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *FileEntry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *FileEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FileEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
 	if m.UpdateTime != 0 {
-		n += 1 + sovIndex(uint64(m.UpdateTime))
+		i = encodeVarintIndex(dAtA, i, uint64(m.UpdateTime))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.CreateTime != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.CreateTime))
+		i--
+		dAtA[i] = 0x18
 	}
 	if m.Size_ != 0 {
-		n += 1 + sovIndex(uint64(m.Size_))
+		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.FileCount != 0 {
-		n += 1 + sovIndex(uint64(m.FileCount))
+	if len(m.Cids) > 0 {
+		for iNdEx := len(m.Cids) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Cids[iNdEx])
+			copy(dAtA[i:], m.Cids[iNdEx])
+			i = encodeVarintIndex(dAtA, i, uint64(len(m.Cids[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DeletionReport) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DeletionReport) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DeletionReport) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Timestamp != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Timestamp))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.BytesFreed != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.BytesFreed))
+		i--
+		dAtA[i] = 0x28
 	}
 	if m.CidCount != 0 {
-		n += 1 + sovIndex(uint64(m.CidCount))
+		i = encodeVarintIndex(dAtA, i, uint64(m.CidCount))
+		i--
+		dAtA[i] = 0x20
 	}
-	if m.Limit != 0 {
-		n += 1 + sovIndex(uint64(m.Limit))
+	if m.FileCount != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.FileCount))
+		i--
+		dAtA[i] = 0x18
 	}
-	return n
+	if len(m.GroupId) > 0 {
+		i -= len(m.GroupId)
+		copy(dAtA[i:], m.GroupId)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.GroupId)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.SpaceId) > 0 {
+		i -= len(m.SpaceId)
+		copy(dAtA[i:], m.SpaceId)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.SpaceId)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *FileEntry) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *SignedDeletionReport) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *SignedDeletionReport) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SignedDeletionReport) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.Cids) > 0 {
-		for _, s := range m.Cids {
-			l = len(s)
-			n += 1 + l + sovIndex(uint64(l))
-		}
+	if len(m.Identity) > 0 {
+		i -= len(m.Identity)
+		copy(dAtA[i:], m.Identity)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.Identity)))
+		i--
+		dAtA[i] = 0x1a
 	}
-	if m.Size_ != 0 {
-		n += 1 + sovIndex(uint64(m.Size_))
+	if len(m.Signature) > 0 {
+		i -= len(m.Signature)
+		copy(dAtA[i:], m.Signature)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.Signature)))
+		i--
+		dAtA[i] = 0x12
 	}
-	if m.CreateTime != 0 {
-		n += 1 + sovIndex(uint64(m.CreateTime))
+	if len(m.Report) > 0 {
+		i -= len(m.Report)
+		copy(dAtA[i:], m.Report)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.Report)))
+		i--
+		dAtA[i] = 0xa
 	}
-	if m.UpdateTime != 0 {
-		n += 1 + sovIndex(uint64(m.UpdateTime))
+	return len(dAtA) - i, nil
+}
+
+func (m *UsageSnapshot) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *UsageSnapshot) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *UsageSnapshot) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.FileCount != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.FileCount))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.CidCount != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.CidCount))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Size_ != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Size_))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *AuditEntry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *AuditEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *AuditEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.SourceSpaceId) > 0 {
+		i -= len(m.SourceSpaceId)
+		copy(dAtA[i:], m.SourceSpaceId)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.SourceSpaceId)))
+		i--
+		dAtA[i] = 0x62
+	}
+	if m.Status != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Status))
+		i--
+		dAtA[i] = 0x58
+	}
+	if len(m.StreamId) > 0 {
+		i -= len(m.StreamId)
+		copy(dAtA[i:], m.StreamId)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.StreamId)))
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.Timestamp != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Timestamp))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.Limit != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.Limit))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.GroupSizeDelta != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.GroupSizeDelta))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.SpaceSizeDelta != 0 {
+		i = encodeVarintIndex(dAtA, i, uint64(m.SpaceSizeDelta))
+		i--
+		dAtA[i] = 0x30
+	}
+	if len(m.FileIds) > 0 {
+		for iNdEx := len(m.FileIds) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.FileIds[iNdEx])
+			copy(dAtA[i:], m.FileIds[iNdEx])
+			i = encodeVarintIndex(dAtA, i, uint64(len(m.FileIds[iNdEx])))
+			i--
+			dAtA[i] = 0x2a
+		}
+	}
+	if len(m.SpaceId) > 0 {
+		i -= len(m.SpaceId)
+		copy(dAtA[i:], m.SpaceId)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.SpaceId)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if len(m.GroupId) > 0 {
+		i -= len(m.GroupId)
+		copy(dAtA[i:], m.GroupId)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.GroupId)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Identity) > 0 {
+		i -= len(m.Identity)
+		copy(dAtA[i:], m.Identity)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.Identity)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Op) > 0 {
+		i -= len(m.Op)
+		copy(dAtA[i:], m.Op)
+		i = encodeVarintIndex(dAtA, i, uint64(len(m.Op)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *AuditSegment) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *AuditSegment) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *AuditSegment) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Entries) > 0 {
+		for iNdEx := len(m.Entries) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Entries[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintIndex(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintIndex(dAtA []byte, offset int, v uint64) int {
+	offset -= sovIndex(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *CidEntry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Size_ != 0 {
+		n += 1 + sovIndex(uint64(m.Size_))
+	}
+	if m.CreateTime != 0 {
+		n += 1 + sovIndex(uint64(m.CreateTime))
+	}
+	if m.UpdateTime != 0 {
+		n += 1 + sovIndex(uint64(m.UpdateTime))
+	}
+	if m.Refs != 0 {
+		n += 1 + sovIndex(uint64(m.Refs))
+	}
+	if m.Version != 0 {
+		n += 1 + sovIndex(uint64(m.Version))
+	}
+	return n
+}
+
+func (m *CidList) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Cids) > 0 {
+		for _, b := range m.Cids {
+			l = len(b)
+			n += 1 + l + sovIndex(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *GroupEntry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.GroupId)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	if m.CreateTime != 0 {
+		n += 1 + sovIndex(uint64(m.CreateTime))
+	}
+	if m.UpdateTime != 0 {
+		n += 1 + sovIndex(uint64(m.UpdateTime))
+	}
+	if m.Size_ != 0 {
+		n += 1 + sovIndex(uint64(m.Size_))
+	}
+	if m.CidCount != 0 {
+		n += 1 + sovIndex(uint64(m.CidCount))
+	}
+	if len(m.SpaceIds) > 0 {
+		for _, s := range m.SpaceIds {
+			l = len(s)
+			n += 1 + l + sovIndex(uint64(l))
+		}
+	}
+	if m.Limit != 0 {
+		n += 1 + sovIndex(uint64(m.Limit))
+	}
+	if m.AccountLimit != 0 {
+		n += 1 + sovIndex(uint64(m.AccountLimit))
+	}
+	if len(m.DeletedSpaceIds) > 0 {
+		for _, s := range m.DeletedSpaceIds {
+			l = len(s)
+			n += 1 + l + sovIndex(uint64(l))
+		}
+	}
+	if m.QuotaWarnLevel != 0 {
+		n += 1 + sovIndex(uint64(m.QuotaWarnLevel))
+	}
+	return n
+}
+
+func (m *SpaceEntry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.GroupId)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	if m.CreateTime != 0 {
+		n += 1 + sovIndex(uint64(m.CreateTime))
+	}
+	if m.UpdateTime != 0 {
+		n += 1 + sovIndex(uint64(m.UpdateTime))
+	}
+	if m.Size_ != 0 {
+		n += 1 + sovIndex(uint64(m.Size_))
+	}
+	if m.FileCount != 0 {
+		n += 1 + sovIndex(uint64(m.FileCount))
+	}
+	if m.CidCount != 0 {
+		n += 1 + sovIndex(uint64(m.CidCount))
+	}
+	if m.Limit != 0 {
+		n += 1 + sovIndex(uint64(m.Limit))
+	}
+	if m.Status != 0 {
+		n += 1 + sovIndex(uint64(m.Status))
+	}
+	if m.DeleteTime != 0 {
+		n += 1 + sovIndex(uint64(m.DeleteTime))
+	}
+	return n
+}
+
+func (m *FileEntry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Cids) > 0 {
+		for _, s := range m.Cids {
+			l = len(s)
+			n += 1 + l + sovIndex(uint64(l))
+		}
+	}
+	if m.Size_ != 0 {
+		n += 1 + sovIndex(uint64(m.Size_))
+	}
+	if m.CreateTime != 0 {
+		n += 1 + sovIndex(uint64(m.CreateTime))
+	}
+	if m.UpdateTime != 0 {
+		n += 1 + sovIndex(uint64(m.UpdateTime))
+	}
+	return n
+}
+
+func (m *DeletionReport) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.SpaceId)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	l = len(m.GroupId)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	if m.FileCount != 0 {
+		n += 1 + sovIndex(uint64(m.FileCount))
+	}
+	if m.CidCount != 0 {
+		n += 1 + sovIndex(uint64(m.CidCount))
+	}
+	if m.BytesFreed != 0 {
+		n += 1 + sovIndex(uint64(m.BytesFreed))
+	}
+	if m.Timestamp != 0 {
+		n += 1 + sovIndex(uint64(m.Timestamp))
+	}
+	return n
+}
+
+func (m *SignedDeletionReport) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Report)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	l = len(m.Signature)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	l = len(m.Identity)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	return n
+}
+
+func (m *UsageSnapshot) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Size_ != 0 {
+		n += 1 + sovIndex(uint64(m.Size_))
+	}
+	if m.CidCount != 0 {
+		n += 1 + sovIndex(uint64(m.CidCount))
+	}
+	if m.FileCount != 0 {
+		n += 1 + sovIndex(uint64(m.FileCount))
+	}
+	return n
+}
+
+func (m *AuditEntry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Op)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	l = len(m.Identity)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	l = len(m.GroupId)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	l = len(m.SpaceId)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	if len(m.FileIds) > 0 {
+		for _, s := range m.FileIds {
+			l = len(s)
+			n += 1 + l + sovIndex(uint64(l))
+		}
+	}
+	if m.SpaceSizeDelta != 0 {
+		n += 1 + sovIndex(uint64(m.SpaceSizeDelta))
+	}
+	if m.GroupSizeDelta != 0 {
+		n += 1 + sovIndex(uint64(m.GroupSizeDelta))
+	}
+	if m.Limit != 0 {
+		n += 1 + sovIndex(uint64(m.Limit))
+	}
+	if m.Timestamp != 0 {
+		n += 1 + sovIndex(uint64(m.Timestamp))
+	}
+	l = len(m.StreamId)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	if m.Status != 0 {
+		n += 1 + sovIndex(uint64(m.Status))
+	}
+	l = len(m.SourceSpaceId)
+	if l > 0 {
+		n += 1 + l + sovIndex(uint64(l))
+	}
+	return n
+}
+
+func (m *AuditSegment) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Entries) > 0 {
+		for _, e := range m.Entries {
+			l = e.Size()
+			n += 1 + l + sovIndex(uint64(l))
+		}
+	}
+	return n
+}
+
+func sovIndex(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozIndex(x uint64) (n int) {
+	return sovIndex(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *CidEntry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIndex
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: CidEntry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: CidEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+			}
+			m.Size_ = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size_ |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreateTime", wireType)
+			}
+			m.CreateTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CreateTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdateTime", wireType)
+			}
+			m.UpdateTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.UpdateTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Refs", wireType)
+			}
+			m.Refs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Refs |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			m.Version = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Version |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIndex(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *CidList) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIndex
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: CidList: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: CidList: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cids", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Cids = append(m.Cids, make([]byte, postIndex-iNdEx))
+			copy(m.Cids[len(m.Cids)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIndex(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *GroupEntry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIndex
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GroupEntry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GroupEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.GroupId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreateTime", wireType)
+			}
+			m.CreateTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CreateTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdateTime", wireType)
+			}
+			m.UpdateTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.UpdateTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+			}
+			m.Size_ = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size_ |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CidCount", wireType)
+			}
+			m.CidCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CidCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SpaceIds", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SpaceIds = append(m.SpaceIds, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AccountLimit", wireType)
+			}
+			m.AccountLimit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.AccountLimit |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DeletedSpaceIds", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DeletedSpaceIds = append(m.DeletedSpaceIds, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QuotaWarnLevel", wireType)
+			}
+			m.QuotaWarnLevel = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.QuotaWarnLevel |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIndex(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIndex
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SpaceEntry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SpaceEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.GroupId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreateTime", wireType)
+			}
+			m.CreateTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CreateTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdateTime", wireType)
+			}
+			m.UpdateTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.UpdateTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+			}
+			m.Size_ = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size_ |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FileCount", wireType)
+			}
+			m.FileCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FileCount |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CidCount", wireType)
+			}
+			m.CidCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CidCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			m.Status = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Status |= SpaceStatus(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DeleteTime", wireType)
+			}
+			m.DeleteTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DeleteTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIndex(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FileEntry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIndex
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FileEntry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FileEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cids", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Cids = append(m.Cids, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+			}
+			m.Size_ = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size_ |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreateTime", wireType)
+			}
+			m.CreateTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CreateTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdateTime", wireType)
+			}
+			m.UpdateTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.UpdateTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIndex(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
 	}
-	return n
-}
 
-func sovIndex(x uint64) (n int) {
-	return (math_bits.Len64(x|1) + 6) / 7
-}
-func sozIndex(x uint64) (n int) {
-	return sovIndex(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
 }
-func (m *CidEntry) Unmarshal(dAtA []byte) error {
+func (m *DeletionReport) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -869,17 +2682,17 @@ func (m *CidEntry) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: CidEntry: wiretype end group for non-group")
+			return fmt.Errorf("proto: DeletionReport: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: CidEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: DeletionReport: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SpaceId", wireType)
 			}
-			m.Size_ = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -889,16 +2702,29 @@ func (m *CidEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Size_ |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SpaceId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CreateTime", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
 			}
-			m.CreateTime = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -908,16 +2734,29 @@ func (m *CidEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CreateTime |= int64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.GroupId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdateTime", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field FileCount", wireType)
 			}
-			m.UpdateTime = 0
+			m.FileCount = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -927,16 +2766,16 @@ func (m *CidEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.UpdateTime |= int64(b&0x7F) << shift
+				m.FileCount |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Refs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CidCount", wireType)
 			}
-			m.Refs = 0
+			m.CidCount = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -946,16 +2785,16 @@ func (m *CidEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Refs |= int32(b&0x7F) << shift
+				m.CidCount |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field BytesFreed", wireType)
 			}
-			m.Version = 0
+			m.BytesFreed = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -965,7 +2804,26 @@ func (m *CidEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Version |= uint32(b&0x7F) << shift
+				m.BytesFreed |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			m.Timestamp = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Timestamp |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -991,7 +2849,7 @@ func (m *CidEntry) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *CidList) Unmarshal(dAtA []byte) error {
+func (m *SignedDeletionReport) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1014,15 +2872,15 @@ func (m *CidList) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: CidList: wiretype end group for non-group")
+			return fmt.Errorf("proto: SignedDeletionReport: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: CidList: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: SignedDeletionReport: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Cids", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Report", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -1049,8 +2907,76 @@ func (m *CidList) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Cids = append(m.Cids, make([]byte, postIndex-iNdEx))
-			copy(m.Cids[len(m.Cids)-1], dAtA[iNdEx:postIndex])
+			m.Report = append(m.Report[:0], dAtA[iNdEx:postIndex]...)
+			if m.Report == nil {
+				m.Report = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Signature", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Signature = append(m.Signature[:0], dAtA[iNdEx:postIndex]...)
+			if m.Signature == nil {
+				m.Signature = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Identity", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Identity = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -1073,7 +2999,7 @@ func (m *CidList) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *GroupEntry) Unmarshal(dAtA []byte) error {
+func (m *UsageSnapshot) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1096,17 +3022,17 @@ func (m *GroupEntry) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: GroupEntry: wiretype end group for non-group")
+			return fmt.Errorf("proto: UsageSnapshot: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: GroupEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: UsageSnapshot: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
 			}
-			var stringLen uint64
+			m.Size_ = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1116,29 +3042,16 @@ func (m *GroupEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.Size_ |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthIndex
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthIndex
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.GroupId = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CreateTime", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CidCount", wireType)
 			}
-			m.CreateTime = 0
+			m.CidCount = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1148,16 +3061,16 @@ func (m *GroupEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CreateTime |= int64(b&0x7F) << shift
+				m.CidCount |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdateTime", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field FileCount", wireType)
 			}
-			m.UpdateTime = 0
+			m.FileCount = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1167,35 +3080,66 @@ func (m *GroupEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.UpdateTime |= int64(b&0x7F) << shift
+				m.FileCount |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipIndex(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			m.Size_ = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowIndex
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Size_ |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthIndex
 			}
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CidCount", wireType)
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AuditEntry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowIndex
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AuditEntry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AuditEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
 			}
-			m.CidCount = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1205,14 +3149,27 @@ func (m *GroupEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CidCount |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 6:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Op = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SpaceIds", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Identity", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -1240,13 +3197,13 @@ func (m *GroupEntry) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.SpaceIds = append(m.SpaceIds, string(dAtA[iNdEx:postIndex]))
+			m.Identity = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
 			}
-			m.Limit = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1256,16 +3213,29 @@ func (m *GroupEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Limit |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 8:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AccountLimit", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
 			}
-			m.AccountLimit = 0
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.GroupId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SpaceId", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1275,64 +3245,27 @@ func (m *GroupEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.AccountLimit |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipIndex(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if (skippy < 0) || (iNdEx+skippy) < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthIndex
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowIndex
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
 			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: SpaceEntry: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SpaceEntry: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.SpaceId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field FileIds", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -1360,13 +3293,13 @@ func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.GroupId = string(dAtA[iNdEx:postIndex])
+			m.FileIds = append(m.FileIds, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 2:
+		case 6:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CreateTime", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field SpaceSizeDelta", wireType)
 			}
-			m.CreateTime = 0
+			m.SpaceSizeDelta = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1376,16 +3309,16 @@ func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CreateTime |= int64(b&0x7F) << shift
+				m.SpaceSizeDelta |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+		case 7:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdateTime", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupSizeDelta", wireType)
 			}
-			m.UpdateTime = 0
+			m.GroupSizeDelta = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1395,16 +3328,16 @@ func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.UpdateTime |= int64(b&0x7F) << shift
+				m.GroupSizeDelta |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
+		case 8:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
 			}
-			m.Size_ = 0
+			m.Limit = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1414,16 +3347,16 @@ func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Size_ |= uint64(b&0x7F) << shift
+				m.Limit |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 5:
+		case 9:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field FileCount", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
 			}
-			m.FileCount = 0
+			m.Timestamp = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1433,16 +3366,16 @@ func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.FileCount |= uint32(b&0x7F) << shift
+				m.Timestamp |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CidCount", wireType)
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StreamId", wireType)
 			}
-			m.CidCount = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1452,16 +3385,29 @@ func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CidCount |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 7:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.StreamId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 11:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
 			}
-			m.Limit = 0
+			m.Status = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1471,11 +3417,43 @@ func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Limit |= uint64(b&0x7F) << shift
+				m.Status |= SpaceStatus(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SourceSpaceId", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIndex
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthIndex
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthIndex
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SourceSpaceId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipIndex(dAtA[iNdEx:])
@@ -1497,7 +3475,7 @@ func (m *SpaceEntry) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *FileEntry) Unmarshal(dAtA []byte) error {
+func (m *AuditSegment) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1520,17 +3498,17 @@ func (m *FileEntry) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: FileEntry: wiretype end group for non-group")
+			return fmt.Errorf("proto: AuditSegment: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: FileEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: AuditSegment: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Cids", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Entries", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowIndex
@@ -1540,81 +3518,26 @@ func (m *FileEntry) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthIndex
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthIndex
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Cids = append(m.Cids, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
-			}
-			m.Size_ = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowIndex
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Size_ |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CreateTime", wireType)
-			}
-			m.CreateTime = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowIndex
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.CreateTime |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdateTime", wireType)
-			}
-			m.UpdateTime = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowIndex
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.UpdateTime |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			m.Entries = append(m.Entries, &AuditEntry{})
+			if err := m.Entries[len(m.Entries)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipIndex(dAtA[iNdEx:])
@@ -0,0 +1,160 @@
+package index
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/ipfs/go-cid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultJournalStale       = time.Hour
+	defaultJournalSweepPeriod = 300
+	journalSweepBatch         = 100
+)
+
+/*
+	Upload journal keys, they are not persisted:
+		uploadJournal: sorted set of journal ids, score is a creation time in milliseconds
+		uploadJournal:{id}: set of cids written to the store by the upload
+*/
+
+const uploadJournalKey = "uploadJournal"
+
+func uploadJournalEntryKey(id string) string {
+	return uploadJournalKey + ":" + id
+}
+
+// UploadJournalAdd records cids that are going to be written to the store by the upload.
+// The entry must be cleared after the successful bind, otherwise the sweeper removes blocks that have no references
+func (ri *redisIndex) UploadJournalAdd(ctx context.Context, cids []cid.Cid) (journalId string, err error) {
+	if len(cids) == 0 {
+		return
+	}
+	journalId = strconv.FormatUint(rand.Uint64(), 36)
+	members := make([]any, len(cids))
+	for i, c := range cids {
+		members[i] = c.String()
+	}
+	_, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.SAdd(ctx, uploadJournalEntryKey(journalId), members...)
+		tx.ZAdd(ctx, uploadJournalKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: journalId})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return
+}
+
+// UploadJournalClear removes the journal entry of the finished upload
+func (ri *redisIndex) UploadJournalClear(ctx context.Context, journalId string) (err error) {
+	if journalId == "" {
+		return
+	}
+	_, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.Del(ctx, uploadJournalEntryKey(journalId))
+		tx.ZRem(ctx, uploadJournalKey, journalId)
+		return nil
+	})
+	return
+}
+
+// SweepUploadJournal removes blocks of stale journal entries that never got referenced
+func (ri *redisIndex) SweepUploadJournal(ctx context.Context) (err error) {
+	mu := ri.redsync.NewMutex("_lock:"+uploadJournalKey, redsync.WithExpiry(time.Minute*10))
+	if err = mu.LockContext(ctx); err != nil {
+		return
+	}
+	defer func() {
+		_, _ = mu.Unlock()
+	}()
+
+	border := time.Now().Add(-time.Duration(ri.journalStale.Load())).UnixMilli()
+	var entries, swept int
+	for {
+		ids, zErr := ri.cl.ZRangeByScore(ctx, uploadJournalKey, &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(border, 10),
+			Count: journalSweepBatch,
+		}).Result()
+		if zErr != nil {
+			return zErr
+		}
+		if len(ids) == 0 {
+			break
+		}
+		for _, id := range ids {
+			var n int
+			if n, err = ri.sweepJournalEntry(ctx, id); err != nil {
+				return
+			}
+			entries++
+			swept += n
+		}
+		if _, err = mu.ExtendContext(ctx); err != nil {
+			return
+		}
+	}
+	if entries != 0 {
+		log.InfoCtx(ctx, "upload journal swept", zap.Int("entries", entries), zap.Int("blocks", swept))
+	}
+	return
+}
+
+func (ri *redisIndex) sweepJournalEntry(ctx context.Context, journalId string) (swept int, err error) {
+	cidStrings, err := ri.cl.SMembers(ctx, uploadJournalEntryKey(journalId)).Result()
+	if err != nil {
+		return
+	}
+	for _, cs := range cidStrings {
+		c, dErr := cid.Decode(cs)
+		if dErr != nil {
+			log.WarnCtx(ctx, "invalid cid in the upload journal", zap.String("journalId", journalId), zap.String("cid", cs))
+			continue
+		}
+		ok, sErr := ri.sweepCid(ctx, c)
+		if sErr != nil {
+			return swept, sErr
+		}
+		if ok {
+			swept++
+		}
+	}
+	ri.metrics.journalSwept.Add(float64(swept))
+	return swept, ri.UploadJournalClear(ctx, journalId)
+}
+
+// sweepCid removes the block when it's not referenced by any file
+func (ri *redisIndex) sweepCid(ctx context.Context, c cid.Cid) (swept bool, err error) {
+	// take the same lock as the upload of the block, so the block can't be uploaded and bound meanwhile
+	l := ri.redsync.NewMutex("_lock:b:"+c.String(), redsync.WithExpiry(time.Minute))
+	if err = l.LockContext(ctx); err != nil {
+		return
+	}
+	defer func() {
+		_, _ = l.Unlock()
+	}()
+	exists, release, err := ri.AcquireKey(ctx, cidKey(c))
+	if err != nil {
+		return
+	}
+	defer release()
+	if !exists {
+		// the block was written, but the cid entry wasn't created
+		return true, ri.persistStore.DeleteMany(ctx, []cid.Cid{c})
+	}
+	entry, err := ri.getCidEntry(ctx, c)
+	if err != nil {
+		return
+	}
+	if entry.Refs > 0 {
+		return
+	}
+	return true, ri.purgeCids(ctx, []*cidEntry{entry}, &purgeStat{})
+}
@@ -0,0 +1,53 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+func TestRedisIndex_SweepUploadJournal(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+	key := newRandKey()
+
+	// the first block is bound, the second one has the entry without refs, the third one has no entry
+	bs := testutil.NewRandBlocks(3)
+	require.NoError(t, fx.BlocksAdd(ctx, bs[:2]))
+	cids, err := fx.CidEntriesByBlocks(ctx, bs[:1])
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(ctx, key, testutil.NewRandCid().String(), cids))
+	cids.Release()
+
+	journalId, err := fx.UploadJournalAdd(ctx, []cid.Cid{bs[0].Cid(), bs[1].Cid(), bs[2].Cid()})
+	require.NoError(t, err)
+	clearedId, err := fx.UploadJournalAdd(ctx, []cid.Cid{testutil.NewRandCid()})
+	require.NoError(t, err)
+	require.NoError(t, fx.UploadJournalClear(ctx, clearedId))
+
+	// entries are not stale yet
+	require.NoError(t, fx.SweepUploadJournal(ctx))
+	ex, err := fx.cl.Exists(ctx, uploadJournalEntryKey(journalId)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), ex)
+
+	fx.journalStale.Store(0)
+	fx.persistStore.EXPECT().DeleteMany(ctx, []cid.Cid{bs[1].Cid()})
+	fx.persistStore.EXPECT().DeleteMany(ctx, []cid.Cid{bs[2].Cid()})
+	require.NoError(t, fx.SweepUploadJournal(ctx))
+
+	exists, err := fx.CidExists(ctx, bs[0].Cid())
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = fx.CidExists(ctx, bs[1].Cid())
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	count, err := fx.cl.ZCard(ctx, uploadJournalKey).Result()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
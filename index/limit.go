@@ -10,6 +10,9 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/events"
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
 )
 
 var ErrLimitExceed = errors.New("limit exceed")
@@ -20,6 +23,10 @@ func (ri *redisIndex) CheckLimits(ctx context.Context, key Key) (err error) {
 		return
 	}
 	defer release()
+	// writes to spaces waiting for deletion are not allowed
+	if entry.space.Status != indexproto.SpaceStatus_SpaceStatusOk {
+		return ErrSpaceReadOnly
+	}
         // INFO: I have remove this check, it basically do check if size of the new file is greater than the overall limit
 	// isolated space
 	if entry.space.Limit != 0 {
@@ -38,17 +45,41 @@ func (ri *redisIndex) CheckLimits(ctx context.Context, key Key) (err error) {
 
 // INFO: SetGroupLimit and set SsetSpaceLimit I believe this are the functions called in the filenode, not sure
 func (ri *redisIndex) SetGroupLimit(ctx context.Context, groupId string, limit uint64) (err error) {
+	outbox, err := ri.newEvents(ctx, groupId, events.Event{Type: events.TypeLimitChanged, Limit: limit})
+	if err != nil {
+		return
+	}
 	op := &spaceLimitOp{
 		redisIndex: ri,
+		outbox:     outbox,
 	}
-	return op.SetGroupLimit(ctx, groupId, limit)
+	if err = op.SetGroupLimit(ctx, groupId, limit); err != nil {
+		return
+	}
+	if err = op.finishEvents(ctx); err != nil {
+		return
+	}
+	ri.audit(ctx, &indexproto.AuditEntry{Op: AuditOpSetGroupLimit, GroupId: groupId, Limit: limit})
+	return
 }
 
 func (ri *redisIndex) SetSpaceLimit(ctx context.Context, key Key, limit uint64) (err error) {
+	outbox, err := ri.newEvents(ctx, key.GroupId, events.Event{Type: events.TypeLimitChanged, SpaceId: key.SpaceId, Limit: limit})
+	if err != nil {
+		return
+	}
 	op := &spaceLimitOp{
 		redisIndex: ri,
+		outbox:     outbox,
+	}
+	if err = op.SetSpaceLimit(ctx, key, limit); err != nil {
+		return
 	}
-	return op.SetSpaceLimit(ctx, key, limit)
+	if err = op.finishEvents(ctx); err != nil {
+		return
+	}
+	ri.audit(ctx, &indexproto.AuditEntry{Op: AuditOpSetSpaceLimit, GroupId: key.GroupId, SpaceId: key.SpaceId, Limit: limit})
+	return
 }
 
 type spaceLimitOp struct {
@@ -56,6 +87,23 @@ type spaceLimitOp struct {
 	groupEntry   *groupEntry
 	spaceEntries []*spaceEntry
 	release      []func()
+	// outbox is written in the transaction of saveAll
+	outbox        *eventOutbox
+	outboxWritten bool
+}
+
+// finishEvents publishes the events of the op, they are written to the outbox on their own when nothing was saved
+func (op *spaceLimitOp) finishEvents(ctx context.Context) (err error) {
+	if !op.outboxWritten {
+		if _, err = op.cl.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			op.outbox.write(ctx, pipe)
+			return nil
+		}); err != nil {
+			return
+		}
+	}
+	op.publishEvents(ctx, op.outbox)
+	return
 }
 
 func (op *spaceLimitOp) SetGroupLimit(ctx context.Context, groupId string, limit uint64) (err error) {
@@ -186,30 +234,13 @@ func (op *spaceLimitOp) SetSpaceLimit(ctx context.Context, key Key, limit uint64
 
 func (op *spaceLimitOp) isolateSpace(ctx context.Context, entry groupSpaceEntry) (err error) {
 	key := Key{GroupId: entry.group.GroupId, SpaceId: entry.space.Id}
-	sk := spaceKey(key)
 	gk := groupKey(key)
 
-	keys, err := op.cl.HGetAll(ctx, sk).Result()
+	cids, cidRefs, err := op.spaceCidRefs(ctx, key)
 	if err != nil {
 		return
 	}
 
-	var cids []cid.Cid
-	var cidRefs []int64
-	// collect all cids
-	for k, val := range keys {
-		if strings.HasPrefix(k, "c:") {
-			c, cErr := cid.Decode(k[2:])
-			if cErr != nil {
-				log.WarnCtx(ctx, "can't decode cid", zap.String("cid", k[2:]), zap.Error(cErr))
-			} else {
-				ref, _ := strconv.ParseInt(val, 10, 64)
-				cids = append(cids, c)
-				cidRefs = append(cidRefs, ref)
-			}
-		}
-	}
-
 	// fetch cid entries
 	// TODO: we don't need to take a lock here, but for now it easiest way
 	cidEntries, err := op.CidEntries(ctx, cids)
@@ -255,27 +286,13 @@ func (op *spaceLimitOp) isolateSpace(ctx context.Context, entry groupSpaceEntry)
 
 func (op *spaceLimitOp) uniteSpace(ctx context.Context, entry groupSpaceEntry) (err error) {
 	key := Key{GroupId: entry.group.GroupId, SpaceId: entry.space.Id}
-	sk := spaceKey(key)
 	gk := groupKey(key)
 
-	keys, err := op.cl.HKeys(ctx, sk).Result()
+	cids, cidRefs, err := op.spaceCidRefs(ctx, key)
 	if err != nil {
 		return
 	}
 
-	var cids []cid.Cid
-	// collect all cids
-	for _, k := range keys {
-		if strings.HasPrefix(k, "c:") {
-			c, cErr := cid.Decode(k[2:])
-			if cErr != nil {
-				log.WarnCtx(ctx, "can't decode cid", zap.String("cid", k[2:]), zap.Error(cErr))
-			} else {
-				cids = append(cids, c)
-			}
-		}
-	}
-
 	// fetch cid entries
 	// TODO: we don't need to take a lock here, but for now it easiest way
 	cidEntries, err := op.CidEntries(ctx, cids)
@@ -284,20 +301,20 @@ func (op *spaceLimitOp) uniteSpace(ctx context.Context, entry groupSpaceEntry) (
 	}
 	defer cidEntries.Release()
 
-	// increment refs in the group
-	var groupDecrResults = make([]*redis.IntCmd, len(cids))
+	// increment refs in the group, the group counts a ref for every file of the space as the space does
+	var groupIncrResults = make([]*redis.IntCmd, len(cids))
 	if _, err = op.cl.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
 		for i, c := range cids {
-			groupDecrResults[i] = pipe.HIncrBy(ctx, gk, cidKey(c), 1)
+			groupIncrResults[i] = pipe.HIncrBy(ctx, gk, cidKey(c), cidRefs[i])
 		}
 		return nil
 	}); err != nil {
 		return
 	}
 
-	// make a list of cids without refs and increase the size
-	for i, res := range groupDecrResults {
-		if res.Val() == 1 {
+	// increase the size by cids that are new for the group
+	for i, res := range groupIncrResults {
+		if res.Val() == cidRefs[i] {
 			op.groupEntry.Size_ += cidEntries.entries[i].Size_
 			op.groupEntry.CidCount++
 		}
@@ -305,6 +322,27 @@ func (op *spaceLimitOp) uniteSpace(ctx context.Context, entry groupSpaceEntry) (
 	return
 }
 
+// spaceCidRefs returns all cids of the space with their refs
+func (op *spaceLimitOp) spaceCidRefs(ctx context.Context, key Key) (cids []cid.Cid, cidRefs []int64, err error) {
+	keys, err := op.cl.HGetAll(ctx, spaceKey(key)).Result()
+	if err != nil {
+		return
+	}
+	for k, val := range keys {
+		if strings.HasPrefix(k, "c:") {
+			c, cErr := cid.Decode(k[2:])
+			if cErr != nil {
+				log.WarnCtx(ctx, "can't decode cid", zap.String("cid", k[2:]), zap.Error(cErr))
+			} else {
+				ref, _ := strconv.ParseInt(val, 10, 64)
+				cids = append(cids, c)
+				cidRefs = append(cidRefs, ref)
+			}
+		}
+	}
+	return
+}
+
 func (op *spaceLimitOp) releaseAll() {
 	for _, r := range op.release {
 		r()
@@ -312,12 +350,23 @@ func (op *spaceLimitOp) releaseAll() {
 }
 
 func (op *spaceLimitOp) saveAll(ctx context.Context) (err error) {
+	keys := []Key{{GroupId: op.groupEntry.GroupId}}
+	for _, sEntry := range op.spaceEntries {
+		keys = append(keys, Key{GroupId: sEntry.GroupId, SpaceId: sEntry.Id})
+	}
+	if err = op.markUsageChanged(ctx, keys...); err != nil {
+		return
+	}
 	_, err = op.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
 		for _, sEntry := range op.spaceEntries {
 			sEntry.Save(ctx, Key{GroupId: sEntry.GroupId, SpaceId: sEntry.Id}, tx)
 		}
 		op.groupEntry.Save(ctx, tx)
+		if op.outbox != nil {
+			op.outbox.write(ctx, tx)
+		}
 		return nil
 	})
+	op.outboxWritten = err == nil
 	return
 }
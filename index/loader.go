@@ -35,6 +35,9 @@ func init() {
 type persistentStore interface {
 	IndexGet(ctx context.Context, key string) (value []byte, err error)
 	IndexPut(ctx context.Context, key string, value []byte) (err error)
+	IndexDelete(ctx context.Context, key string) (err error)
+	IndexList(ctx context.Context, prefix string, f func(key string) error) (err error)
+	DeleteMany(ctx context.Context, toDelete []cid.Cid) error
 
 	Get(ctx context.Context, k cid.Cid) (blocks.Block, error)
 }
@@ -115,8 +118,12 @@ func (ri *redisIndex) AcquireSpace(ctx context.Context, key Key) (entry groupSpa
 }
 
 func (ri *redisIndex) acquireKey(ctx context.Context, key string) (exists bool, release func(), err error) {
+	st := time.Now()
 	mu := ri.redsync.NewMutex("_lock:"+key, redsync.WithExpiry(time.Minute*20))
-	if err = mu.LockContext(ctx); err != nil {
+	err = mu.LockContext(ctx)
+	// failed locks are observed too, long waits ending with a timeout are the most interesting ones
+	ri.metrics.lockWait.Observe(time.Since(st).Seconds())
+	if err != nil {
 		return
 	}
 	release = func() {
@@ -156,10 +163,15 @@ func (ri *redisIndex) acquireKey(ctx context.Context, key string) (exists bool,
 	if val == nil {
 		return false, release, nil
 	}
+	if val, err = decodeDump(val); err != nil {
+		release()
+		return false, nil, err
+	}
 	if err = ri.cl.Restore(ctx, key, 0, string(val)).Err(); err != nil {
 		release()
 		return false, nil, err
 	}
+	ri.metrics.bloomRestored.Inc()
 	return true, release, nil
 }
 func (ri *redisIndex) updateKeyUsage(ctx context.Context, key string) (err error) {
@@ -192,6 +204,11 @@ func (ri *redisIndex) PersistKeys(ctx context.Context) {
 		}(part)
 	}
 	wg.Wait()
+	ri.lastPersist.Store(time.Now().Unix())
+	ri.metrics.persistMoved.Add(float64(stat.moved.Load()))
+	ri.metrics.persistMissed.Add(float64(stat.missed.Load()))
+	ri.metrics.persistDeleted.Add(float64(stat.deleted.Load()))
+	ri.metrics.persistErrors.Add(float64(stat.errors.Load()))
 	log.Info("persist",
 		zap.Duration("dur", time.Since(st)),
 		zap.Int32("handled", stat.handled.Load()),
@@ -200,11 +217,12 @@ func (ri *redisIndex) PersistKeys(ctx context.Context) {
 		zap.Int32("errors", stat.errors.Load()),
 		zap.Int32("moved", stat.moved.Load()),
 		zap.Int32("moved kbs", stat.movedBytes.Load()/1024),
+		zap.Int32("stored kbs", stat.storedBytes.Load()/1024),
 	)
 }
 
 func (ri *redisIndex) persistKeys(ctx context.Context, part int, stat *persistStat) (err error) {
-	deadline := time.Now().Add(-ri.persistTtl).Unix()
+	deadline := time.Now().Add(-time.Duration(ri.persistTtl.Load())).Unix()
 	sk := "store:{" + strconv.FormatInt(int64(part), 10) + "}"
 	keys, err := ri.cl.ZRangeByScore(ctx, sk, &redis.ZRangeBy{
 		Min: "0",
@@ -255,7 +273,8 @@ func (ri *redisIndex) persistKey(ctx context.Context, storeKey, key string, dead
 	}
 
 	// persist the dump
-	if err = ri.persistStore.IndexPut(ctx, key, []byte(dump)); err != nil {
+	data := encodeDump(ri.persistCodec, []byte(dump))
+	if err = ri.persistStore.IndexPut(ctx, key, data); err != nil {
 		return
 	}
 	// remove from queue and add to bloom filter
@@ -267,16 +286,18 @@ func (ri *redisIndex) persistKey(ctx context.Context, storeKey, key string, dead
 
 	stat.moved.Add(1)
 	stat.movedBytes.Add(int32(len(dump)))
+	stat.storedBytes.Add(int32(len(data)))
 
 	// remove key
 	return ri.cl.Del(ctx, key).Err()
 }
 
 type persistStat struct {
-	handled    atomic.Int32
-	moved      atomic.Int32
-	movedBytes atomic.Int32
-	missed     atomic.Int32
-	deleted    atomic.Int32
-	errors     atomic.Int32
+	handled     atomic.Int32
+	moved       atomic.Int32
+	movedBytes  atomic.Int32
+	storedBytes atomic.Int32
+	missed      atomic.Int32
+	deleted     atomic.Int32
+	errors      atomic.Int32
 }
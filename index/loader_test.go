@@ -45,7 +45,16 @@ func TestRedisIndex_PersistKeys(t *testing.T) {
 }
 
 func TestRedisIndex_AcquireKey(t *testing.T) {
-	fx := newFixtureConfig(t, &config.Config{PersistTtl: 1})
+	t.Run("raw", func(t *testing.T) {
+		testAcquireKey(t, &config.Config{PersistTtl: 1})
+	})
+	t.Run("compressed", func(t *testing.T) {
+		testAcquireKey(t, &config.Config{PersistTtl: 1, PersistCompression: compressionSnappy})
+	})
+}
+
+func testAcquireKey(t *testing.T, conf *config.Config) {
+	fx := newFixtureConfig(t, conf)
 	defer fx.Finish(t)
 
 	bs := testutil.NewRandBlocks(5)
@@ -0,0 +1,127 @@
+package index
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricNamespace = "filenode"
+	metricSubsystem = "index"
+)
+
+type indexMetrics struct {
+	persistMoved   prometheus.Counter
+	persistMissed  prometheus.Counter
+	persistDeleted prometheus.Counter
+	persistErrors  prometheus.Counter
+	lockWait       prometheus.Histogram
+	bloomRestored  prometheus.Counter
+	reservations   *prometheus.CounterVec
+	journalSwept   prometheus.Counter
+}
+
+func newIndexMetrics() *indexMetrics {
+	return &indexMetrics{
+		persistMoved: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "persist_moved_total",
+			Help:      "count of keys moved to the persistent store",
+		}),
+		persistMissed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "persist_missed_total",
+			Help:      "count of keys skipped by persist because of recent activity",
+		}),
+		persistDeleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "persist_deleted_total",
+			Help:      "count of removed keys dropped from the persist queue",
+		}),
+		persistErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "persist_errors_total",
+			Help:      "count of persist errors",
+		}),
+		lockWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "lock_wait_seconds",
+			Help:      "time spent waiting for a key lock",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}),
+		bloomRestored: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "bloom_restored_total",
+			Help:      "count of keys restored from the persistent store",
+		}),
+		reservations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "reservations_total",
+			Help:      "count of quota reservations by result",
+		}, []string{"result"}),
+		journalSwept: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "journal_swept_total",
+			Help:      "count of orphaned blocks removed by the upload journal sweeper",
+		}),
+	}
+}
+
+func (ri *redisIndex) registerMetrics(reg *prometheus.Registry) {
+	reg.MustRegister(
+		ri.metrics.persistMoved,
+		ri.metrics.persistMissed,
+		ri.metrics.persistDeleted,
+		ri.metrics.persistErrors,
+		ri.metrics.lockWait,
+		ri.metrics.bloomRestored,
+		ri.metrics.reservations,
+		ri.metrics.journalSwept,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "cid_count",
+			Help:      "total count of cids",
+		}, func() float64 {
+			return ri.systemCounter(cidCount)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "cid_bytes",
+			Help:      "total size of cids",
+		}, func() float64 {
+			return ri.systemCounter(cidSizeSumKey)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "cid_waiters",
+			Help:      "count of WaitCidExists subscribers",
+		}, func() float64 {
+			ri.cidSubscriptionsMu.Lock()
+			defer ri.cidSubscriptionsMu.Unlock()
+			return float64(ri.cidWaiters)
+		}),
+	)
+}
+
+func (ri *redisIndex) systemCounter(key string) float64 {
+	ctx, cancel := context.WithTimeout(ri.ctx, time.Second*5)
+	defer cancel()
+	res, err := ri.cl.Get(ctx, key).Float64()
+	if err != nil {
+		return 0
+	}
+	return res
+}
@@ -12,8 +12,10 @@ package mock_index
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	index "github.com/anyproto/any-sync-filenode/index"
+	indexproto "github.com/anyproto/any-sync-filenode/index/indexproto"
 	app "github.com/anyproto/any-sync/app"
 	blocks "github.com/ipfs/go-block-format"
 	cid "github.com/ipfs/go-cid"
@@ -43,6 +45,62 @@ func (m *MockIndex) EXPECT() *MockIndexMockRecorder {
 	return m.recorder
 }
 
+// AuditQuery mocks base method.
+func (m *MockIndex) AuditQuery(arg0 context.Context, arg1 index.AuditFilter) ([]index.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditQuery", arg0, arg1)
+	ret0, _ := ret[0].([]index.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuditQuery indicates an expected call of AuditQuery.
+func (mr *MockIndexMockRecorder) AuditQuery(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditQuery", reflect.TypeOf((*MockIndex)(nil).AuditQuery), arg0, arg1)
+}
+
+// BandwidthAdd mocks base method.
+func (m *MockIndex) BandwidthAdd(arg0 index.Key, arg1, arg2 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BandwidthAdd", arg0, arg1, arg2)
+}
+
+// BandwidthAdd indicates an expected call of BandwidthAdd.
+func (mr *MockIndexMockRecorder) BandwidthAdd(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BandwidthAdd", reflect.TypeOf((*MockIndex)(nil).BandwidthAdd), arg0, arg1, arg2)
+}
+
+// BandwidthExport mocks base method.
+func (m *MockIndex) BandwidthExport(arg0 context.Context, arg1 int, arg2 func(index.BandwidthBucket) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BandwidthExport", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BandwidthExport indicates an expected call of BandwidthExport.
+func (mr *MockIndexMockRecorder) BandwidthExport(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BandwidthExport", reflect.TypeOf((*MockIndex)(nil).BandwidthExport), arg0, arg1, arg2)
+}
+
+// BandwidthHistory mocks base method.
+func (m *MockIndex) BandwidthHistory(arg0 context.Context, arg1 index.Key, arg2 int) ([]index.BandwidthBucket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BandwidthHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]index.BandwidthBucket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BandwidthHistory indicates an expected call of BandwidthHistory.
+func (mr *MockIndexMockRecorder) BandwidthHistory(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BandwidthHistory", reflect.TypeOf((*MockIndex)(nil).BandwidthHistory), arg0, arg1, arg2)
+}
+
 // BlocksAdd mocks base method.
 func (m *MockIndex) BlocksAdd(arg0 context.Context, arg1 []blocks.Block) error {
 	m.ctrl.T.Helper()
@@ -101,6 +159,34 @@ func (mr *MockIndexMockRecorder) CheckLimits(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLimits", reflect.TypeOf((*MockIndex)(nil).CheckLimits), arg0, arg1)
 }
 
+// CheckSpace mocks base method.
+func (m *MockIndex) CheckSpace(arg0 context.Context, arg1 index.Key) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSpace", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSpace indicates an expected call of CheckSpace.
+func (mr *MockIndexMockRecorder) CheckSpace(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSpace", reflect.TypeOf((*MockIndex)(nil).CheckSpace), arg0, arg1)
+}
+
+// CheckSpaceGroup mocks base method.
+func (m *MockIndex) CheckSpaceGroup(arg0 context.Context, arg1 index.Key) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSpaceGroup", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSpaceGroup indicates an expected call of CheckSpaceGroup.
+func (mr *MockIndexMockRecorder) CheckSpaceGroup(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSpaceGroup", reflect.TypeOf((*MockIndex)(nil).CheckSpaceGroup), arg0, arg1)
+}
+
 // CidEntries mocks base method.
 func (m *MockIndex) CidEntries(arg0 context.Context, arg1 []cid.Cid) (*index.CidEntries, error) {
 	m.ctrl.T.Helper()
@@ -175,6 +261,21 @@ func (mr *MockIndexMockRecorder) Close(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockIndex)(nil).Close), arg0)
 }
 
+// DeletedSpaces mocks base method.
+func (m *MockIndex) DeletedSpaces(arg0 context.Context, arg1 time.Time) ([]index.Key, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletedSpaces", arg0, arg1)
+	ret0, _ := ret[0].([]index.Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeletedSpaces indicates an expected call of DeletedSpaces.
+func (mr *MockIndexMockRecorder) DeletedSpaces(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletedSpaces", reflect.TypeOf((*MockIndex)(nil).DeletedSpaces), arg0, arg1)
+}
+
 // FileBind mocks base method.
 func (m *MockIndex) FileBind(arg0 context.Context, arg1 index.Key, arg2 string, arg3 *index.CidEntries) error {
 	m.ctrl.T.Helper()
@@ -189,6 +290,35 @@ func (mr *MockIndexMockRecorder) FileBind(arg0, arg1, arg2, arg3 any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileBind", reflect.TypeOf((*MockIndex)(nil).FileBind), arg0, arg1, arg2, arg3)
 }
 
+// FileCidEntries mocks base method.
+func (m *MockIndex) FileCidEntries(arg0 context.Context, arg1 index.Key, arg2 string) (*index.CidEntries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FileCidEntries", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*index.CidEntries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FileCidEntries indicates an expected call of FileCidEntries.
+func (mr *MockIndexMockRecorder) FileCidEntries(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileCidEntries", reflect.TypeOf((*MockIndex)(nil).FileCidEntries), arg0, arg1, arg2)
+}
+
+// FileCopy mocks base method.
+func (m *MockIndex) FileCopy(arg0 context.Context, arg1, arg2 index.Key, arg3 string, arg4 *index.CidEntries) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FileCopy", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FileCopy indicates an expected call of FileCopy.
+func (mr *MockIndexMockRecorder) FileCopy(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileCopy", reflect.TypeOf((*MockIndex)(nil).FileCopy), arg0, arg1, arg2, arg3, arg4)
+}
+
 // FileInfo mocks base method.
 func (m *MockIndex) FileInfo(arg0 context.Context, arg1 index.Key, arg2 ...string) ([]index.FileInfo, error) {
 	m.ctrl.T.Helper()
@@ -243,6 +373,20 @@ func (mr *MockIndexMockRecorder) FilesList(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilesList", reflect.TypeOf((*MockIndex)(nil).FilesList), arg0, arg1)
 }
 
+// FlushAudit mocks base method.
+func (m *MockIndex) FlushAudit(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlushAudit", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FlushAudit indicates an expected call of FlushAudit.
+func (mr *MockIndexMockRecorder) FlushAudit(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushAudit", reflect.TypeOf((*MockIndex)(nil).FlushAudit), arg0)
+}
+
 // GroupInfo mocks base method.
 func (m *MockIndex) GroupInfo(arg0 context.Context, arg1 string) (index.GroupInfo, error) {
 	m.ctrl.T.Helper()
@@ -317,6 +461,49 @@ func (mr *MockIndexMockRecorder) OnBlockUploaded(arg0 any, arg1 ...any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnBlockUploaded", reflect.TypeOf((*MockIndex)(nil).OnBlockUploaded), varargs...)
 }
 
+// ReservationCommit mocks base method.
+func (m *MockIndex) ReservationCommit(arg0 context.Context, arg1 *index.Reservation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReservationCommit", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReservationCommit indicates an expected call of ReservationCommit.
+func (mr *MockIndexMockRecorder) ReservationCommit(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReservationCommit", reflect.TypeOf((*MockIndex)(nil).ReservationCommit), arg0, arg1)
+}
+
+// ReservationRelease mocks base method.
+func (m *MockIndex) ReservationRelease(arg0 context.Context, arg1 *index.Reservation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReservationRelease", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReservationRelease indicates an expected call of ReservationRelease.
+func (mr *MockIndexMockRecorder) ReservationRelease(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReservationRelease", reflect.TypeOf((*MockIndex)(nil).ReservationRelease), arg0, arg1)
+}
+
+// Reserve mocks base method.
+func (m *MockIndex) Reserve(arg0 context.Context, arg1 index.Key, arg2 []index.CidSize) (*index.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*index.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockIndexMockRecorder) Reserve(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockIndex)(nil).Reserve), arg0, arg1, arg2)
+}
+
 // Run mocks base method.
 func (m *MockIndex) Run(arg0 context.Context) error {
 	m.ctrl.T.Helper()
@@ -359,6 +546,20 @@ func (mr *MockIndexMockRecorder) SetSpaceLimit(arg0, arg1, arg2 any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSpaceLimit", reflect.TypeOf((*MockIndex)(nil).SetSpaceLimit), arg0, arg1, arg2)
 }
 
+// SnapshotUsage mocks base method.
+func (m *MockIndex) SnapshotUsage(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SnapshotUsage", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SnapshotUsage indicates an expected call of SnapshotUsage.
+func (mr *MockIndexMockRecorder) SnapshotUsage(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SnapshotUsage", reflect.TypeOf((*MockIndex)(nil).SnapshotUsage), arg0)
+}
+
 // SpaceDelete mocks base method.
 func (m *MockIndex) SpaceDelete(arg0 context.Context, arg1 index.Key) (bool, error) {
 	m.ctrl.T.Helper()
@@ -389,6 +590,124 @@ func (mr *MockIndexMockRecorder) SpaceInfo(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpaceInfo", reflect.TypeOf((*MockIndex)(nil).SpaceInfo), arg0, arg1)
 }
 
+// SpacePurge mocks base method.
+func (m *MockIndex) SpacePurge(arg0 context.Context, arg1 index.Key) (*indexproto.DeletionReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpacePurge", arg0, arg1)
+	ret0, _ := ret[0].(*indexproto.DeletionReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SpacePurge indicates an expected call of SpacePurge.
+func (mr *MockIndexMockRecorder) SpacePurge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpacePurge", reflect.TypeOf((*MockIndex)(nil).SpacePurge), arg0, arg1)
+}
+
+// SpaceRestore mocks base method.
+func (m *MockIndex) SpaceRestore(arg0 context.Context, arg1 string) (index.Key, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpaceRestore", arg0, arg1)
+	ret0, _ := ret[0].(index.Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SpaceRestore indicates an expected call of SpaceRestore.
+func (mr *MockIndexMockRecorder) SpaceRestore(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpaceRestore", reflect.TypeOf((*MockIndex)(nil).SpaceRestore), arg0, arg1)
+}
+
+// SpaceSetStatus mocks base method.
+func (m *MockIndex) SpaceSetStatus(arg0 context.Context, arg1 index.Key, arg2 indexproto.SpaceStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpaceSetStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SpaceSetStatus indicates an expected call of SpaceSetStatus.
+func (mr *MockIndexMockRecorder) SpaceSetStatus(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpaceSetStatus", reflect.TypeOf((*MockIndex)(nil).SpaceSetStatus), arg0, arg1, arg2)
+}
+
+// SpaceSoftDelete mocks base method.
+func (m *MockIndex) SpaceSoftDelete(arg0 context.Context, arg1 index.Key) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpaceSoftDelete", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SpaceSoftDelete indicates an expected call of SpaceSoftDelete.
+func (mr *MockIndexMockRecorder) SpaceSoftDelete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpaceSoftDelete", reflect.TypeOf((*MockIndex)(nil).SpaceSoftDelete), arg0, arg1)
+}
+
+// SpaceTransfer mocks base method.
+func (m *MockIndex) SpaceTransfer(arg0 context.Context, arg1 index.Key, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpaceTransfer", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SpaceTransfer indicates an expected call of SpaceTransfer.
+func (mr *MockIndexMockRecorder) SpaceTransfer(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpaceTransfer", reflect.TypeOf((*MockIndex)(nil).SpaceTransfer), arg0, arg1, arg2)
+}
+
+// UploadJournalAdd mocks base method.
+func (m *MockIndex) UploadJournalAdd(arg0 context.Context, arg1 []cid.Cid) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadJournalAdd", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadJournalAdd indicates an expected call of UploadJournalAdd.
+func (mr *MockIndexMockRecorder) UploadJournalAdd(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadJournalAdd", reflect.TypeOf((*MockIndex)(nil).UploadJournalAdd), arg0, arg1)
+}
+
+// UploadJournalClear mocks base method.
+func (m *MockIndex) UploadJournalClear(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadJournalClear", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UploadJournalClear indicates an expected call of UploadJournalClear.
+func (mr *MockIndexMockRecorder) UploadJournalClear(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadJournalClear", reflect.TypeOf((*MockIndex)(nil).UploadJournalClear), arg0, arg1)
+}
+
+// UsageHistory mocks base method.
+func (m *MockIndex) UsageHistory(arg0 context.Context, arg1 index.Key, arg2 int) ([]index.UsageSnapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsageHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]index.UsageSnapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UsageHistory indicates an expected call of UsageHistory.
+func (mr *MockIndexMockRecorder) UsageHistory(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsageHistory", reflect.TypeOf((*MockIndex)(nil).UsageHistory), arg0, arg1, arg2)
+}
+
 // WaitCidExists mocks base method.
 func (m *MockIndex) WaitCidExists(arg0 context.Context, arg1 cid.Cid) error {
 	m.ctrl.T.Helper()
@@ -402,3 +721,17 @@ func (mr *MockIndexMockRecorder) WaitCidExists(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitCidExists", reflect.TypeOf((*MockIndex)(nil).WaitCidExists), arg0, arg1)
 }
+
+// WaitCidsExist mocks base method.
+func (m *MockIndex) WaitCidsExist(arg0 context.Context, arg1 []cid.Cid, arg2 func(cid.Cid) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitCidsExist", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitCidsExist indicates an expected call of WaitCidsExist.
+func (mr *MockIndexMockRecorder) WaitCidsExist(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitCidsExist", reflect.TypeOf((*MockIndex)(nil).WaitCidsExist), arg0, arg1, arg2)
+}
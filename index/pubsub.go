@@ -2,6 +2,8 @@ package index
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
@@ -9,55 +11,152 @@ import (
 	"go.uber.org/zap"
 )
 
-const cidsChannel = "cidsChan"
+const (
+	// cidsStream is a stream of uploaded cids, every node reads it from the last seen id, so messages are not lost while reconnecting
+	cidsStream       = "cidsStream"
+	cidsStreamMaxLen = 100000
+	cidsStreamField  = "c"
+	cidsStreamBlock  = time.Second * 5
+)
+
+const (
+	defaultCidWaitMax        = time.Minute * 5
+	defaultCidWaitMaxWaiters = 10000
+	defaultCidWaitRecheck    = time.Second * 5
+)
+
+var (
+	ErrCidWaitTimeout = errors.New("cid wait timeout")
+	ErrTooManyWaiters = errors.New("too many cid waiters")
+)
 
+// WaitCidExists waits until the cid is uploaded to any node
 func (ri *redisIndex) WaitCidExists(ctx context.Context, k cid.Cid) (err error) {
-	ck := cidKey(k)
-	exists, release, err := ri.acquireKey(ctx, ck)
+	return ri.WaitCidsExist(ctx, []cid.Cid{k}, nil)
+}
+
+// WaitCidsExist waits until all the cids are uploaded to any node and calls the given func for every cid as soon as it exists.
+// All the cids share one waiter, it's registered before the existence check,
+// and the existence is re-checked periodically in case the notification was missed
+func (ri *redisIndex) WaitCidsExist(ctx context.Context, cids []cid.Cid, onExists func(c cid.Cid) error) (err error) {
+	pending := make(map[string]cid.Cid, len(cids))
+	for _, c := range cids {
+		pending[cidKey(c)] = c
+	}
+	if len(pending) == 0 {
+		return
+	}
+	w, err := ri.addCidWaiter(pending)
 	if err != nil {
-		return err
+		return
+	}
+	defer ri.removeCidWaiter(w)
+
+	found := func(ck string) error {
+		c, ok := pending[ck]
+		if !ok {
+			return nil
+		}
+		delete(pending, ck)
+		if onExists != nil {
+			return onExists(c)
+		}
+		return nil
 	}
-	if exists {
-		release()
+	check := func() error {
+		for ck := range pending {
+			exists, cErr := ri.CheckKey(ctx, ck)
+			if cErr != nil {
+				return cErr
+			}
+			if exists {
+				if err := found(ck); err != nil {
+					return err
+				}
+			}
+		}
 		return nil
 	}
 
+	if err = check(); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, time.Duration(ri.cidWaitMax.Load()), ErrCidWaitTimeout)
+	defer cancel()
+	recheck := time.NewTicker(time.Duration(ri.cidWaitRecheck.Load()))
+	defer recheck.Stop()
+	for len(pending) != 0 {
+		select {
+		case ck := <-w.ch:
+			if err = found(ck); err != nil {
+				return
+			}
+		case <-recheck.C:
+			if cErr := check(); cErr != nil {
+				if ctx.Err() == nil {
+					log.WarnCtx(ctx, "cid wait recheck error", zap.Error(cErr))
+				}
+			}
+		case <-ctx.Done():
+			if errors.Is(context.Cause(ctx), ErrCidWaitTimeout) {
+				return ErrCidWaitTimeout
+			}
+			return ctx.Err()
+		}
+	}
+	return
+}
+
+// cidWaiter receives keys of uploaded cids it's subscribed to, every key is sent once, so the channel never blocks
+type cidWaiter struct {
+	ch   chan string
+	keys []string
+}
+
+func (ri *redisIndex) addCidWaiter(keys map[string]cid.Cid) (w *cidWaiter, err error) {
 	ri.cidSubscriptionsMu.Lock()
-	release()
-	var ch = make(chan struct{})
-	m := ri.cidSubscriptions[ck]
-	if m == nil {
-		m = make(map[chan struct{}]struct{})
-	}
-	m[ch] = struct{}{}
-	ri.cidSubscriptions[ck] = m
-	ri.cidSubscriptionsMu.Unlock()
-
-	cleanup := func() {
-		ri.cidSubscriptionsMu.Lock()
+	defer ri.cidSubscriptionsMu.Unlock()
+	if ri.cidWaiters >= int(ri.cidWaitMaxWaiters.Load()) {
+		return nil, ErrTooManyWaiters
+	}
+	w = &cidWaiter{ch: make(chan string, len(keys)), keys: make([]string, 0, len(keys))}
+	for ck := range keys {
 		m := ri.cidSubscriptions[ck]
-		if m != nil {
-			delete(m, ch)
+		if m == nil {
+			m = make(map[*cidWaiter]struct{})
+			ri.cidSubscriptions[ck] = m
+		}
+		m[w] = struct{}{}
+		w.keys = append(w.keys, ck)
+	}
+	ri.cidWaiters++
+	return
+}
+
+func (ri *redisIndex) removeCidWaiter(w *cidWaiter) {
+	ri.cidSubscriptionsMu.Lock()
+	defer ri.cidSubscriptionsMu.Unlock()
+	ri.cidWaiters--
+	for _, ck := range w.keys {
+		if m := ri.cidSubscriptions[ck]; m != nil {
+			delete(m, w)
 			if len(m) == 0 {
 				delete(ri.cidSubscriptions, ck)
 			}
 		}
-		ri.cidSubscriptionsMu.Unlock()
-	}
-
-	select {
-	case <-ctx.Done():
-		cleanup()
-		return ctx.Err()
-	case <-ch:
-		return
 	}
 }
 
 func (ri *redisIndex) OnBlockUploaded(ctx context.Context, bs ...blocks.Block) {
 	if _, err := ri.cl.Pipelined(ctx, func(pipe redis.Pipeliner) error {
 		for _, b := range bs {
-			_ = pipe.Publish(ctx, cidsChannel, cidKey(b.Cid()))
+			_ = pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: cidsStream,
+				MaxLen: cidsStreamMaxLen,
+				Approx: true,
+				Values: []any{cidsStreamField, cidKey(b.Cid())},
+			})
 		}
 		return nil
 	}); err != nil {
@@ -65,26 +164,82 @@ func (ri *redisIndex) OnBlockUploaded(ctx context.Context, bs ...blocks.Block) {
 	}
 }
 
+// cidsStreamLastId returns the id of the last message in the stream, the node is interested only in messages after its start
+func (ri *redisIndex) cidsStreamLastId(ctx context.Context) (lastId string, err error) {
+	msgs, err := ri.cl.XRevRangeN(ctx, cidsStream, "+", "-", 1).Result()
+	if err != nil {
+		return
+	}
+	if len(msgs) == 0 {
+		return "0", nil
+	}
+	return msgs[0].ID, nil
+}
+
+// readCidsStream reads the stream starting after the given id until the context is done and returns the last seen id
+func (ri *redisIndex) readCidsStream(ctx context.Context, lastId string) string {
+	var retryDelay time.Duration
+	for {
+		select {
+		case <-ctx.Done():
+			return lastId
+		case <-time.After(retryDelay):
+		}
+		res, err := ri.cl.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{cidsStream, lastId},
+			Count:   1000,
+			Block:   cidsStreamBlock,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				retryDelay = 0
+				continue
+			}
+			if ctx.Err() != nil {
+				return lastId
+			}
+			// redis is not available, continue from the same id after reconnect
+			log.WarnCtx(ctx, "cids stream read error", zap.Error(err))
+			retryDelay = min(retryDelay*2+time.Second/10, time.Second*5)
+			continue
+		}
+		retryDelay = 0
+		for _, stream := range res {
+			for _, msg := range stream.Messages {
+				if ck, ok := msg.Values[cidsStreamField].(string); ok {
+					ri.handleSubscriptionMessage(ck)
+				}
+				lastId = msg.ID
+			}
+		}
+	}
+}
+
 func (ri *redisIndex) subscription(ctx context.Context) {
-	sub := ri.cl.Subscribe(ctx, cidsChannel)
-	defer func() {
-		_ = sub.Unsubscribe(ctx, cidsChannel)
-	}()
-	ch := sub.Channel()
-	for msg := range ch {
-		ri.handleSubscriptionMessage(msg.Payload)
+	var (
+		lastId string
+		err    error
+	)
+	for {
+		if lastId, err = ri.cidsStreamLastId(ctx); err == nil {
+			break
+		}
+		log.WarnCtx(ctx, "can't get the cids stream position", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
 	}
+	ri.readCidsStream(ctx, lastId)
 }
 
 func (ri *redisIndex) handleSubscriptionMessage(msg string) {
 	ri.cidSubscriptionsMu.Lock()
 	defer ri.cidSubscriptionsMu.Unlock()
 
-	chans := ri.cidSubscriptions[msg]
-	if chans != nil {
-		for ch := range chans {
-			close(ch)
-		}
-		delete(ri.cidSubscriptions, msg)
+	for w := range ri.cidSubscriptions[msg] {
+		w.ch <- msg
 	}
+	delete(ri.cidSubscriptions, msg)
 }
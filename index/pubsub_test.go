@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ipfs/go-cid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -66,4 +67,133 @@ func TestRedisIndex_WaitCidExists(t *testing.T) {
 
 		assert.ErrorIs(t, fx.WaitCidExists(tCtx, bs[0].Cid()), context.DeadlineExceeded)
 	})
+
+	t.Run("server timeout", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		fx.cidWaitMax.Store(int64(time.Second / 10))
+		bs := testutil.NewRandBlocks(1)
+
+		assert.ErrorIs(t, fx.WaitCidExists(ctx, bs[0].Cid()), ErrCidWaitTimeout)
+	})
+
+	t.Run("max waiters", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		fx.cidWaitMaxWaiters.Store(1)
+		bs := testutil.NewRandBlocks(2)
+
+		tCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		done := make(chan error)
+		go func() {
+			done <- fx.WaitCidExists(tCtx, bs[0].Cid())
+		}()
+		require.Eventually(t, func() bool {
+			fx.cidSubscriptionsMu.Lock()
+			defer fx.cidSubscriptionsMu.Unlock()
+			return fx.cidWaiters == 1
+		}, time.Second, time.Millisecond*10)
+		assert.ErrorIs(t, fx.WaitCidExists(tCtx, bs[1].Cid()), ErrTooManyWaiters)
+		fx.OnBlockUploaded(ctx, bs[0])
+		require.NoError(t, <-done)
+	})
+
+	t.Run("recheck", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		fx.cidWaitRecheck.Store(int64(time.Second / 10))
+		bs := testutil.NewRandBlocks(1)
+
+		tCtx, cancel := context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+		done := make(chan error)
+		go func() {
+			done <- fx.WaitCidExists(tCtx, bs[0].Cid())
+		}()
+		time.Sleep(time.Second / 5)
+		// the block is added without the notification
+		require.NoError(t, fx.BlocksAdd(ctx, bs))
+		require.NoError(t, <-done)
+	})
+}
+
+func TestRedisIndex_WaitCidExistsMultiInstance(t *testing.T) {
+	fx1 := newFixture(t)
+	defer fx1.Finish(t)
+	fx2 := newFixture(t)
+	defer fx2.Finish(t)
+	// let both nodes start reading the stream
+	time.Sleep(time.Second / 2)
+
+	t.Run("uploaded to another node", func(t *testing.T) {
+		bs := testutil.NewRandBlocks(1)
+		tCtx, cancel := context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+		done := make(chan error)
+		go func() {
+			done <- fx1.WaitCidExists(tCtx, bs[0].Cid())
+		}()
+		time.Sleep(time.Second / 5)
+		require.NoError(t, fx2.BlocksAdd(ctx, bs))
+		fx2.OnBlockUploaded(ctx, bs...)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("uploaded while reconnecting", func(t *testing.T) {
+		// a node that is not reading the stream at the moment of the upload
+		ri := &redisIndex{cl: fx1.cl, cidSubscriptions: make(map[string]map[*cidWaiter]struct{})}
+		ri.cidWaitMaxWaiters.Store(10)
+		bs := testutil.NewRandBlocks(1)
+		w, err := ri.addCidWaiter(map[string]cid.Cid{cidKey(bs[0].Cid()): bs[0].Cid()})
+		require.NoError(t, err)
+		defer ri.removeCidWaiter(w)
+
+		lastId, err := ri.cidsStreamLastId(ctx)
+		require.NoError(t, err)
+		fx2.OnBlockUploaded(ctx, bs...)
+
+		// reconnected, continue from the last seen id
+		rCtx, cancel := context.WithCancel(ctx)
+		go ri.readCidsStream(rCtx, lastId)
+		defer cancel()
+		select {
+		case <-w.ch:
+		case <-time.After(time.Second * 5):
+			t.Fatal("waiter is not notified")
+		}
+	})
+}
+
+func TestRedisIndex_WaitCidsExist(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+	bs := testutil.NewRandBlocks(3)
+	cids := []cid.Cid{bs[0].Cid(), bs[1].Cid(), bs[2].Cid()}
+	// the first block already exists
+	require.NoError(t, fx.BlocksAdd(ctx, bs[:1]))
+
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	reported := make(chan cid.Cid, len(cids))
+	done := make(chan error)
+	go func() {
+		done <- fx.WaitCidsExist(tCtx, cids, func(c cid.Cid) error {
+			reported <- c
+			return nil
+		})
+	}()
+	assert.Equal(t, bs[0].Cid(), <-reported)
+
+	fx.OnBlockUploaded(ctx, bs[2])
+	assert.Equal(t, bs[2].Cid(), <-reported)
+	fx.OnBlockUploaded(ctx, bs[1], bs[2])
+	assert.Equal(t, bs[1].Cid(), <-reported)
+	require.NoError(t, <-done)
+	assert.Len(t, reported, 0)
+
+	fx.cidSubscriptionsMu.Lock()
+	defer fx.cidSubscriptionsMu.Unlock()
+	assert.Zero(t, fx.cidWaiters)
+	assert.Empty(t, fx.cidSubscriptions)
 }
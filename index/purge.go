@@ -0,0 +1,160 @@
+package index
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/ipfs/go-cid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+type purgeStat struct {
+	fileCount  uint32
+	cidCount   uint64
+	bytesFreed uint64
+}
+
+func deletionReportKey(spaceId string, timestamp int64) string {
+	return "deletionReport:" + spaceId + ":" + strconv.FormatInt(timestamp, 10)
+}
+
+// SpacePurge deletes the space like SpaceDelete and also removes blocks that are not referenced by other spaces.
+// The signed report is stored in the index bucket, nil report means the space doesn't exist.
+func (ri *redisIndex) SpacePurge(ctx context.Context, key Key) (report *indexproto.DeletionReport, err error) {
+	entry, release, err := ri.AcquireSpace(ctx, key)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	if !entry.spaceExists {
+		return nil, nil
+	}
+	stat := &purgeStat{}
+	before := entry.sizes()
+	fileIds, _, err := ri.spaceDelete(ctx, key, entry, stat)
+	ri.auditSpaceChange(ctx, AuditOpSpacePurge, key, entry, before, fileIds...)
+	if err != nil {
+		return
+	}
+	report = &indexproto.DeletionReport{
+		SpaceId:    key.SpaceId,
+		GroupId:    key.GroupId,
+		FileCount:  stat.fileCount,
+		CidCount:   stat.cidCount,
+		BytesFreed: stat.bytesFreed,
+		Timestamp:  time.Now().Unix(),
+	}
+	if err = ri.storeDeletionReport(ctx, report); err != nil {
+		return nil, err
+	}
+	log.InfoCtx(ctx, "space purged",
+		zap.String("spaceId", key.SpaceId),
+		zap.Uint32("files", stat.fileCount),
+		zap.Uint64("cids", stat.cidCount),
+		zap.Uint64("bytes", stat.bytesFreed),
+	)
+	return
+}
+
+// purgeUnreferenced removes the blocks of unreferenced cids that are not being uploaded right now.
+// The upload checks that the block exists under the block lock, so the block is deleted under the same lock.
+// The upload takes the block lock before the cid locks held here, so the lock is not waited for: a busy block is
+// kept with zero refs and recorded in the upload journal, the sweeper removes it later if it's still unreferenced
+func (ri *redisIndex) purgeUnreferenced(ctx context.Context, entries []*cidEntry, stat *purgeStat) (err error) {
+	var (
+		locked  = make([]*cidEntry, 0, len(entries))
+		busy    []cid.Cid
+		lockers []*redsync.Mutex
+	)
+	defer func() {
+		for _, l := range lockers {
+			_, _ = l.Unlock()
+		}
+	}()
+	for _, e := range entries {
+		l := ri.redsync.NewMutex("_lock:b:"+e.Cid.String(), redsync.WithExpiry(time.Minute))
+		if lErr := l.TryLockContext(ctx); lErr != nil {
+			if err = e.Save(ctx, ri.cl); err != nil {
+				return
+			}
+			busy = append(busy, e.Cid)
+			continue
+		}
+		lockers = append(lockers, l)
+		locked = append(locked, e)
+	}
+	if len(busy) != 0 {
+		if _, err = ri.UploadJournalAdd(ctx, busy); err != nil {
+			return
+		}
+	}
+	return ri.purgeCids(ctx, locked, stat)
+}
+
+// purgeCids removes unreferenced cids from the store and the index, the caller must hold the cid and the block locks
+func (ri *redisIndex) purgeCids(ctx context.Context, entries []*cidEntry, stat *purgeStat) (err error) {
+	if len(entries) == 0 {
+		return
+	}
+	var toDelete = make([]cid.Cid, len(entries))
+	for i, e := range entries {
+		toDelete[i] = e.Cid
+	}
+	if err = ri.persistStore.DeleteMany(ctx, toDelete); err != nil {
+		return
+	}
+
+	var persisted = make([]*redis.BoolCmd, len(entries))
+	if _, err = ri.cl.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, e := range entries {
+			ck := cidKey(e.Cid)
+			pipe.Del(ctx, ck)
+			pipe.DecrBy(ctx, cidSizeSumKey, int64(e.Size_))
+			pipe.Decr(ctx, cidCount)
+			persisted[i] = pipe.BFExists(ctx, bloomFilterKey(ck), ck)
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	// remove persisted copies, otherwise the entry will be restored on the next access
+	for i, e := range entries {
+		if persisted[i].Val() {
+			if err = ri.persistStore.IndexDelete(ctx, cidKey(e.Cid)); err != nil {
+				return
+			}
+		}
+		stat.cidCount++
+		stat.bytesFreed += e.Size_
+	}
+	return
+}
+
+func (ri *redisIndex) storeDeletionReport(ctx context.Context, report *indexproto.DeletionReport) (err error) {
+	data, err := report.Marshal()
+	if err != nil {
+		return
+	}
+	signKey := ri.account.Account().SignKey
+	signature, err := signKey.Sign(data)
+	if err != nil {
+		return
+	}
+	signed := &indexproto.SignedDeletionReport{
+		Report:    data,
+		Signature: signature,
+		Identity:  signKey.GetPublic().Account(),
+	}
+	signedData, err := signed.Marshal()
+	if err != nil {
+		return
+	}
+	return ri.persistStore.IndexPut(ctx, deletionReportKey(report.SpaceId, report.Timestamp), signedData)
+}
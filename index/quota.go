@@ -0,0 +1,45 @@
+package index
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/notifier"
+)
+
+// effectiveLimit is the limit of groups and spaces reported to clients by GroupInfo and SpaceInfo,
+// stored limits are pinned to defaultLimit and aren't enforced, so quotas are checked against this one
+const effectiveLimit uint64 = 1000000000000
+
+// updateQuotaWarnLevel moves the warn level of the group to the highest threshold crossed by the current usage.
+// It returns the threshold to notify about when the usage has crossed a new threshold, every crossing is reported once
+// until the usage goes below the threshold again
+func (ri *redisIndex) updateQuotaWarnLevel(group *groupEntry) (crossed uint32) {
+	var level uint32
+	usage := float64(group.Size_) * 100 / float64(effectiveLimit)
+	for _, threshold := range *ri.quotaWarnThresholds.Load() {
+		if usage >= float64(threshold) {
+			level = threshold
+		}
+	}
+	if level > group.QuotaWarnLevel {
+		crossed = level
+	}
+	group.QuotaWarnLevel = level
+	return
+}
+
+func (ri *redisIndex) notifyQuotaWarning(ctx context.Context, group *groupEntry, threshold uint32) {
+	log.InfoCtx(ctx, "quota warning threshold crossed", zap.String("groupId", group.GroupId), zap.Uint32("threshold", threshold))
+	if ri.notifier == nil {
+		return
+	}
+	ri.notifier.Notify(notifier.Event{
+		Type:      notifier.EventQuotaWarning,
+		GroupId:   group.GroupId,
+		Threshold: threshold,
+		Size:      group.Size_,
+		Limit:     effectiveLimit,
+	})
+}
@@ -0,0 +1,34 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+func TestRedisIndex_updateQuotaWarnLevel(t *testing.T) {
+	ri := &redisIndex{}
+	thresholds := []uint32{80, 95}
+	ri.quotaWarnThresholds.Store(&thresholds)
+	// the stored limit is pinned to the default one, the usage is compared with the limit reported to clients
+	group := &groupEntry{GroupEntry: &indexproto.GroupEntry{Limit: 1024}}
+
+	var crossed []uint32
+	for _, percent := range []uint64{10, 81, 85, 96, 99, 50, 82, 100} {
+		group.Size_ = effectiveLimit / 100 * percent
+		if threshold := ri.updateQuotaWarnLevel(group); threshold != 0 {
+			crossed = append(crossed, threshold)
+		}
+	}
+	// every crossing is reported once, going below the threshold allows to report it again
+	assert.Equal(t, []uint32{80, 95, 80, 95}, crossed)
+	assert.Equal(t, uint32(95), group.QuotaWarnLevel)
+
+	t.Run("usage above the stored limit", func(t *testing.T) {
+		group := &groupEntry{GroupEntry: &indexproto.GroupEntry{Limit: 1024, Size_: 1024 * 1024}}
+		assert.Zero(t, ri.updateQuotaWarnLevel(group))
+		assert.Zero(t, group.QuotaWarnLevel)
+	})
+}
@@ -0,0 +1,177 @@
+package index
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+const defaultReservationTtl = time.Minute * 5
+
+// CidSize is a cid with the size of its block
+type CidSize struct {
+	Cid  cid.Cid
+	Size uint64
+}
+
+// Sizes returns cids of the entries with their sizes
+func (ce *CidEntries) Sizes() []CidSize {
+	sizes := make([]CidSize, len(ce.entries))
+	for i, e := range ce.entries {
+		sizes[i] = CidSize{Cid: e.Cid, Size: e.Size_}
+	}
+	return sizes
+}
+
+// Reservation is the size reserved against the limit of the group or the isolated space for an upload in progress
+type Reservation struct {
+	Key  Key
+	Id   string
+	Size uint64
+
+	holder string
+}
+
+/*
+	Reservation keys, they are not persisted:
+		rsv:g:{groupId}: map
+			{reservationId} -> {size}:{expireTimeUnixMilli}
+		rsv:s:{groupId}/{spaceId}: map, for isolated spaces
+*/
+
+func reservationKey(kind, id string) string {
+	return "rsv:" + kind + ":" + id
+}
+
+// Reserve reserves the size of cids that are not bound to the group or the isolated space yet.
+// It returns ErrSpaceReadOnly when the space doesn't accept writes and ErrLimitExceed when the current usage with all active reservations doesn't fit the effective limit.
+// The reservation must be committed after the file bind or released on failure, otherwise it expires.
+// A nil reservation is returned when there is nothing to reserve
+func (ri *redisIndex) Reserve(ctx context.Context, key Key, cids []CidSize) (reservation *Reservation, err error) {
+	entry, release, err := ri.AcquireSpace(ctx, key)
+	if err != nil {
+		return
+	}
+	defer release()
+	// writes to spaces waiting for deletion are not allowed
+	if entry.space.Status != indexproto.SpaceStatus_SpaceStatusOk {
+		return nil, ErrSpaceReadOnly
+	}
+
+	var (
+		holder     = reservationKey("g", key.GroupId)
+		boundKey   = groupKey(key)
+		usage      = entry.group.Size_
+		isolated   = entry.space.Limit != 0
+		existCmds  = make([]*redis.BoolCmd, len(cids))
+		uniqueCids = make(map[string]struct{}, len(cids))
+	)
+	if isolated {
+		holder = reservationKey("s", key.GroupId+"/"+key.SpaceId)
+		boundKey = spaceKey(key)
+		usage = entry.space.Size_
+	}
+
+	_, err = ri.cl.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, c := range cids {
+			existCmds[i] = pipe.HExists(ctx, boundKey, cidKey(c.Cid))
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	var size uint64
+	for i, c := range cids {
+		if _, ok := uniqueCids[c.Cid.KeyString()]; ok {
+			continue
+		}
+		uniqueCids[c.Cid.KeyString()] = struct{}{}
+		if !existCmds[i].Val() {
+			size += c.Size
+		}
+	}
+	if size == 0 {
+		return
+	}
+
+	reserved, err := ri.activeReservations(ctx, holder)
+	if err != nil {
+		return
+	}
+	// stored limits aren't enforced, the usage is checked against the limit reported to clients
+	if usage+reserved+size > effectiveLimit {
+		ri.metrics.reservations.WithLabelValues("rejected").Inc()
+		return nil, ErrLimitExceed
+	}
+
+	ttl := time.Duration(ri.reservationTtl.Load())
+	expireAt := time.Now().Add(ttl).UnixMilli()
+	reservation = &Reservation{
+		Key:    key,
+		Id:     strconv.FormatUint(rand.Uint64(), 36),
+		Size:   size,
+		holder: holder,
+	}
+	_, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.HSet(ctx, holder, reservation.Id, strconv.FormatUint(size, 10)+":"+strconv.FormatInt(expireAt, 10))
+		tx.PExpire(ctx, holder, ttl)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// activeReservations returns the sum of not expired reservations of the holder, expired ones are removed
+func (ri *redisIndex) activeReservations(ctx context.Context, holder string) (reserved uint64, err error) {
+	res, err := ri.cl.HGetAll(ctx, holder).Result()
+	if err != nil {
+		return
+	}
+	now := time.Now().UnixMilli()
+	var expired []string
+	for id, val := range res {
+		sizeStr, expireStr, _ := strings.Cut(val, ":")
+		size, _ := strconv.ParseUint(sizeStr, 10, 64)
+		expireAt, _ := strconv.ParseInt(expireStr, 10, 64)
+		if expireAt <= now {
+			expired = append(expired, id)
+			continue
+		}
+		reserved += size
+	}
+	if len(expired) != 0 {
+		if dErr := ri.cl.HDel(ctx, holder, expired...).Err(); dErr != nil {
+			log.WarnCtx(ctx, "can't remove expired reservations", zap.String("holder", holder), zap.Error(dErr))
+		}
+	}
+	return
+}
+
+// ReservationCommit removes the reservation after the successful file bind, the bound size is counted by the usage since then
+func (ri *redisIndex) ReservationCommit(ctx context.Context, reservation *Reservation) (err error) {
+	return ri.removeReservation(ctx, reservation, "committed")
+}
+
+// ReservationRelease returns the reserved size back when the upload has failed
+func (ri *redisIndex) ReservationRelease(ctx context.Context, reservation *Reservation) (err error) {
+	return ri.removeReservation(ctx, reservation, "released")
+}
+
+func (ri *redisIndex) removeReservation(ctx context.Context, reservation *Reservation, result string) (err error) {
+	if reservation == nil {
+		return
+	}
+	ri.metrics.reservations.WithLabelValues(result).Inc()
+	return ri.cl.HDel(ctx, reservation.holder, reservation.Id).Err()
+}
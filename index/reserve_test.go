@@ -0,0 +1,100 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+func TestRedisIndex_Reserve(t *testing.T) {
+	newCidSizes := func(size uint64) []CidSize {
+		return []CidSize{{Cid: testutil.NewRandCid(), Size: size}}
+	}
+	t.Run("concurrent uploads", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key := newRandKey()
+
+		size := effectiveLimit / 10 * 6
+		r1, err := fx.Reserve(ctx, key, newCidSizes(size))
+		require.NoError(t, err)
+		require.NotNil(t, r1)
+		assert.Equal(t, size, r1.Size)
+
+		_, err = fx.Reserve(ctx, key, newCidSizes(size))
+		assert.ErrorIs(t, err, ErrLimitExceed)
+
+		require.NoError(t, fx.ReservationRelease(ctx, r1))
+		r2, err := fx.Reserve(ctx, key, newCidSizes(size))
+		require.NoError(t, err)
+		require.NoError(t, fx.ReservationCommit(ctx, r2))
+	})
+	t.Run("usage above the default limit", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key := newRandKey()
+
+		// 11 blocks of 110 bytes are more than the default limit of 1024
+		bs := testutil.NewRandBlocks(11)
+		require.NoError(t, fx.BlocksAdd(ctx, bs))
+		cids, err := fx.CidEntriesByBlocks(ctx, bs)
+		require.NoError(t, err)
+		require.NoError(t, fx.FileBind(ctx, key, "fileId", cids))
+		cids.Release()
+
+		r, err := fx.Reserve(ctx, key, newCidSizes(1024))
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		require.NoError(t, fx.ReservationCommit(ctx, r))
+	})
+	t.Run("read only", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key := newRandKey()
+		bs := testutil.NewRandBlocks(1)
+		require.NoError(t, fx.BlocksAdd(ctx, bs))
+		cids, err := fx.CidEntriesByBlocks(ctx, bs)
+		require.NoError(t, err)
+		require.NoError(t, fx.FileBind(ctx, key, "fileId", cids))
+		cids.Release()
+
+		require.NoError(t, fx.SpaceSetStatus(ctx, key, indexproto.SpaceStatus_SpaceStatusDeletionPending))
+		_, err = fx.Reserve(ctx, key, newCidSizes(10))
+		assert.ErrorIs(t, err, ErrSpaceReadOnly)
+	})
+	t.Run("bound cids", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key := newRandKey()
+
+		bs := testutil.NewRandBlocks(3)
+		require.NoError(t, fx.BlocksAdd(ctx, bs))
+		cids, err := fx.CidEntriesByBlocks(ctx, bs)
+		require.NoError(t, err)
+		require.NoError(t, fx.FileBind(ctx, key, "fileId", cids))
+
+		r, err := fx.Reserve(ctx, key, cids.Sizes())
+		cids.Release()
+		require.NoError(t, err)
+		assert.Nil(t, r)
+	})
+	t.Run("expired", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key := newRandKey()
+
+		size := effectiveLimit / 10 * 6
+		fx.reservationTtl.Store(int64(time.Millisecond * 50))
+		_, err := fx.Reserve(ctx, key, newCidSizes(size))
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond * 100)
+
+		_, err = fx.Reserve(ctx, key, newCidSizes(size))
+		require.NoError(t, err)
+	})
+}
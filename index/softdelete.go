@@ -0,0 +1,166 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+const (
+	// deletedSpacesKey is a sorted set of soft deleted space ids, the score is a deletion time
+	deletedSpacesKey = "deletedSpaces.{system}"
+	// deletedSpacesGroupsKey is a map spaceId -> groupId for soft deleted spaces
+	deletedSpacesGroupsKey = "deletedSpacesGroups.{system}"
+)
+
+var (
+	ErrSpaceDeleted    = errors.New("space is deleted")
+	ErrSpaceNotDeleted = errors.New("space is not deleted")
+)
+
+// SpaceSoftDelete hides the space from the group and rejects all the requests to it,
+// files and cid refs are kept until the space is restored or purged by SpaceDelete
+func (ri *redisIndex) SpaceSoftDelete(ctx context.Context, key Key) (ok bool, err error) {
+	entry, release, err := ri.AcquireSpace(ctx, key)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	if !entry.spaceExists || entry.space.Status == indexproto.SpaceStatus_SpaceStatusDeleted {
+		return false, nil
+	}
+	now := time.Now()
+	entry.space.Status = indexproto.SpaceStatus_SpaceStatusDeleted
+	entry.space.DeleteTime = now.Unix()
+	entry.group.SpaceIds = slices.DeleteFunc(entry.group.SpaceIds, func(spaceId string) bool {
+		return spaceId == key.SpaceId
+	})
+	if !slices.Contains(entry.group.DeletedSpaceIds, key.SpaceId) {
+		entry.group.DeletedSpaceIds = append(entry.group.DeletedSpaceIds, key.SpaceId)
+	}
+	// system keys and keys of the group are in different slots, so they are written separately.
+	// The space is marked as deleted first: CheckSpace rejects requests to it right away,
+	// and a space with the Ok status left in the deleted set is cleaned up by SpaceRestore or deleted by the purge
+	if err = ri.markUsageChanged(ctx, key); err != nil {
+		return
+	}
+	if _, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.ZAdd(ctx, deletedSpacesKey, redis.Z{Score: float64(now.Unix()), Member: key.SpaceId})
+		tx.HSet(ctx, deletedSpacesGroupsKey, key.SpaceId, key.GroupId)
+		return nil
+	}); err != nil {
+		return
+	}
+	if _, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		entry.space.Save(ctx, key, tx)
+		entry.group.Save(ctx, tx)
+		return nil
+	}); err != nil {
+		return
+	}
+	ri.audit(ctx, &indexproto.AuditEntry{Op: AuditOpSpaceSoftDelete, GroupId: key.GroupId, SpaceId: key.SpaceId})
+	return true, nil
+}
+
+// SpaceRestore returns the soft deleted space back to the group
+func (ri *redisIndex) SpaceRestore(ctx context.Context, spaceId string) (key Key, err error) {
+	groupId, err := ri.cl.HGet(ctx, deletedSpacesGroupsKey, spaceId).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			err = ErrSpaceNotDeleted
+		}
+		return
+	}
+	key = Key{GroupId: groupId, SpaceId: spaceId}
+	entry, release, err := ri.AcquireSpace(ctx, key)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	if entry.space.Status != indexproto.SpaceStatus_SpaceStatusDeleted {
+		if !entry.spaceExists {
+			return key, ErrSpaceNotDeleted
+		}
+		// a half applied soft delete or restore, only the space is left in the deleted set
+		if err = ri.unmarkDeleted(ctx, key.SpaceId); err != nil {
+			return
+		}
+		ri.audit(ctx, &indexproto.AuditEntry{Op: AuditOpSpaceRestore, GroupId: key.GroupId, SpaceId: key.SpaceId})
+		return key, nil
+	}
+	return key, ri.spaceRestore(ctx, key, entry)
+}
+
+func (ri *redisIndex) spaceRestore(ctx context.Context, key Key, entry groupSpaceEntry) (err error) {
+	entry.space.Status = indexproto.SpaceStatus_SpaceStatusOk
+	entry.space.DeleteTime = 0
+	entry.group.DeletedSpaceIds = slices.DeleteFunc(entry.group.DeletedSpaceIds, func(spaceId string) bool {
+		return spaceId == key.SpaceId
+	})
+	entry.group.AddSpaceId(key.SpaceId)
+	if err = ri.markUsageChanged(ctx, key); err != nil {
+		return
+	}
+	// the space is removed from the deleted set last, so it stays rejected by CheckSpace until it's fully restored
+	if _, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		entry.space.Save(ctx, key, tx)
+		entry.group.Save(ctx, tx)
+		return nil
+	}); err != nil {
+		return
+	}
+	if err = ri.unmarkDeleted(ctx, key.SpaceId); err != nil {
+		return
+	}
+	ri.audit(ctx, &indexproto.AuditEntry{Op: AuditOpSpaceRestore, GroupId: key.GroupId, SpaceId: key.SpaceId})
+	return
+}
+
+func (ri *redisIndex) unmarkDeleted(ctx context.Context, spaceId string) (err error) {
+	_, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.ZRem(ctx, deletedSpacesKey, spaceId)
+		tx.HDel(ctx, deletedSpacesGroupsKey, spaceId)
+		return nil
+	})
+	return
+}
+
+// CheckSpace returns ErrSpaceDeleted when the space is soft deleted
+func (ri *redisIndex) CheckSpace(ctx context.Context, key Key) (err error) {
+	if err = ri.cl.ZScore(ctx, deletedSpacesKey, key.SpaceId).Err(); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return
+	}
+	return ErrSpaceDeleted
+}
+
+// DeletedSpaces returns keys of spaces soft deleted before the given time
+func (ri *redisIndex) DeletedSpaces(ctx context.Context, before time.Time) (keys []Key, err error) {
+	spaceIds, err := ri.cl.ZRangeByScore(ctx, deletedSpacesKey, &redis.ZRangeBy{
+		Min: "0",
+		Max: strconv.FormatInt(before.Unix(), 10),
+	}).Result()
+	if err != nil || len(spaceIds) == 0 {
+		return
+	}
+	groupIds, err := ri.cl.HMGet(ctx, deletedSpacesGroupsKey, spaceIds...).Result()
+	if err != nil {
+		return
+	}
+	for i, spaceId := range spaceIds {
+		if groupId, ok := groupIds[i].(string); ok {
+			keys = append(keys, Key{GroupId: groupId, SpaceId: spaceId})
+		}
+	}
+	return
+}
@@ -0,0 +1,133 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+func TestRedisIndex_SpaceSoftDelete(t *testing.T) {
+	newSpace := func(fx *fixture) (key Key, fileId string) {
+		key = newRandKey()
+		bs := testutil.NewRandBlocks(3)
+		require.NoError(t, fx.BlocksAdd(ctx, bs))
+		cids, err := fx.CidEntriesByBlocks(ctx, bs)
+		require.NoError(t, err)
+		fileId = testutil.NewRandCid().String()
+		require.NoError(t, fx.FileBind(ctx, key, fileId, cids))
+		cids.Release()
+		return
+	}
+	t.Run("restore", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key, fileId := newSpace(fx)
+
+		ok, err := fx.SpaceSoftDelete(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		groupInfo, err := fx.GroupInfo(ctx, key.GroupId)
+		require.NoError(t, err)
+		assert.NotContains(t, groupInfo.SpaceIds, key.SpaceId)
+		assert.ErrorIs(t, fx.CheckSpace(ctx, key), ErrSpaceDeleted)
+		assert.ErrorIs(t, fx.CheckLimits(ctx, key), ErrSpaceReadOnly)
+
+		// files are kept
+		fileIds, err := fx.FilesList(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, []string{fileId}, fileIds)
+
+		// second call
+		ok, err = fx.SpaceSoftDelete(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		restoredKey, err := fx.SpaceRestore(ctx, key.SpaceId)
+		require.NoError(t, err)
+		assert.Equal(t, key, restoredKey)
+
+		groupInfo, err = fx.GroupInfo(ctx, key.GroupId)
+		require.NoError(t, err)
+		assert.Contains(t, groupInfo.SpaceIds, key.SpaceId)
+		assert.NoError(t, fx.CheckSpace(ctx, key))
+		assert.NoError(t, fx.CheckLimits(ctx, key))
+
+		_, err = fx.SpaceRestore(ctx, key.SpaceId)
+		assert.ErrorIs(t, err, ErrSpaceNotDeleted)
+	})
+	t.Run("restore by status", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key, _ := newSpace(fx)
+
+		_, err := fx.SpaceSoftDelete(ctx, key)
+		require.NoError(t, err)
+		require.NoError(t, fx.SpaceSetStatus(ctx, key, indexproto.SpaceStatus_SpaceStatusDeletionPending))
+		assert.ErrorIs(t, fx.CheckSpace(ctx, key), ErrSpaceDeleted)
+
+		require.NoError(t, fx.SpaceSetStatus(ctx, key, indexproto.SpaceStatus_SpaceStatusOk))
+		assert.NoError(t, fx.CheckSpace(ctx, key))
+		groupInfo, err := fx.GroupInfo(ctx, key.GroupId)
+		require.NoError(t, err)
+		assert.Contains(t, groupInfo.SpaceIds, key.SpaceId)
+	})
+	t.Run("half applied", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key, _ := newSpace(fx)
+
+		// only the system keys are written
+		require.NoError(t, fx.cl.ZAdd(ctx, deletedSpacesKey, redis.Z{Score: float64(time.Now().Unix()), Member: key.SpaceId}).Err())
+		require.NoError(t, fx.cl.HSet(ctx, deletedSpacesGroupsKey, key.SpaceId, key.GroupId).Err())
+		assert.ErrorIs(t, fx.CheckSpace(ctx, key), ErrSpaceDeleted)
+
+		restoredKey, err := fx.SpaceRestore(ctx, key.SpaceId)
+		require.NoError(t, err)
+		assert.Equal(t, key, restoredKey)
+		assert.NoError(t, fx.CheckSpace(ctx, key))
+
+		// the purge removes the space from the deleted set
+		require.NoError(t, fx.cl.ZAdd(ctx, deletedSpacesKey, redis.Z{Score: float64(time.Now().Unix()), Member: key.SpaceId}).Err())
+		require.NoError(t, fx.cl.HSet(ctx, deletedSpacesGroupsKey, key.SpaceId, key.GroupId).Err())
+		ok, err := fx.SpaceDelete(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		keys, err := fx.DeletedSpaces(ctx, time.Now())
+		require.NoError(t, err)
+		assert.NotContains(t, keys, key)
+	})
+	t.Run("purge", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key, _ := newSpace(fx)
+
+		_, err := fx.SpaceSoftDelete(ctx, key)
+		require.NoError(t, err)
+
+		keys, err := fx.DeletedSpaces(ctx, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		keys, err = fx.DeletedSpaces(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Contains(t, keys, key)
+
+		ok, err := fx.SpaceDelete(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		groupInfo, err := fx.GroupInfo(ctx, key.GroupId)
+		require.NoError(t, err)
+		assert.Empty(t, groupInfo.BytesUsage)
+		assert.NoError(t, fx.CheckSpace(ctx, key))
+		keys, err = fx.DeletedSpaces(ctx, time.Now())
+		require.NoError(t, err)
+		assert.NotContains(t, keys, key)
+	})
+}
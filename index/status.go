@@ -0,0 +1,54 @@
+package index
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+var ErrSpaceReadOnly = errors.New("space is read only")
+
+func (ri *redisIndex) SpaceSetStatus(ctx context.Context, key Key, status indexproto.SpaceStatus) (err error) {
+	entry, release, err := ri.AcquireSpace(ctx, key)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	if entry.space.Status == status {
+		return
+	}
+	if entry.space.Status == indexproto.SpaceStatus_SpaceStatusDeleted {
+		// the deletion was reverted - return the space back to the group
+		if status == indexproto.SpaceStatus_SpaceStatusOk {
+			return ri.spaceRestore(ctx, key, entry)
+		}
+		return
+	}
+	// statuses of spaces this node never stored are not kept, except the deletion tombstone that isn't a member of the group
+	tombstone := !entry.spaceExists
+	if tombstone && status != indexproto.SpaceStatus_SpaceStatusDeleted {
+		return
+	}
+	entry.space.Status = status
+	if !tombstone {
+		entry.group.AddSpaceId(key.SpaceId)
+	}
+	if err = ri.markSpaceChanged(ctx, key); err != nil {
+		return
+	}
+	if _, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		entry.space.Save(ctx, key, tx)
+		if !tombstone {
+			entry.group.Save(ctx, tx)
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+	ri.audit(ctx, &indexproto.AuditEntry{Op: AuditOpSpaceSetStatus, GroupId: key.GroupId, SpaceId: key.SpaceId, Status: status})
+	return
+}
@@ -0,0 +1,69 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+func TestRedisIndex_SpaceSetStatus(t *testing.T) {
+	t.Run("read only", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+
+		k := newRandKey()
+		bs := testutil.NewRandBlocks(3)
+		require.NoError(t, fx.BlocksAdd(ctx, bs))
+		cids, err := fx.CidEntriesByBlocks(ctx, bs)
+		require.NoError(t, err)
+		require.NoError(t, fx.FileBind(ctx, k, testutil.NewRandCid().String(), cids))
+		cids.Release()
+		require.NoError(t, fx.CheckLimits(ctx, k))
+
+		require.NoError(t, fx.SpaceSetStatus(ctx, k, indexproto.SpaceStatus_SpaceStatusDeletionPending))
+		assert.ErrorIs(t, fx.CheckLimits(ctx, k), ErrSpaceReadOnly)
+
+		require.NoError(t, fx.SpaceSetStatus(ctx, k, indexproto.SpaceStatus_SpaceStatusOk))
+		assert.NoError(t, fx.CheckLimits(ctx, k))
+	})
+	t.Run("unknown space", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+
+		k := newRandKey()
+		require.NoError(t, fx.SpaceSetStatus(ctx, k, indexproto.SpaceStatus_SpaceStatusDeletionPending))
+		exists, err := fx.cl.Exists(ctx, spaceKey(k)).Result()
+		require.NoError(t, err)
+		assert.Zero(t, exists)
+		groupInfo, err := fx.GroupInfo(ctx, k.GroupId)
+		require.NoError(t, err)
+		assert.Empty(t, groupInfo.SpaceIds)
+	})
+	t.Run("tombstone of unknown space", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+
+		k := newRandKey()
+		require.NoError(t, fx.SpaceSetStatus(ctx, k, indexproto.SpaceStatus_SpaceStatusDeleted))
+		space, err := fx.getSpaceEntry(ctx, k)
+		require.NoError(t, err)
+		assert.Equal(t, indexproto.SpaceStatus_SpaceStatusDeleted, space.Status)
+		groupInfo, err := fx.GroupInfo(ctx, k.GroupId)
+		require.NoError(t, err)
+		assert.Empty(t, groupInfo.SpaceIds)
+	})
+	t.Run("ok for unknown space", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+
+		k := newRandKey()
+		require.NoError(t, fx.SpaceSetStatus(ctx, k, indexproto.SpaceStatus_SpaceStatusOk))
+		exists, err := fx.cl.Exists(ctx, spaceKey(k)).Result()
+		require.NoError(t, err)
+		assert.Zero(t, exists)
+	})
+}
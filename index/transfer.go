@@ -0,0 +1,375 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/ipfs/go-cid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+const (
+	// spaceGroupsKey is a map spaceId -> groupId of the group the space is stored in
+	spaceGroupsKey = "spaceGroups.{system}"
+	// spaceTransfersKey is a map spaceId -> groupId of transfers in progress, an interrupted transfer is resumed by the next call
+	spaceTransfersKey = "spaceTransfers.{system}"
+	// spaceGroupsBackfilledKey is set when groups of all spaces stored before the map was added are remembered
+	spaceGroupsBackfilledKey = "spaceGroupsBackfilled.{system}"
+)
+
+var ErrSpaceExists = errors.New("space already exists in the group")
+
+// CheckSpaceGroup transfers the space to the group of the key when it's stored in another group, e.g. the owner of the space has changed
+func (ri *redisIndex) CheckSpaceGroup(ctx context.Context, key Key) (err error) {
+	groupId, err := ri.cl.HGet(ctx, spaceGroupsKey, key.SpaceId).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return
+	}
+	if groupId == key.GroupId {
+		return
+	}
+	ok, err := ri.SpaceTransfer(ctx, Key{GroupId: groupId, SpaceId: key.SpaceId}, key.GroupId)
+	if err != nil {
+		return
+	}
+	if ok {
+		log.InfoCtx(ctx, "space transferred to the new owner", zap.String("spaceId", key.SpaceId), zap.String("from", groupId), zap.String("to", key.GroupId))
+	}
+	return
+}
+
+// SpaceTransfer moves the space with all its files from the group of the key to the given group.
+// Cid refs of the space are added to the new group and then removed from the old one, an isolated space returns its limit to the old group and shares the limit of the new group.
+// Every step is one transaction and the transfer in progress is recorded, so a failed transfer is finished by the next call
+func (ri *redisIndex) SpaceTransfer(ctx context.Context, key Key, groupId string) (ok bool, err error) {
+	if key.GroupId == groupId {
+		return false, nil
+	}
+	newKey := Key{GroupId: groupId, SpaceId: key.SpaceId}
+	entry, target, release, err := ri.acquireTransfer(ctx, key, newKey)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	inProgress, err := ri.spaceTransferInProgress(ctx, newKey)
+	if err != nil {
+		return
+	}
+	if !entry.spaceExists {
+		if target.spaceExists {
+			// only the map of groups is left to update, or it points to a removed space
+			return inProgress, ri.finishSpaceTransfer(ctx, key, newKey)
+		}
+		return false, nil
+	}
+	if entry.space.Status == indexproto.SpaceStatus_SpaceStatusDeleted {
+		return false, ErrSpaceDeleted
+	}
+	if target.spaceExists && !inProgress {
+		return false, ErrSpaceExists
+	}
+
+	op := &spaceLimitOp{redisIndex: ri}
+	cids, cidRefs, err := op.spaceCidRefs(ctx, key)
+	if err != nil {
+		return
+	}
+	// TODO: we don't need to take a lock here, but for now it easiest way
+	cidEntries, err := ri.CidEntries(ctx, cids)
+	if err != nil {
+		return
+	}
+	defer cidEntries.Release()
+
+	var (
+		spaceSize   = int64(entry.space.Size_)
+		before      = entry.sizes()
+		targetSizes = target.sizes()
+	)
+	if err = ri.markUsageChanged(ctx, Key{GroupId: key.GroupId}, newKey); err != nil {
+		return
+	}
+	if !target.spaceExists {
+		if err = ri.cl.HSet(ctx, spaceTransfersKey, key.SpaceId, groupId).Err(); err != nil {
+			return
+		}
+		if err = ri.transferToGroup(ctx, key, entry, target, cids, cidRefs, cidEntries); err != nil {
+			return
+		}
+	}
+	if err = ri.transferFromGroup(ctx, key, entry, cids, cidRefs, cidEntries); err != nil {
+		return
+	}
+	if err = ri.finishSpaceTransfer(ctx, key, newKey); err != nil {
+		return
+	}
+
+	ri.audit(ctx, &indexproto.AuditEntry{
+		Op:             AuditOpSpaceTransfer,
+		GroupId:        key.GroupId,
+		SpaceId:        key.SpaceId,
+		SpaceSizeDelta: -spaceSize,
+		GroupSizeDelta: int64(entry.group.Size_) - int64(before.group),
+	})
+	ri.audit(ctx, &indexproto.AuditEntry{
+		Op:             AuditOpSpaceTransfer,
+		GroupId:        groupId,
+		SpaceId:        key.SpaceId,
+		SpaceSizeDelta: spaceSize,
+		GroupSizeDelta: int64(target.group.Size_) - int64(targetSizes.group),
+	})
+	return true, nil
+}
+
+// acquireTransfer locks the space in both groups, groups are locked in the order of their keys, so opposite transfers don't deadlock
+func (ri *redisIndex) acquireTransfer(ctx context.Context, key, newKey Key) (entry, target groupSpaceEntry, release func(), err error) {
+	first, second := key, newKey
+	if groupKey(newKey) < groupKey(key) {
+		first, second = newKey, key
+	}
+	firstEntry, firstRelease, err := ri.AcquireSpace(ctx, first)
+	if err != nil {
+		return
+	}
+	secondEntry, secondRelease, err := ri.AcquireSpace(ctx, second)
+	if err != nil {
+		firstRelease()
+		return
+	}
+	release = func() {
+		secondRelease()
+		firstRelease()
+	}
+	if first == key {
+		return firstEntry, secondEntry, release, nil
+	}
+	return secondEntry, firstEntry, release, nil
+}
+
+func (ri *redisIndex) spaceTransferInProgress(ctx context.Context, newKey Key) (ok bool, err error) {
+	groupId, err := ri.cl.HGet(ctx, spaceTransfersKey, newKey.SpaceId).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return
+	}
+	return groupId == newKey.GroupId, nil
+}
+
+// transferToGroup copies the space to the new group and adds its cid refs to the group in one transaction,
+// the space and the group keys of the new group are in the same slot
+func (ri *redisIndex) transferToGroup(ctx context.Context, key Key, entry, target groupSpaceEntry, cids []cid.Cid, cidRefs []int64, cidEntries *CidEntries) (err error) {
+	newKey := Key{GroupId: target.group.GroupId, SpaceId: key.SpaceId}
+	gk := groupKey(newKey)
+	// keys of the groups can be in different slots, so the space is copied with dump and restore
+	dump, err := ri.cl.Dump(ctx, spaceKey(key)).Result()
+	if err != nil {
+		return
+	}
+	groupRefs, err := ri.groupCidRefs(ctx, gk, cids)
+	if err != nil {
+		return
+	}
+	// cids new for the group increase its size
+	for i := range cids {
+		if groupRefs[i] <= 0 {
+			target.group.Size_ += cidEntries.entries[i].Size_
+			target.group.CidCount++
+		}
+	}
+	// the old space entry is still needed to remove the space from the old group
+	spaceCopy := *entry.space.SpaceEntry
+	space := &spaceEntry{SpaceEntry: &spaceCopy, Id: key.SpaceId}
+	space.GroupId = newKey.GroupId
+	// the space shares the limit of the new group
+	space.Limit = 0
+	target.group.AddSpaceId(key.SpaceId)
+	_, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.RestoreReplace(ctx, spaceKey(newKey), 0, dump)
+		for i, c := range cids {
+			tx.HIncrBy(ctx, gk, cidKey(c), cidRefs[i])
+		}
+		space.Save(ctx, newKey, tx)
+		target.group.Save(ctx, tx)
+		return nil
+	})
+	return
+}
+
+// transferFromGroup removes the space with its cid refs from the old group in one transaction
+func (ri *redisIndex) transferFromGroup(ctx context.Context, key Key, entry groupSpaceEntry, cids []cid.Cid, cidRefs []int64, cidEntries *CidEntries) (err error) {
+	gk := groupKey(key)
+	var (
+		groupRemoveKeys []string
+		groupDecr       = make(map[string]int64)
+	)
+	if entry.space.Limit != 0 {
+		// the isolated space doesn't count cids in the group, just return the limit
+		entry.group.Limit += entry.space.Limit
+	} else {
+		groupRefs, rErr := ri.groupCidRefs(ctx, gk, cids)
+		if rErr != nil {
+			return rErr
+		}
+		for i, c := range cids {
+			if groupRefs[i] <= cidRefs[i] {
+				groupRemoveKeys = append(groupRemoveKeys, cidKey(c))
+				entry.group.Size_ -= cidEntries.entries[i].Size_
+				entry.group.CidCount--
+			} else {
+				groupDecr[cidKey(c)] = cidRefs[i]
+			}
+		}
+	}
+	entry.group.SpaceIds = slices.DeleteFunc(entry.group.SpaceIds, func(spaceId string) bool {
+		return spaceId == key.SpaceId
+	})
+	_, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		if len(groupRemoveKeys) != 0 {
+			tx.HDel(ctx, gk, groupRemoveKeys...)
+		}
+		for ck, refs := range groupDecr {
+			tx.HIncrBy(ctx, gk, ck, -refs)
+		}
+		entry.group.Save(ctx, tx)
+		tx.Del(ctx, spaceKey(key))
+		return nil
+	})
+	return
+}
+
+// finishSpaceTransfer points the space to the new group and removes the old space key from the persistent store
+func (ri *redisIndex) finishSpaceTransfer(ctx context.Context, key, newKey Key) (err error) {
+	if _, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.HSet(ctx, spaceGroupsKey, key.SpaceId, newKey.GroupId)
+		tx.HDel(ctx, spaceTransfersKey, key.SpaceId)
+		return nil
+	}); err != nil {
+		return
+	}
+	// the old key could be persisted before, remove it to not restore it again
+	if err = ri.persistStore.IndexDelete(ctx, spaceKey(key)); err != nil {
+		log.WarnCtx(ctx, "can't remove the persisted space key", zap.String("spaceId", key.SpaceId), zap.Error(err))
+	}
+	return nil
+}
+
+// groupCidRefs returns refs of the cids in the group, a missing cid has no refs
+func (ri *redisIndex) groupCidRefs(ctx context.Context, gk string, cids []cid.Cid) (refs []int64, err error) {
+	if len(cids) == 0 {
+		return
+	}
+	fields := make([]string, len(cids))
+	for i, c := range cids {
+		fields[i] = cidKey(c)
+	}
+	values, err := ri.cl.HMGet(ctx, gk, fields...).Result()
+	if err != nil {
+		return
+	}
+	refs = make([]int64, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			refs[i], _ = strconv.ParseInt(s, 10, 64)
+		}
+	}
+	return
+}
+
+// BackfillSpaceGroups remembers groups of spaces that were not changed since the map of groups was added, so they can be transferred too.
+// Spaces are found in the queues of keys to persist and in the persistent store, it's done once for the index
+func (ri *redisIndex) BackfillSpaceGroups(ctx context.Context) (err error) {
+	mu := ri.redsync.NewMutex("_lock:spaceGroupsBackfill", redsync.WithExpiry(time.Hour))
+	if err = mu.TryLockContext(ctx); err != nil {
+		// another node does it
+		return nil
+	}
+	defer func() {
+		_, _ = mu.Unlock()
+	}()
+	done, err := ri.cl.Exists(ctx, spaceGroupsBackfilledKey).Result()
+	if err != nil || done > 0 {
+		return
+	}
+
+	st := time.Now()
+	var count int
+	backfill := func(sk string) error {
+		ok, bErr := ri.backfillSpaceGroup(ctx, sk)
+		if ok {
+			count++
+		}
+		return bErr
+	}
+	for part := 0; part < partitionCount; part++ {
+		keys, zErr := ri.cl.ZRange(ctx, "store:{"+strconv.Itoa(part)+"}", 0, -1).Result()
+		if zErr != nil {
+			return zErr
+		}
+		for _, k := range keys {
+			if !strings.HasPrefix(k, "s:") {
+				continue
+			}
+			if err = backfill(k); err != nil {
+				return
+			}
+		}
+	}
+	if err = ri.persistStore.IndexList(ctx, "s:", backfill); err != nil {
+		return
+	}
+	if err = ri.cl.Set(ctx, spaceGroupsBackfilledKey, time.Now().Unix(), 0).Err(); err != nil {
+		return
+	}
+	log.InfoCtx(ctx, "space groups backfilled", zap.Int("count", count), zap.Duration("dur", time.Since(st)))
+	return
+}
+
+// backfillSpaceGroup remembers the group of the space key if the space isn't known yet
+func (ri *redisIndex) backfillSpaceGroup(ctx context.Context, sk string) (ok bool, err error) {
+	spaceId, _, found := strings.Cut(strings.TrimPrefix(sk, "s:"), ".{")
+	if !found {
+		return
+	}
+	if known, hErr := ri.cl.HExists(ctx, spaceGroupsKey, spaceId).Result(); hErr != nil || known {
+		return false, hErr
+	}
+	exists, release, err := ri.AcquireKey(ctx, sk)
+	if err != nil {
+		return
+	}
+	defer release()
+	if !exists {
+		return
+	}
+	data, err := ri.cl.HGet(ctx, sk, infoKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return
+	}
+	space := &indexproto.SpaceEntry{}
+	if err = space.Unmarshal([]byte(data)); err != nil {
+		return
+	}
+	if spaceKey(Key{GroupId: space.GroupId, SpaceId: spaceId}) != sk {
+		log.WarnCtx(ctx, "space key doesn't match the group", zap.String("key", sk), zap.String("groupId", space.GroupId))
+		return false, nil
+	}
+	// a space saved in the meantime is already in the map with the actual group
+	return ri.cl.HSetNX(ctx, spaceGroupsKey, spaceId, space.GroupId).Result()
+}
@@ -0,0 +1,219 @@
+package index
+
+import (
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+func TestRedisIndex_SpaceTransfer(t *testing.T) {
+	t.Run("owner changed", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		fx.persistStore.EXPECT().IndexDelete(ctx, gomock.Any())
+
+		bs := testutil.NewRandBlocks(3)
+		require.NoError(t, fx.BlocksAdd(ctx, bs))
+		key := newRandKey()
+		otherKey := Key{GroupId: key.GroupId, SpaceId: newRandKey().SpaceId}
+		newKey := Key{GroupId: newRandKey().GroupId, SpaceId: key.SpaceId}
+
+		// the first block is shared with another space of the group, the second one is bound by two files
+		bind := func(k Key, bs ...blocks.Block) {
+			cids, err := fx.CidEntriesByBlocks(ctx, bs)
+			require.NoError(t, err)
+			require.NoError(t, fx.FileBind(ctx, k, testutil.NewRandCid().String(), cids))
+			cids.Release()
+		}
+		bind(otherKey, bs[0])
+		bind(key, bs...)
+		bind(key, bs[1])
+		spaceInfo, err := fx.SpaceInfo(ctx, key)
+		require.NoError(t, err)
+
+		require.NoError(t, fx.CheckSpaceGroup(ctx, key))
+		require.NoError(t, fx.CheckSpaceGroup(ctx, newKey))
+
+		groupInfo, err := fx.GroupInfo(ctx, key.GroupId)
+		require.NoError(t, err)
+		assert.Equal(t, []string{otherKey.SpaceId}, groupInfo.SpaceIds)
+		assert.Equal(t, uint64(1), groupInfo.CidsCount)
+		assert.Equal(t, uint64(len(bs[0].RawData())), groupInfo.BytesUsage)
+
+		newGroupInfo, err := fx.GroupInfo(ctx, newKey.GroupId)
+		require.NoError(t, err)
+		assert.Equal(t, []string{key.SpaceId}, newGroupInfo.SpaceIds)
+		assert.Equal(t, spaceInfo.BytesUsage, newGroupInfo.BytesUsage)
+		assert.Equal(t, spaceInfo.CidsCount, newGroupInfo.CidsCount)
+
+		newSpaceInfo, err := fx.SpaceInfo(ctx, newKey)
+		require.NoError(t, err)
+		assert.Equal(t, spaceInfo, newSpaceInfo)
+		oldSpaceInfo, err := fx.SpaceInfo(ctx, key)
+		require.NoError(t, err)
+		assert.Zero(t, oldSpaceInfo.FileCount)
+		assert.Zero(t, oldSpaceInfo.BytesUsage)
+
+		// the cid bound by two files stays in the new group after one of them is unbound
+		fileIds, err := fx.FilesList(ctx, newKey)
+		require.NoError(t, err)
+		require.Len(t, fileIds, 2)
+		fileInfo, err := fx.FileInfo(ctx, newKey, fileIds...)
+		require.NoError(t, err)
+		for i, fi := range fileInfo {
+			if fi.CidsCount == 1 {
+				require.NoError(t, fx.FileUnbind(ctx, newKey, fileIds[i]))
+			}
+		}
+		newGroupInfo, err = fx.GroupInfo(ctx, newKey.GroupId)
+		require.NoError(t, err)
+		assert.Equal(t, spaceInfo.BytesUsage, newGroupInfo.BytesUsage)
+	})
+	t.Run("resume after the new group", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		fx.persistStore.EXPECT().IndexDelete(ctx, gomock.Any())
+		tr := newTestTransfer(t, fx)
+
+		// the transfer is interrupted before the space is removed from the old group
+		tr.run(t, false)
+		ok, err := fx.SpaceTransfer(ctx, tr.key, tr.newKey.GroupId)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		tr.check(t)
+	})
+	t.Run("resume after the old group", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		fx.persistStore.EXPECT().IndexDelete(ctx, gomock.Any())
+		tr := newTestTransfer(t, fx)
+
+		// the transfer is interrupted before the map of groups is updated
+		tr.run(t, true)
+		groupId, err := fx.cl.HGet(ctx, spaceGroupsKey, tr.key.SpaceId).Result()
+		require.NoError(t, err)
+		assert.Equal(t, tr.key.GroupId, groupId)
+
+		require.NoError(t, fx.CheckSpaceGroup(ctx, tr.newKey))
+		groupId, err = fx.cl.HGet(ctx, spaceGroupsKey, tr.key.SpaceId).Result()
+		require.NoError(t, err)
+		assert.Equal(t, tr.newKey.GroupId, groupId)
+		inProgress, err := fx.spaceTransferInProgress(ctx, tr.newKey)
+		require.NoError(t, err)
+		assert.False(t, inProgress)
+		tr.check(t)
+	})
+	t.Run("same group", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		key := newRandKey()
+		ok, err := fx.SpaceTransfer(ctx, key, key.GroupId)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("target exists", func(t *testing.T) {
+		fx := newFixture(t)
+		defer fx.Finish(t)
+		bs := testutil.NewRandBlocks(1)
+		require.NoError(t, fx.BlocksAdd(ctx, bs))
+		key := newRandKey()
+		newKey := Key{GroupId: newRandKey().GroupId, SpaceId: key.SpaceId}
+		for _, k := range []Key{key, newKey} {
+			cids, err := fx.CidEntriesByBlocks(ctx, bs)
+			require.NoError(t, err)
+			require.NoError(t, fx.FileBind(ctx, k, testutil.NewRandCid().String(), cids))
+			cids.Release()
+		}
+		_, err := fx.SpaceTransfer(ctx, key, newKey.GroupId)
+		assert.ErrorIs(t, err, ErrSpaceExists)
+	})
+}
+
+type testTransfer struct {
+	fx        *fixture
+	bs        []blocks.Block
+	key       Key
+	otherKey  Key
+	newKey    Key
+	spaceInfo SpaceInfo
+}
+
+// newTestTransfer binds files to the space, the first block is shared with another space of the group
+func newTestTransfer(t *testing.T, fx *fixture) *testTransfer {
+	tr := &testTransfer{fx: fx, bs: testutil.NewRandBlocks(3), key: newRandKey()}
+	require.NoError(t, fx.BlocksAdd(ctx, tr.bs))
+	tr.otherKey = Key{GroupId: tr.key.GroupId, SpaceId: newRandKey().SpaceId}
+	tr.newKey = Key{GroupId: newRandKey().GroupId, SpaceId: tr.key.SpaceId}
+	bind := func(k Key, bs ...blocks.Block) {
+		cids, err := fx.CidEntriesByBlocks(ctx, bs)
+		require.NoError(t, err)
+		require.NoError(t, fx.FileBind(ctx, k, testutil.NewRandCid().String(), cids))
+		cids.Release()
+	}
+	bind(tr.otherKey, tr.bs[0])
+	bind(tr.key, tr.bs...)
+	var err error
+	tr.spaceInfo, err = fx.SpaceInfo(ctx, tr.key)
+	require.NoError(t, err)
+	return tr
+}
+
+// run makes the first steps of the transfer like SpaceTransfer does
+func (tr *testTransfer) run(t *testing.T, fromGroup bool) {
+	entry, target, release, err := tr.fx.acquireTransfer(ctx, tr.key, tr.newKey)
+	require.NoError(t, err)
+	defer release()
+	op := &spaceLimitOp{redisIndex: tr.fx.redisIndex}
+	cids, cidRefs, err := op.spaceCidRefs(ctx, tr.key)
+	require.NoError(t, err)
+	cidEntries, err := tr.fx.CidEntries(ctx, cids)
+	require.NoError(t, err)
+	defer cidEntries.Release()
+	require.NoError(t, tr.fx.cl.HSet(ctx, spaceTransfersKey, tr.key.SpaceId, tr.newKey.GroupId).Err())
+	require.NoError(t, tr.fx.transferToGroup(ctx, tr.key, entry, target, cids, cidRefs, cidEntries))
+	if fromGroup {
+		require.NoError(t, tr.fx.transferFromGroup(ctx, tr.key, entry, cids, cidRefs, cidEntries))
+	}
+}
+
+func (tr *testTransfer) check(t *testing.T) {
+	groupInfo, err := tr.fx.GroupInfo(ctx, tr.key.GroupId)
+	require.NoError(t, err)
+	assert.Equal(t, []string{tr.otherKey.SpaceId}, groupInfo.SpaceIds)
+	assert.Equal(t, uint64(1), groupInfo.CidsCount)
+	assert.Equal(t, uint64(len(tr.bs[0].RawData())), groupInfo.BytesUsage)
+
+	newGroupInfo, err := tr.fx.GroupInfo(ctx, tr.newKey.GroupId)
+	require.NoError(t, err)
+	assert.Equal(t, []string{tr.key.SpaceId}, newGroupInfo.SpaceIds)
+	assert.Equal(t, tr.spaceInfo.BytesUsage, newGroupInfo.BytesUsage)
+	assert.Equal(t, tr.spaceInfo.CidsCount, newGroupInfo.CidsCount)
+}
+
+func TestRedisIndex_BackfillSpaceGroups(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+	<-fx.backfillDone
+
+	bs := testutil.NewRandBlocks(1)
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+	key := newRandKey()
+	cids, err := fx.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(ctx, key, "fileId", cids))
+	cids.Release()
+
+	// the space was stored before the map of groups
+	require.NoError(t, fx.cl.HDel(ctx, spaceGroupsKey, key.SpaceId).Err())
+	require.NoError(t, fx.cl.Del(ctx, spaceGroupsBackfilledKey).Err())
+
+	require.NoError(t, fx.BackfillSpaceGroups(ctx))
+	groupId, err := fx.cl.HGet(ctx, spaceGroupsKey, key.SpaceId).Result()
+	require.NoError(t, err)
+	assert.Equal(t, key.GroupId, groupId)
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/events"
 )
 
 func (ri *redisIndex) FileUnbind(ctx context.Context, key Key, fileIds ...string) (err error) {
@@ -13,15 +15,22 @@ func (ri *redisIndex) FileUnbind(ctx context.Context, key Key, fileIds ...string
 		return
 	}
 	defer release()
-	for _, fileId := range fileIds {
-		if err = ri.fileUnbind(ctx, key, entry, fileId); err != nil {
+	before := entry.sizes()
+	for i, fileId := range fileIds {
+		if err = ri.fileUnbind(ctx, key, entry, fileId, nil); err != nil {
+			// record the files unbound before the error
+			if i != 0 {
+				ri.auditSpaceChange(ctx, AuditOpFileUnbind, key, entry, before, fileIds[:i]...)
+			}
 			return
 		}
 	}
+	ri.auditSpaceChange(ctx, AuditOpFileUnbind, key, entry, before, fileIds...)
 	return
 }
 
-func (ri *redisIndex) fileUnbind(ctx context.Context, key Key, entry groupSpaceEntry, fileId string) (err error) {
+// fileUnbind removes the file from the space, when purge is not nil, blocks that are not referenced anymore are removed from the store
+func (ri *redisIndex) fileUnbind(ctx context.Context, key Key, entry groupSpaceEntry, fileId string, purge *purgeStat) (err error) {
 	var (
 		sk = spaceKey(key)
 		gk = groupKey(key)
@@ -102,6 +111,22 @@ func (ri *redisIndex) fileUnbind(ctx context.Context, key Key, entry groupSpaceE
 		}
 	}
 
+	// the usage only goes down here, so the warn level can be lowered to report the next crossing
+	ri.updateQuotaWarnLevel(entry.group)
+
+	if err = ri.markUsageChanged(ctx, key); err != nil {
+		return
+	}
+	outbox, err := ri.newEvents(ctx, key.GroupId, events.Event{
+		Type:     events.TypeFileUnbound,
+		SpaceId:  key.SpaceId,
+		FileId:   fileId,
+		Size:     fileInfo.Size_,
+		CidCount: uint64(len(fileInfo.Cids)),
+	})
+	if err != nil {
+		return
+	}
 	// do updates in one tx
 	_, err = ri.cl.TxPipelined(ctx, func(tx redis.Pipeliner) error {
 		tx.HDel(ctx, sk, fileKey(fileId))
@@ -123,15 +148,29 @@ func (ri *redisIndex) fileUnbind(ctx context.Context, key Key, entry groupSpaceE
 		}
 		entry.space.Save(ctx, key, tx)
 		entry.group.Save(ctx, tx)
+		outbox.write(ctx, tx)
 		return nil
 	})
+	if err == nil {
+		ri.publishEvents(ctx, outbox)
+	}
 
 	// update cids
+	var unreferenced []*cidEntry
 	for _, idx := range affectedCidIdx {
 		cids.entries[idx].Refs--
+		if purge != nil && cids.entries[idx].Refs <= 0 {
+			unreferenced = append(unreferenced, cids.entries[idx])
+			continue
+		}
 		if saveErr := cids.entries[idx].Save(ctx, ri.cl); saveErr != nil {
 			log.WarnCtx(ctx, "unable to save cid info", zap.Error(saveErr), zap.String("cid", cids.entries[idx].Cid.String()))
 		}
 	}
+	if purge != nil {
+		purge.fileCount++
+		// cids are still locked here, so nobody can bind them until they are removed
+		return ri.purgeUnreferenced(ctx, unreferenced, purge)
+	}
 	return
 }
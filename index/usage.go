@@ -0,0 +1,267 @@
+package index
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+)
+
+const (
+	usageSnapshotPeriodSec = 3600
+	usageDirtyKey          = "usage:dirty"
+)
+
+// UsageSnapshot is a usage of a group or a space at the end of the day, SpaceId is empty for groups
+type UsageSnapshot struct {
+	GroupId   string
+	SpaceId   string
+	Time      time.Time
+	Size      uint64
+	CidCount  uint64
+	FileCount uint32
+}
+
+/*
+	Usage keys:
+		usage:dirty: set of groups and spaces changed since the last snapshot
+			g:{groupId}
+			s:{groupId}/{spaceId}
+		usage:g:{groupId}: map, persisted like other index keys
+			{day} -> proto(UsageSnapshot)
+		usage:s:{groupId}/{spaceId}: map
+*/
+
+func usageGroupMember(groupId string) string {
+	return "g:" + groupId
+}
+
+func usageSpaceMember(k Key) string {
+	return "s:" + k.GroupId + "/" + k.SpaceId
+}
+
+func usageKey(member string) string {
+	return "usage:" + member
+}
+
+// markUsageChanged adds the groups and the spaces of the keys to the next snapshot, a key without a space id marks only the group.
+// It's called before the change and outside of its transaction, the set is in another slot and an extra snapshot of the same usage is harmless
+func (ri *redisIndex) markUsageChanged(ctx context.Context, keys ...Key) (err error) {
+	members := make([]any, 0, len(keys)*2)
+	for _, k := range keys {
+		members = append(members, usageGroupMember(k.GroupId))
+		if k.SpaceId != "" {
+			members = append(members, usageSpaceMember(k))
+		}
+	}
+	return ri.cl.SAdd(ctx, usageDirtyKey, members...).Err()
+}
+
+// markSpaceChanged marks the usage of the space as changed and remembers the group storing the space, it's called before the space is saved
+func (ri *redisIndex) markSpaceChanged(ctx context.Context, key Key) (err error) {
+	_, err = ri.cl.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, usageDirtyKey, usageGroupMember(key.GroupId), usageSpaceMember(key))
+		pipe.HSet(ctx, spaceGroupsKey, key.SpaceId, key.GroupId)
+		return nil
+	})
+	return
+}
+
+// SnapshotUsage writes today's usage snapshot of every group and space changed since the last run
+func (ri *redisIndex) SnapshotUsage(ctx context.Context) (err error) {
+	mu := ri.redsync.NewMutex("_lock:usageSnapshot", redsync.WithExpiry(time.Minute*10))
+	if err = mu.LockContext(ctx); err != nil {
+		return
+	}
+	defer func() {
+		_, _ = mu.Unlock()
+	}()
+
+	st := time.Now()
+	var count int
+	day := time.Now().UTC().Format(bandwidthDayLayout)
+	for {
+		members, pErr := ri.cl.SPopN(ctx, usageDirtyKey, 100).Result()
+		if pErr != nil {
+			return pErr
+		}
+		if len(members) == 0 {
+			break
+		}
+		for i, member := range members {
+			if err = ri.snapshotUsage(ctx, member, day); err != nil {
+				// return the rest back, they will be processed next time
+				rest := make([]any, 0, len(members)-i)
+				for _, m := range members[i:] {
+					rest = append(rest, m)
+				}
+				if aErr := ri.cl.SAdd(ctx, usageDirtyKey, rest...).Err(); aErr != nil {
+					log.WarnCtx(ctx, "can't return usage members", zap.Error(aErr))
+				}
+				return
+			}
+			count++
+		}
+		if _, err = mu.ExtendContext(ctx); err != nil {
+			return
+		}
+	}
+	log.InfoCtx(ctx, "usage snapshot", zap.Int("count", count), zap.Duration("dur", time.Since(st)))
+	return
+}
+
+func (ri *redisIndex) snapshotUsage(ctx context.Context, member, day string) (err error) {
+	snapshot, err := ri.currentUsage(ctx, member)
+	if err != nil {
+		return
+	}
+	data, err := snapshot.Marshal()
+	if err != nil {
+		return
+	}
+	uk := usageKey(member)
+	_, release, err := ri.AcquireKey(ctx, uk)
+	if err != nil {
+		return
+	}
+	defer release()
+	if err = ri.cl.HSet(ctx, uk, day, data).Err(); err != nil {
+		return
+	}
+	return ri.trimUsage(ctx, uk)
+}
+
+func (ri *redisIndex) currentUsage(ctx context.Context, member string) (snapshot *indexproto.UsageSnapshot, err error) {
+	kind, id, _ := strings.Cut(member, ":")
+	if kind == "s" {
+		groupId, spaceId, _ := strings.Cut(id, "/")
+		return ri.spaceUsage(ctx, Key{GroupId: groupId, SpaceId: spaceId})
+	}
+
+	key := Key{GroupId: id}
+	_, release, err := ri.AcquireKey(ctx, groupKey(key))
+	if err != nil {
+		return
+	}
+	gEntry, err := ri.getGroupEntry(ctx, key)
+	release()
+	if err != nil {
+		return
+	}
+	snapshot = &indexproto.UsageSnapshot{
+		Size_:    gEntry.Size_,
+		CidCount: gEntry.CidCount,
+	}
+	// the group doesn't count files, so sum them up from its spaces
+	for _, spaceId := range gEntry.SpaceIds {
+		sSnapshot, sErr := ri.spaceUsage(ctx, Key{GroupId: key.GroupId, SpaceId: spaceId})
+		if sErr != nil {
+			return nil, sErr
+		}
+		snapshot.FileCount += sSnapshot.FileCount
+	}
+	return
+}
+
+func (ri *redisIndex) spaceUsage(ctx context.Context, key Key) (snapshot *indexproto.UsageSnapshot, err error) {
+	_, release, err := ri.AcquireKey(ctx, spaceKey(key))
+	if err != nil {
+		return
+	}
+	defer release()
+	sEntry, err := ri.getSpaceEntry(ctx, key)
+	if err != nil {
+		return
+	}
+	return &indexproto.UsageSnapshot{
+		Size_:     sEntry.Size_,
+		CidCount:  sEntry.CidCount,
+		FileCount: sEntry.FileCount,
+	}, nil
+}
+
+// trimUsage removes snapshots older than the history, the latest snapshot is always kept
+func (ri *redisIndex) trimUsage(ctx context.Context, uk string) (err error) {
+	historyDays := int(ri.usageHistoryDays.Load())
+	if historyDays <= 0 {
+		return
+	}
+	days, err := ri.cl.HKeys(ctx, uk).Result()
+	if err != nil {
+		return
+	}
+	sort.Strings(days)
+	border := time.Now().UTC().AddDate(0, 0, -historyDays).Format(bandwidthDayLayout)
+	var toDelete []string
+	for _, day := range days[:len(days)-1] {
+		if day < border {
+			toDelete = append(toDelete, day)
+		}
+	}
+	if len(toDelete) == 0 {
+		return
+	}
+	return ri.cl.HDel(ctx, uk, toDelete...).Err()
+}
+
+// UsageHistory returns the daily usage of the space for the last days, the group usage is returned when the space id is empty.
+// Days without a snapshot have the usage of the previous snapshot, days before the first snapshot are skipped
+func (ri *redisIndex) UsageHistory(ctx context.Context, key Key, days int) (snapshots []UsageSnapshot, err error) {
+	member := usageGroupMember(key.GroupId)
+	if key.SpaceId != "" {
+		member = usageSpaceMember(key)
+	}
+	uk := usageKey(member)
+	_, release, err := ri.AcquireKey(ctx, uk)
+	if err != nil {
+		return
+	}
+	res, err := ri.cl.HGetAll(ctx, uk).Result()
+	release()
+	if err != nil {
+		return
+	}
+
+	recorded := make([]string, 0, len(res))
+	for day := range res {
+		recorded = append(recorded, day)
+	}
+	sort.Strings(recorded)
+
+	var (
+		last *indexproto.UsageSnapshot
+		idx  int
+	)
+	for _, day := range lastDays(days) {
+		for ; idx < len(recorded) && recorded[idx] <= day; idx++ {
+			s := &indexproto.UsageSnapshot{}
+			if uErr := s.Unmarshal([]byte(res[recorded[idx]])); uErr != nil {
+				log.WarnCtx(ctx, "invalid usage snapshot", zap.String("key", uk), zap.String("day", recorded[idx]), zap.Error(uErr))
+				continue
+			}
+			last = s
+		}
+		if last == nil {
+			continue
+		}
+		dayTime, pErr := time.Parse(bandwidthDayLayout, day)
+		if pErr != nil {
+			return nil, pErr
+		}
+		snapshots = append(snapshots, UsageSnapshot{
+			GroupId:   key.GroupId,
+			SpaceId:   key.SpaceId,
+			Time:      dayTime,
+			Size:      last.Size_,
+			CidCount:  last.CidCount,
+			FileCount: last.FileCount,
+		})
+	}
+	return
+}
@@ -0,0 +1,62 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/index/indexproto"
+	"github.com/anyproto/any-sync-filenode/testutil"
+)
+
+func TestRedisIndex_UsageHistory(t *testing.T) {
+	fx := newFixture(t)
+	defer fx.Finish(t)
+
+	key := newRandKey()
+	bs := testutil.NewRandBlocks(3)
+	require.NoError(t, fx.BlocksAdd(ctx, bs))
+	cids, err := fx.CidEntriesByBlocks(ctx, bs)
+	require.NoError(t, err)
+	require.NoError(t, fx.FileBind(ctx, key, "fileId", cids))
+	cids.Release()
+
+	// write an old snapshot, it must be carried forward until today
+	old := &indexproto.UsageSnapshot{Size_: 1, CidCount: 1, FileCount: 1}
+	data, err := old.Marshal()
+	require.NoError(t, err)
+	oldDay := time.Now().UTC().AddDate(0, 0, -3).Format(bandwidthDayLayout)
+	require.NoError(t, fx.cl.HSet(ctx, usageKey(usageSpaceMember(key)), oldDay, data).Err())
+
+	require.NoError(t, fx.SnapshotUsage(ctx))
+
+	t.Run("space", func(t *testing.T) {
+		snapshots, err := fx.UsageHistory(ctx, key, 5)
+		require.NoError(t, err)
+		require.Len(t, snapshots, 4)
+		assert.Equal(t, uint64(1), snapshots[0].Size)
+		assert.Equal(t, uint64(1), snapshots[2].Size)
+		last := snapshots[3]
+		assert.Equal(t, key.SpaceId, last.SpaceId)
+		assert.Equal(t, uint64(3), last.CidCount)
+		assert.Equal(t, uint32(1), last.FileCount)
+		assert.NotZero(t, last.Size)
+	})
+	t.Run("group", func(t *testing.T) {
+		snapshots, err := fx.UsageHistory(ctx, Key{GroupId: key.GroupId}, 5)
+		require.NoError(t, err)
+		require.Len(t, snapshots, 1)
+		assert.Equal(t, uint64(3), snapshots[0].CidCount)
+		assert.Equal(t, uint32(1), snapshots[0].FileCount)
+		assert.Empty(t, snapshots[0].SpaceId)
+	})
+	t.Run("trim", func(t *testing.T) {
+		fx.usageHistoryDays.Store(2)
+		require.NoError(t, fx.trimUsage(ctx, usageKey(usageSpaceMember(key))))
+		snapshots, err := fx.UsageHistory(ctx, key, 5)
+		require.NoError(t, err)
+		require.Len(t, snapshots, 1)
+	})
+}
@@ -0,0 +1,124 @@
+// Package metricserver serves prometheus metrics of the common metric component on an address that can be changed on config reload
+package metricserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/app/logger"
+	"github.com/anyproto/any-sync/metric"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const CName = "filenode.metricServer"
+
+var log = logger.NewNamed(CName)
+
+type configSource interface {
+	// GetMetricAddr returns the listen address of the metrics endpoint, the common metric component doesn't listen itself
+	GetMetricAddr() string
+}
+
+func New() MetricServer {
+	return new(metricServer)
+}
+
+type MetricServer interface {
+	// Addr returns the address the server listens on, it's empty when the server is disabled
+	Addr() string
+	app.ComponentRunnable
+}
+
+type metricServer struct {
+	mu       sync.Mutex
+	metric   metric.Metric
+	addr     string
+	server   *http.Server
+	listener net.Listener
+}
+
+func (ms *metricServer) Init(a *app.App) (err error) {
+	ms.metric = a.MustComponent(metric.CName).(metric.Metric)
+	ms.addr = a.MustComponent("config").(configSource).GetMetricAddr()
+	return
+}
+
+func (ms *metricServer) Name() (name string) {
+	return CName
+}
+
+func (ms *metricServer) Run(ctx context.Context) (err error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.server, ms.listener, err = ms.listen(ms.addr)
+	return
+}
+
+func (ms *metricServer) ReloadableFields() []string {
+	return []string{"metric"}
+}
+
+// Reload moves the server to the new address, the old listener is closed only when the new one is started
+func (ms *metricServer) Reload(ctx context.Context, conf app.Component) (err error) {
+	addr := conf.(configSource).GetMetricAddr()
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if addr == ms.addr {
+		return
+	}
+	server, listener, err := ms.listen(addr)
+	if err != nil {
+		return
+	}
+	if ms.server != nil {
+		if e := ms.server.Shutdown(ctx); e != nil {
+			log.Warn("can't stop the previous metric server", zap.Error(e))
+		}
+	}
+	ms.addr, ms.server, ms.listener = addr, server, listener
+	return
+}
+
+func (ms *metricServer) listen(addr string) (server *http.Server, listener net.Listener, err error) {
+	if addr == "" {
+		return
+	}
+	if listener, err = net.Listen("tcp", addr); err != nil {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(ms.metric.Registry(), promhttp.HandlerOpts{}))
+	// other paths are served by the default mux, e.g. /debug/pprof registered by the main package
+	mux.Handle("/", http.DefaultServeMux)
+	server = &http.Server{Handler: mux}
+	go func() {
+		if e := server.Serve(listener); e != nil && !errors.Is(e, http.ErrServerClosed) {
+			log.Error("metric server error", zap.Error(e))
+		}
+	}()
+	log.Info("metric server started", zap.String("addr", listener.Addr().String()))
+	return
+}
+
+func (ms *metricServer) Addr() string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.listener == nil {
+		return ""
+	}
+	return ms.listener.Addr().String()
+}
+
+func (ms *metricServer) Close(ctx context.Context) (err error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.server != nil {
+		return ms.server.Shutdown(ctx)
+	}
+	return
+}
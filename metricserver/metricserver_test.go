@@ -0,0 +1,76 @@
+package metricserver
+
+import (
+	"context"
+	"net/http"
+	_ "net/http/pprof"
+	"testing"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ctx = context.Background()
+
+func TestMetricServer_Reload(t *testing.T) {
+	conf := &testConfig{addr: "127.0.0.1:0"}
+	ms := New().(*metricServer)
+	a := new(app.App)
+	a.Register(conf).Register(metric.New()).Register(ms)
+	require.NoError(t, a.Start(ctx))
+	defer func() {
+		require.NoError(t, a.Close(ctx))
+	}()
+
+	addr := ms.Addr()
+	require.NotEmpty(t, addr)
+	assertServes(t, addr)
+
+	t.Run("same address", func(t *testing.T) {
+		require.NoError(t, ms.Reload(ctx, conf))
+		assert.Equal(t, addr, ms.Addr())
+	})
+	t.Run("new address", func(t *testing.T) {
+		require.NoError(t, ms.Reload(ctx, &testConfig{addr: "localhost:0"}))
+		newAddr := ms.Addr()
+		assert.NotEqual(t, addr, newAddr)
+		assertServes(t, newAddr)
+		_, err := http.Get("http://" + addr + "/metrics")
+		assert.Error(t, err)
+		addr = newAddr
+	})
+	t.Run("address in use", func(t *testing.T) {
+		// the server keeps listening on the previous address
+		require.Error(t, ms.Reload(ctx, &testConfig{addr: addr}))
+		assert.Equal(t, addr, ms.Addr())
+		assertServes(t, addr)
+	})
+	t.Run("default mux", func(t *testing.T) {
+		resp, err := http.Get("http://" + addr + "/debug/pprof/")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+	t.Run("disable", func(t *testing.T) {
+		require.NoError(t, ms.Reload(ctx, &testConfig{}))
+		assert.Empty(t, ms.Addr())
+	})
+}
+
+func assertServes(t *testing.T, addr string) {
+	resp, err := http.Get("http://" + addr + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+type testConfig struct {
+	addr string
+}
+
+func (c *testConfig) Init(a *app.App) (err error) { return }
+func (c *testConfig) Name() string                { return "config" }
+func (c *testConfig) GetMetric() metric.Config    { return metric.Config{} }
+func (c *testConfig) GetMetricAddr() string       { return c.addr }
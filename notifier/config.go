@@ -0,0 +1,29 @@
+package notifier
+
+import "time"
+
+type configSource interface {
+	GetNotifier() Config
+}
+
+type Config struct {
+	// WebhookConfig sets the url the events are posted to as json, events are only logged when it's empty
+	WebhookConfig `yaml:",inline"`
+	// QueueSize is a max count of events waiting for the delivery, new events are dropped when the queue is full
+	QueueSize int `yaml:"queueSize"`
+}
+
+// WebhookConfig configures the webhook sink, it's shared by components posting events
+type WebhookConfig struct {
+	WebhookUrl string `yaml:"webhookUrl"`
+	// TimeoutSec is a timeout of one delivery attempt, 10 seconds by default
+	TimeoutSec int `yaml:"timeoutSec"`
+}
+
+// Timeout returns the timeout of one delivery attempt
+func (c WebhookConfig) Timeout() time.Duration {
+	if c.TimeoutSec <= 0 {
+		return time.Second * defaultTimeoutSec
+	}
+	return time.Second * time.Duration(c.TimeoutSec)
+}
@@ -0,0 +1,150 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/app/logger"
+	"go.uber.org/zap"
+)
+
+const CName = "filenode.notifier"
+
+var log = logger.NewNamed(CName)
+
+const (
+	defaultQueueSize  = 1000
+	defaultTimeoutSec = 10
+	sendAttempts      = 3
+)
+
+const (
+	// EventQuotaWarning is sent when the group usage crosses one of the configured thresholds
+	EventQuotaWarning = "quotaWarning"
+)
+
+// Event is a message delivered to the sink
+type Event struct {
+	// Id is a unique id of the event, it's sent as the Idempotency-Key header, so the receiver can skip duplicates
+	Id      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	GroupId string `json:"groupId"`
+	SpaceId string `json:"spaceId,omitempty"`
+	FileId  string `json:"fileId,omitempty"`
+	// Threshold is a crossed usage threshold in percents
+	Threshold uint32    `json:"threshold,omitempty"`
+	Size      uint64    `json:"size,omitempty"`
+	CidCount  uint64    `json:"cidCount,omitempty"`
+	Limit     uint64    `json:"limit,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Sink delivers events, e.g. to a webhook or to the log
+type Sink interface {
+	Send(ctx context.Context, event Event) error
+}
+
+func New() Notifier {
+	return new(notifier)
+}
+
+// NewWithSink creates the notifier that delivers events to the given sink instead of the configured one
+func NewWithSink(sink Sink) Notifier {
+	return &notifier{sink: sink}
+}
+
+type Notifier interface {
+	// Notify queues the event for the delivery, it never blocks
+	Notify(event Event)
+	app.ComponentRunnable
+}
+
+type notifier struct {
+	sink    Sink
+	timeout time.Duration
+	queue   chan Event
+	done    chan struct{}
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+}
+
+func (n *notifier) Init(a *app.App) (err error) {
+	conf := a.MustComponent("config").(configSource).GetNotifier()
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = defaultQueueSize
+	}
+	n.timeout = conf.Timeout()
+	if n.sink == nil {
+		if conf.WebhookUrl != "" {
+			n.sink = NewWebhookSink(conf.WebhookUrl)
+		} else {
+			n.sink = NewLogSink()
+		}
+	}
+	n.queue = make(chan Event, conf.QueueSize)
+	n.done = make(chan struct{})
+	n.ctx, n.ctxCancel = context.WithCancel(context.Background())
+	return
+}
+
+func (n *notifier) Name() (name string) {
+	return CName
+}
+
+func (n *notifier) Run(ctx context.Context) (err error) {
+	go n.sendLoop()
+	return
+}
+
+func (n *notifier) Notify(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	select {
+	case n.queue <- event:
+	default:
+		log.Warn("notification queue is full, event dropped", zap.String("type", event.Type), zap.String("groupId", event.GroupId))
+	}
+}
+
+func (n *notifier) sendLoop() {
+	defer close(n.done)
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case event := <-n.queue:
+			n.send(event)
+		}
+	}
+}
+
+func (n *notifier) send(event Event) {
+	var err error
+	for i := 0; i < sendAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-time.After(time.Second * time.Duration(i)):
+			}
+		}
+		ctx, cancel := context.WithTimeout(n.ctx, n.timeout)
+		err = n.sink.Send(ctx, event)
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+	log.Warn("can't deliver the event", zap.String("type", event.Type), zap.String("groupId", event.GroupId), zap.Error(err))
+}
+
+func (n *notifier) Close(ctx context.Context) (err error) {
+	if n.ctxCancel != nil {
+		n.ctxCancel()
+		<-n.done
+	}
+	return
+}
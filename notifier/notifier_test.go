@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ctx = context.Background()
+
+func TestNotifier_Webhook(t *testing.T) {
+	t.Run("deliver", func(t *testing.T) {
+		events := make(chan Event, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event Event
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+			events <- event
+		}))
+		defer server.Close()
+
+		fx := newFixture(t, Config{WebhookConfig: WebhookConfig{WebhookUrl: server.URL}})
+		defer fx.finish(t)
+
+		fx.Notify(Event{Type: EventQuotaWarning, GroupId: "g1", Threshold: 80, Size: 80, Limit: 100})
+		select {
+		case event := <-events:
+			assert.Equal(t, EventQuotaWarning, event.Type)
+			assert.Equal(t, "g1", event.GroupId)
+			assert.Equal(t, uint32(80), event.Threshold)
+			assert.False(t, event.Time.IsZero())
+		case <-time.After(time.Second * 5):
+			t.Fatal("event is not delivered")
+		}
+	})
+	t.Run("retry", func(t *testing.T) {
+		var calls = make(chan struct{}, 2)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls <- struct{}{}
+			if len(calls) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+
+		fx := newFixture(t, Config{WebhookConfig: WebhookConfig{WebhookUrl: server.URL}})
+		defer fx.finish(t)
+
+		fx.Notify(Event{Type: EventQuotaWarning, GroupId: "g1"})
+		assert.Eventually(t, func() bool {
+			return len(calls) == 2
+		}, time.Second*5, time.Millisecond*10)
+	})
+}
+
+func TestNotifier_QueueFull(t *testing.T) {
+	// the send loop is not running, so the queue is not drained
+	n := &notifier{queue: make(chan Event, 1)}
+	n.Notify(Event{GroupId: "g1"})
+	n.Notify(Event{GroupId: "g2"})
+	require.Len(t, n.queue, 1)
+	assert.Equal(t, "g1", (<-n.queue).GroupId)
+}
+
+func newFixture(t *testing.T, conf Config) *fixture {
+	fx := &fixture{
+		notifier: New().(*notifier),
+		a:        new(app.App),
+	}
+	fx.a.Register(&testConfig{conf: conf})
+	fx.a.Register(fx.notifier)
+	require.NoError(t, fx.a.Start(ctx))
+	return fx
+}
+
+type fixture struct {
+	*notifier
+	a *app.App
+}
+
+func (fx *fixture) finish(t *testing.T) {
+	require.NoError(t, fx.a.Close(ctx))
+}
+
+type testConfig struct {
+	conf Config
+}
+
+func (c *testConfig) Init(a *app.App) (err error) { return }
+func (c *testConfig) Name() string                { return "config" }
+func (c *testConfig) GetNotifier() Config         { return c.conf }
+
+func TestWebhookSink_IdempotencyKey(t *testing.T) {
+	keys := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.Header.Get("Idempotency-Key")
+	}))
+	defer server.Close()
+
+	require.NoError(t, NewWebhookSink(server.URL).Send(ctx, Event{Id: "e1", Type: EventQuotaWarning}))
+	assert.Equal(t, "e1", <-keys)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	assert.Error(t, NewWebhookSink(failing.URL).Send(ctx, Event{Type: EventQuotaWarning}))
+}
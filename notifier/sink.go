@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// NewLogSink creates the sink that writes events to the log
+func NewLogSink() Sink {
+	return logSink{}
+}
+
+type logSink struct{}
+
+func (logSink) Send(ctx context.Context, event Event) error {
+	log.InfoCtx(ctx, "notification",
+		zap.String("type", event.Type),
+		zap.String("groupId", event.GroupId),
+		zap.String("spaceId", event.SpaceId),
+		zap.Uint32("threshold", event.Threshold),
+		zap.Uint64("size", event.Size),
+		zap.Uint64("limit", event.Limit),
+	)
+	return nil
+}
+
+// NewWebhookSink creates the sink that posts every event as json to the given url, the event id is sent as the Idempotency-Key header
+func NewWebhookSink(url string) Sink {
+	return &webhookSink{url: url, client: http.DefaultClient}
+}
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhookSink) Send(ctx context.Context, event Event) (err error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if event.Id != "" {
+		req.Header.Set("Idempotency-Key", event.Id)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return
+}
@@ -0,0 +1,24 @@
+package ratelimit
+
+type configSource interface {
+	GetRateLimit() Config
+}
+
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Read and Write are default budgets for read and write methods
+	Read  Limit `yaml:"read"`
+	Write Limit `yaml:"write"`
+	// Methods overrides budgets of the given methods, e.g. blockGet
+	Methods map[string]Limit `yaml:"methods"`
+}
+
+// Limit is a budget that is applied to every peer and every space separately
+type Limit struct {
+	// Rps is a rate of requests per second, zero means unlimited
+	Rps float64 `yaml:"rps"`
+	// Burst is a max count of requests at once, equals to rps by default
+	Burst int `yaml:"burst"`
+	// BytesPerSec limits the traffic of blockPush and blockGet, zero means unlimited
+	BytesPerSec int `yaml:"bytesPerSec"`
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/anyproto/any-sync/net/peer"
+)
+
+type ctxKey uint
+
+const ctxKeyRequest ctxKey = iota
+
+type ctxRequest struct {
+	method string
+	size   int
+}
+
+// AllowPeer takes the request of the method from the budget of the peer of the context and the size from its traffic budget,
+// pass zero size to only check that the traffic budget is not exhausted. It's called before the access to the space is checked,
+// the returned context carries the request, so AllowSpace charges the space after the check. The limiter can be nil
+func AllowPeer(ctx context.Context, rl RateLimiter, method string, size int) (context.Context, error) {
+	if rl == nil {
+		return ctx, nil
+	}
+	peerId, _ := peer.CtxPeerId(ctx)
+	if err := rl.Allow(ctx, method, peerId, ""); err != nil {
+		return ctx, err
+	}
+	if err := rl.AllowBytes(ctx, method, peerId, "", size); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, ctxKeyRequest, ctxRequest{method: method, size: size}), nil
+}
+
+// AllowSpace takes the request started by AllowPeer from the budgets of the space. It must be called only when the peer
+// has access to the space, so peers without access can't spend the budget of the space and deny service to its members
+func AllowSpace(ctx context.Context, rl RateLimiter, spaceId string) error {
+	req, ok := ctx.Value(ctxKeyRequest).(ctxRequest)
+	if rl == nil || !ok || spaceId == "" {
+		return nil
+	}
+	if err := rl.Allow(ctx, req.method, "", spaceId); err != nil {
+		return err
+	}
+	return rl.AllowBytes(ctx, req.method, "", spaceId, req.size)
+}
+
+// TakeBytes takes the size of the data that is already read from the traffic budgets of the peer and the space,
+// so it limits the next requests. Pass an empty space id when the access to the space is not checked
+func TakeBytes(ctx context.Context, rl RateLimiter, method, spaceId string, size int) {
+	if rl == nil {
+		return
+	}
+	peerId, _ := peer.CtxPeerId(ctx)
+	_ = rl.AllowBytes(ctx, method, peerId, spaceId, size)
+}
@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/app/logger"
+	"github.com/anyproto/any-sync/commonfile/fileproto"
+	"github.com/anyproto/any-sync/net/rpc/rpcerr"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/anyproto/any-sync-filenode/redisprovider"
+)
+
+const CName = "filenode.rateLimit"
+
+var log = logger.NewNamed(CName)
+
+var ErrRateLimited = rpcerr.ErrGroup(fileproto.ErrCodes_ErrorOffset).Register(errors.New("rate limit exceeded"), 50)
+
+// Methods that don't change the data, all others are limited by the write budget
+var readMethods = map[string]bool{
+	"blockGet":    true,
+	"blockUrls":   true,
+	"blocksCheck": true,
+	"filesInfo":   true,
+	"filesGet":    true,
+	"check":       true,
+	"spaceInfo":   true,
+	"accountInfo": true,
+}
+
+// tokenBucket takes tokens from the bucket stored in a hash, it uses the redis time, so all the instances share the same clock.
+// In the strict mode the cost must be available, otherwise the bucket can go into debt while it's not empty.
+// KEYS[1] - bucket key; ARGV[1] - rate per second, ARGV[2] - burst, ARGV[3] - cost, ARGV[4] - strict flag
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local strict = ARGV[4] == "1"
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local allowed = 0
+if (strict and tokens >= cost) or (not strict and tokens > 0) then
+	allowed = 1
+	tokens = tokens - cost
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) * 1000 / rate) + 1000)
+return allowed
+`)
+
+func New() RateLimiter {
+	return new(rateLimiter)
+}
+
+type RateLimiter interface {
+	// Allow takes a request from the budget of the method for the peer and then for the space, peer or space id can be empty
+	Allow(ctx context.Context, method, peerId, spaceId string) error
+	// AllowBytes takes bytes from the traffic budget of the method; pass zero size to only check that the budget is not exhausted
+	AllowBytes(ctx context.Context, method, peerId, spaceId string, size int) error
+	app.Component
+}
+
+type rateLimiter struct {
+	redis redis.UniversalClient
+	conf  atomic.Pointer[Config]
+}
+
+func (r *rateLimiter) Init(a *app.App) (err error) {
+	r.redis = a.MustComponent(redisprovider.CName).(redisprovider.RedisProvider).Redis()
+	conf := a.MustComponent("config").(configSource).GetRateLimit()
+	r.conf.Store(&conf)
+	return
+}
+
+func (r *rateLimiter) Name() (name string) {
+	return CName
+}
+
+func (r *rateLimiter) ReloadableFields() []string {
+	return []string{"rateLimit"}
+}
+
+func (r *rateLimiter) Reload(ctx context.Context, conf app.Component) (err error) {
+	newConf := conf.(configSource).GetRateLimit()
+	r.conf.Store(&newConf)
+	return
+}
+
+func (r *rateLimiter) Allow(ctx context.Context, method, peerId, spaceId string) error {
+	bucket, limit, ok := r.limit(method)
+	if !ok || limit.Rps <= 0 {
+		return nil
+	}
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(limit.Rps))
+	}
+	return r.take(ctx, bucket, peerId, spaceId, limit.Rps, burst, 1, true)
+}
+
+func (r *rateLimiter) AllowBytes(ctx context.Context, method, peerId, spaceId string, size int) error {
+	bucket, limit, ok := r.limit(method)
+	if !ok || limit.BytesPerSec <= 0 {
+		return nil
+	}
+	return r.take(ctx, bucket+"Bytes", peerId, spaceId, float64(limit.BytesPerSec), limit.BytesPerSec, size, false)
+}
+
+// limit returns the bucket name and the budget of the method
+func (r *rateLimiter) limit(method string) (bucket string, limit Limit, ok bool) {
+	conf := r.conf.Load()
+	if !conf.Enabled {
+		return
+	}
+	if limit, ok = conf.Methods[method]; ok {
+		return method, limit, true
+	}
+	if readMethods[method] {
+		return "read", conf.Read, true
+	}
+	return "write", conf.Write, true
+}
+
+// take takes the cost from the buckets of the peer and the space one by one, a bucket rejecting the request stops it.
+// Every peer and every space has its own hash tag, so the buckets are spread over the cluster slots
+func (r *rateLimiter) take(ctx context.Context, bucket, peerId, spaceId string, rate float64, burst, cost int, strict bool) (err error) {
+	var keys []string
+	if peerId != "" {
+		keys = append(keys, "rl:{p:"+peerId+"}."+bucket)
+	}
+	if spaceId != "" {
+		keys = append(keys, "rl:{s:"+spaceId+"}."+bucket)
+	}
+	strictArg := "0"
+	if strict {
+		strictArg = "1"
+	}
+	args := []any{strconv.FormatFloat(rate, 'f', -1, 64), burst, cost, strictArg}
+	for _, key := range keys {
+		allowed, rErr := tokenBucket.Run(ctx, r.redis, []string{key}, args...).Int()
+		if rErr != nil {
+			// don't reject requests when redis is not available, other parts of the request will fail anyway
+			log.WarnCtx(ctx, "rate limit check error", zap.String("key", key), zap.Error(rErr))
+			return nil
+		}
+		if allowed == 0 {
+			return ErrRateLimited
+		}
+	}
+	return nil
+}
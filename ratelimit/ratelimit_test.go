@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anyproto/any-sync/app"
+	"github.com/anyproto/any-sync/net/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/redisprovider/testredisprovider"
+)
+
+var ctx = context.Background()
+
+func TestRateLimiter_Allow(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		fx := newFixture(t, Config{Read: Limit{Rps: 0.001, Burst: 1}})
+		defer fx.finish(t)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, fx.Allow(ctx, "blockGet", "p1", "s1"))
+		}
+	})
+	t.Run("peer and space", func(t *testing.T) {
+		fx := newFixture(t, Config{Enabled: true, Read: Limit{Rps: 0.001, Burst: 2}})
+		defer fx.finish(t)
+		require.NoError(t, fx.Allow(ctx, "blockGet", "p1", "s1"))
+		require.NoError(t, fx.Allow(ctx, "blocksCheck", "p1", "s2"))
+		// read budget of the peer is exhausted
+		assert.ErrorIs(t, fx.Allow(ctx, "filesInfo", "p1", "s3"), ErrRateLimited)
+		// the space has one more request
+		require.NoError(t, fx.Allow(ctx, "blockGet", "p2", "s1"))
+		assert.ErrorIs(t, fx.Allow(ctx, "blockGet", "p3", "s1"), ErrRateLimited)
+		// write budget is not limited
+		require.NoError(t, fx.Allow(ctx, "blockPush", "p1", "s1"))
+	})
+	t.Run("rejected by peer", func(t *testing.T) {
+		fx := newFixture(t, Config{Enabled: true, Read: Limit{Rps: 0.001, Burst: 1}})
+		defer fx.finish(t)
+		require.NoError(t, fx.Allow(ctx, "blockGet", "p1", "s1"))
+		// the peer rejects the request, so the budget of the space is not spent
+		assert.ErrorIs(t, fx.Allow(ctx, "blockGet", "p1", "s2"), ErrRateLimited)
+		require.NoError(t, fx.Allow(ctx, "blockGet", "p2", "s2"))
+	})
+	t.Run("method", func(t *testing.T) {
+		fx := newFixture(t, Config{
+			Enabled: true,
+			Read:    Limit{Rps: 100},
+			Methods: map[string]Limit{"blocksCheck": {Rps: 0.001, Burst: 1}},
+		})
+		defer fx.finish(t)
+		require.NoError(t, fx.Allow(ctx, "blocksCheck", "p1", ""))
+		assert.ErrorIs(t, fx.Allow(ctx, "blocksCheck", "p1", ""), ErrRateLimited)
+		require.NoError(t, fx.Allow(ctx, "blockGet", "p1", ""))
+	})
+}
+
+func TestRateLimiter_AllowBytes(t *testing.T) {
+	fx := newFixture(t, Config{Enabled: true, Write: Limit{BytesPerSec: 1000}})
+	defer fx.finish(t)
+	// the bucket is not empty, so the request goes into debt
+	require.NoError(t, fx.AllowBytes(ctx, "blockPush", "p1", "s1", 1500))
+	assert.ErrorIs(t, fx.AllowBytes(ctx, "blockPush", "p1", "s2", 0), ErrRateLimited)
+	assert.ErrorIs(t, fx.AllowBytes(ctx, "blockPush", "p2", "s1", 0), ErrRateLimited)
+	require.NoError(t, fx.AllowBytes(ctx, "blockPush", "p2", "s2", 100))
+}
+
+func TestAllowSpace(t *testing.T) {
+	fx := newFixture(t, Config{Enabled: true, Read: Limit{Rps: 0.001, Burst: 1}})
+	defer fx.finish(t)
+
+	// a peer without access to the space is charged before the check and never reaches the space
+	pCtx := peer.CtxWithPeerId(ctx, "p1")
+	_, err := AllowPeer(pCtx, fx, "blockGet", 0)
+	require.NoError(t, err)
+	_, err = AllowPeer(pCtx, fx, "blockGet", 0)
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// the member still has the budget of the space
+	mCtx, err := AllowPeer(peer.CtxWithPeerId(ctx, "p2"), fx, "blockGet", 0)
+	require.NoError(t, err)
+	require.NoError(t, AllowSpace(mCtx, fx, "s1"))
+	mCtx, err = AllowPeer(peer.CtxWithPeerId(ctx, "p3"), fx, "blockGet", 0)
+	require.NoError(t, err)
+	assert.ErrorIs(t, AllowSpace(mCtx, fx, "s1"), ErrRateLimited)
+
+	// the space is not charged without the request started by AllowPeer
+	require.NoError(t, AllowSpace(ctx, fx, "s2"))
+	require.NoError(t, AllowSpace(ctx, nil, "s1"))
+}
+
+type testConfig struct {
+	conf Config
+}
+
+func (c *testConfig) Init(a *app.App) (err error) { return }
+func (c *testConfig) Name() string                { return "config" }
+func (c *testConfig) GetRateLimit() Config        { return c.conf }
+
+func newFixture(t *testing.T, conf Config) *fixture {
+	fx := &fixture{
+		rateLimiter: New().(*rateLimiter),
+		a:           new(app.App),
+	}
+	fx.a.Register(testredisprovider.NewTestRedisProviderNum(8)).
+		Register(&testConfig{conf: conf}).
+		Register(fx.rateLimiter)
+	require.NoError(t, fx.a.Start(ctx))
+	return fx
+}
+
+type fixture struct {
+	*rateLimiter
+	a *app.App
+}
+
+func (fx *fixture) finish(t *testing.T) {
+	require.NoError(t, fx.a.Close(ctx))
+}
@@ -0,0 +1,117 @@
+package redisprovider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultUrl = "redis://127.0.0.1:6379/?dial_timeout=3&db=1&read_timeout=6s&max_retries=2"
+
+// credentials can be passed via env to keep them out of the config file
+const (
+	envUsername         = "ANYSYNC_FILENODE_REDIS_USERNAME"
+	envPassword         = "ANYSYNC_FILENODE_REDIS_PASSWORD"
+	envSentinelUsername = "ANYSYNC_FILENODE_REDIS_SENTINEL_USERNAME"
+	envSentinelPassword = "ANYSYNC_FILENODE_REDIS_SENTINEL_PASSWORD"
+)
+
+func newClient(conf Config) (cl redis.UniversalClient, err error) {
+	conf = withEnvCredentials(conf)
+	tlsConf, err := conf.Tls.tlsConfig()
+	if err != nil {
+		return
+	}
+	if conf.Sentinel.MasterName != "" {
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       conf.Sentinel.MasterName,
+			SentinelAddrs:    conf.Sentinel.Addrs,
+			SentinelUsername: conf.Sentinel.Username,
+			SentinelPassword: conf.Sentinel.Password,
+			Username:         conf.Username,
+			Password:         conf.Password,
+			DB:               conf.Sentinel.Db,
+			TLSConfig:        tlsConf,
+		}), nil
+	}
+	if conf.Url == "" {
+		conf.IsCluster = false
+		conf.Url = defaultUrl
+	}
+	if conf.IsCluster {
+		opts, e := redis.ParseClusterURL(conf.Url)
+		if e != nil {
+			return nil, e
+		}
+		setCredentials(conf, &opts.Username, &opts.Password)
+		if tlsConf != nil {
+			opts.TLSConfig = tlsConf
+		}
+		return redis.NewClusterClient(opts), nil
+	}
+	opts, e := redis.ParseURL(conf.Url)
+	if e != nil {
+		return nil, e
+	}
+	setCredentials(conf, &opts.Username, &opts.Password)
+	if tlsConf != nil {
+		opts.TLSConfig = tlsConf
+	}
+	return redis.NewClient(opts), nil
+}
+
+func withEnvCredentials(conf Config) Config {
+	for env, field := range map[string]*string{
+		envUsername:         &conf.Username,
+		envPassword:         &conf.Password,
+		envSentinelUsername: &conf.Sentinel.Username,
+		envSentinelPassword: &conf.Sentinel.Password,
+	} {
+		if val, ok := os.LookupEnv(env); ok {
+			*field = val
+		}
+	}
+	return conf
+}
+
+// setCredentials overrides credentials from the url when they are set explicitly
+func setCredentials(conf Config, username, password *string) {
+	if conf.Username != "" {
+		*username = conf.Username
+	}
+	if conf.Password != "" {
+		*password = conf.Password
+	}
+}
+
+func (c TlsConfig) tlsConfig() (conf *tls.Config, err error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	conf = &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if c.CaFile != "" {
+		caData, e := os.ReadFile(c.CaFile)
+		if e != nil {
+			return nil, e
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CaFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, e := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if e != nil {
+			return nil, e
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return
+}
@@ -5,6 +5,28 @@ type configSource interface {
 }
 
 type Config struct {
-	IsCluster bool   `yaml:"isCluster"`
-	Url       string `yaml:"url"`
+	IsCluster bool           `yaml:"isCluster"`
+	Url       string         `yaml:"url"`
+	Username  string         `yaml:"username"`
+	Password  string         `yaml:"password"`
+	Sentinel  SentinelConfig `yaml:"sentinel"`
+	Tls       TlsConfig      `yaml:"tls"`
+}
+
+// SentinelConfig enables the failover client when MasterName is set, the Url is ignored in this case
+type SentinelConfig struct {
+	MasterName string   `yaml:"masterName"`
+	Addrs      []string `yaml:"addrs"`
+	Username   string   `yaml:"username"`
+	Password   string   `yaml:"password"`
+	Db         int      `yaml:"db"`
+}
+
+type TlsConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	CaFile             string `yaml:"caFile"`
+	CertFile           string `yaml:"certFile"`
+	KeyFile            string `yaml:"keyFile"`
+	ServerName         string `yaml:"serverName"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
 }
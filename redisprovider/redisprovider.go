@@ -24,23 +24,7 @@ type redisProvider struct {
 
 func (r *redisProvider) Init(a *app.App) (err error) {
 	conf := a.MustComponent("config").(configSource).GetRedis()
-	if conf.Url == "" {
-		conf.IsCluster = false
-		conf.Url = "redis://127.0.0.1:6379/?dial_timeout=3&db=1&read_timeout=6s&max_retries=2"
-	}
-	if conf.IsCluster {
-		opts, e := redis.ParseClusterURL(conf.Url)
-		if e != nil {
-			return e
-		}
-		r.redis = redis.NewClusterClient(opts)
-	} else {
-		opts, e := redis.ParseURL(conf.Url)
-		if e != nil {
-			return e
-		}
-		r.redis = redis.NewClient(opts)
-	}
+	r.redis, err = newClient(conf)
 	return
 }
 
@@ -58,6 +42,14 @@ func (r *redisProvider) Run(ctx context.Context) (err error) {
 	return r.redis.Del(ctx, "_test_bf").Err()
 }
 
+// HealthCheck pings redis and makes sure the bloom filter module is still loaded
+func (r *redisProvider) HealthCheck(ctx context.Context) (err error) {
+	if err = r.redis.Ping(ctx).Err(); err != nil {
+		return
+	}
+	return r.redis.BFExists(ctx, "_test_bf", 1).Err()
+}
+
 func (r *redisProvider) Redis() redis.UniversalClient {
 	return r.redis
 }
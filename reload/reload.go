@@ -0,0 +1,28 @@
+// Package reload describes components that are able to apply config changes without restarting the app
+package reload
+
+import (
+	"context"
+
+	"github.com/anyproto/any-sync/app"
+)
+
+// Reloadable is implemented by components that opt in to config hot reload
+type Reloadable interface {
+	app.Component
+	// ReloadableFields returns yaml paths of the config fields the component applies on reload, e.g. "s3Store.maxThreads".
+	// A path also covers all nested fields.
+	ReloadableFields() []string
+	// Reload applies the new config, conf is the config component with the same getters as on Init
+	Reload(ctx context.Context, conf app.Component) error
+}
+
+// Covers reports whether the field path is covered by one of the given reloadable paths
+func Covers(reloadable []string, path string) bool {
+	for _, r := range reloadable {
+		if r == path || (len(path) > len(r) && path[:len(r)] == r && path[len(r)] == '.') {
+			return true
+		}
+	}
+	return false
+}
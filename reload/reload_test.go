@@ -0,0 +1,15 @@
+package reload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCovers(t *testing.T) {
+	fields := []string{"defaultLimit", "s3Store"}
+	assert.True(t, Covers(fields, "defaultLimit"))
+	assert.True(t, Covers(fields, "s3Store.maxThreads"))
+	assert.False(t, Covers(fields, "s3StoreX"))
+	assert.False(t, Covers(fields, "persistTtl"))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/anyproto/any-sync/app"
 	"github.com/anyproto/any-sync/app/logger"
@@ -48,6 +49,11 @@ func (s *fsstore) Run(ctx context.Context) (err error) {
 	return
 }
 
+func (s *fsstore) HealthCheck(ctx context.Context) (err error) {
+	_, err = os.Stat(s.path)
+	return
+}
+
 func (s *fsstore) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
 	val, err := os.ReadFile(filepath.Join(s.path, k.String()))
 	if err != nil {
@@ -106,3 +112,25 @@ func (s *fsstore) IndexGet(ctx context.Context, key string) (value []byte, err e
 func (s *fsstore) IndexPut(ctx context.Context, key string, value []byte) (err error) {
 	return os.WriteFile(filepath.Join(s.path, key), value, 0777)
 }
+
+func (s *fsstore) IndexList(ctx context.Context, prefix string, f func(key string) error) (err error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) {
+			if err = f(e.Name()); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (s *fsstore) IndexDelete(ctx context.Context, key string) (err error) {
+	if err = os.Remove(filepath.Join(s.path, key)); os.IsNotExist(err) {
+		return nil
+	}
+	return
+}
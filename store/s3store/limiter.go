@@ -0,0 +1,31 @@
+package s3store
+
+import "sync"
+
+// limiter limits the count of concurrent s3 requests, the limit can be changed on the fly
+type limiter struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newLimiter(size int) *limiter {
+	return &limiter{ch: make(chan struct{}, size)}
+}
+
+// acquire blocks until a slot is free and returns the func to free it
+func (l *limiter) acquire() (release func()) {
+	l.mu.Lock()
+	ch := l.ch
+	l.mu.Unlock()
+	ch <- struct{}{}
+	return func() { <-ch }
+}
+
+// resize sets the new limit, requests started before keep their slots in the previous limiter
+func (l *limiter) resize(size int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cap(l.ch) != size {
+		l.ch = make(chan struct{}, size)
+	}
+}
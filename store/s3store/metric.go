@@ -0,0 +1,44 @@
+package s3store
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type s3Metrics struct {
+	opDuration  *prometheus.HistogramVec
+	limiterWait *prometheus.HistogramVec
+}
+
+func newS3Metrics() *s3Metrics {
+	return &s3Metrics{
+		opDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "filenode",
+			Subsystem: "s3store",
+			Name:      "op_duration_seconds",
+			Help:      "duration of s3 operations including the limiter wait",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"op"}),
+		limiterWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "filenode",
+			Subsystem: "s3store",
+			Name:      "limiter_wait_seconds",
+			Help:      "time spent waiting for a free s3 thread",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}, []string{"op"}),
+	}
+}
+
+func (m *s3Metrics) register(reg *prometheus.Registry) {
+	reg.MustRegister(m.opDuration, m.limiterWait)
+}
+
+func (m *s3Metrics) observe(op string, total, wait time.Duration) {
+	m.opDuration.WithLabelValues(op).Observe(total.Seconds())
+	m.limiterWait.WithLabelValues(op).Observe(wait.Seconds())
+}
+
+func (m *s3Metrics) observeIndex(op string, total time.Duration) {
+	m.opDuration.WithLabelValues(op).Observe(total.Seconds())
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/anyproto/any-sync/app"
 	"github.com/anyproto/any-sync/app/logger"
 	"github.com/anyproto/any-sync/commonfile/fileblockstore"
+	"github.com/anyproto/any-sync/metric"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -40,8 +41,10 @@ type s3store struct {
 	bucket      *string
 	indexBucket *string
 	client      *s3.S3
-	limiter     chan struct{}
+	limiter     *limiter
 	sess        *session.Session
+	metric      metric.Metric
+	metrics     *s3Metrics
 }
 
 func (s *s3store) Init(a *app.App) (err error) {
@@ -97,7 +100,9 @@ func (s *s3store) Init(a *app.App) (err error) {
 	s.indexBucket = aws.String(conf.IndexBucket)
 
 	s.client = s3.New(s.sess)
-	s.limiter = make(chan struct{}, conf.MaxThreads)
+	s.limiter = newLimiter(conf.MaxThreads)
+	s.metric, _ = a.Component(metric.CName).(metric.Metric)
+	s.metrics = newS3Metrics()
 	return nil
 }
 
@@ -105,15 +110,43 @@ func (s *s3store) Name() (name string) {
 	return CName
 }
 
+func (s *s3store) ReloadableFields() []string {
+	return []string{"s3Store.maxThreads"}
+}
+
+func (s *s3store) Reload(ctx context.Context, conf app.Component) (err error) {
+	maxThreads := conf.(configSource).GetS3Store().MaxThreads
+	if maxThreads <= 0 {
+		maxThreads = 16
+	}
+	s.limiter.resize(maxThreads)
+	log.Info("s3 max threads changed", zap.Int("maxThreads", maxThreads))
+	return
+}
+
 func (s *s3store) Run(ctx context.Context) (err error) {
+	if s.metric != nil {
+		s.metrics.register(s.metric.Registry())
+	}
 	return nil
 }
 
+// HealthCheck makes sure the index bucket is reachable
+func (s *s3store) HealthCheck(ctx context.Context) (err error) {
+	_, err = s.client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: s.indexBucket,
+	})
+	return
+}
+
 func (s *s3store) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
 	st := time.Now()
-	s.limiter <- struct{}{}
-	defer func() { <-s.limiter }()
+	release := s.limiter.acquire()
+	defer release()
 	wait := time.Since(st)
+	defer func() {
+		s.metrics.observe("get", time.Since(st), wait)
+	}()
 	obj, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: s.bucket,
 		Key:    aws.String(k.String()),
@@ -137,6 +170,16 @@ func (s *s3store) Get(ctx context.Context, k cid.Cid) (blocks.Block, error) {
 	return blocks.NewBlockWithCid(data, k)
 }
 
+// PresignGet returns an url to download the block from the bucket directly, the url is signed locally and valid for the ttl
+func (s *s3store) PresignGet(ctx context.Context, k cid.Cid, ttl time.Duration) (url string, err error) {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: s.bucket,
+		Key:    aws.String(k.String()),
+	})
+	req.SetContext(ctx)
+	return req.Presign(ttl)
+}
+
 func (s *s3store) GetMany(ctx context.Context, ks []cid.Cid) <-chan blocks.Block {
 	var res = make(chan blocks.Block)
 	go func() {
@@ -171,9 +214,12 @@ func (s *s3store) GetMany(ctx context.Context, ks []cid.Cid) <-chan blocks.Block
 
 func (s *s3store) Add(ctx context.Context, bs []blocks.Block) error {
 	st := time.Now()
-	s.limiter <- struct{}{}
-	defer func() { <-s.limiter }()
+	release := s.limiter.acquire()
+	defer release()
 	wait := time.Since(st)
+	defer func() {
+		s.metrics.observe("put", time.Since(st), wait)
+	}()
 	var dataLen int
 	for _, b := range bs {
 		data := b.RawData()
@@ -207,13 +253,14 @@ func (s *s3store) DeleteMany(ctx context.Context, ks []cid.Cid) error {
 func (s *s3store) Delete(ctx context.Context, c cid.Cid) error {
 	// TODO: make batch delete
 	st := time.Now()
-	s.limiter <- struct{}{}
+	release := s.limiter.acquire()
 	wait := time.Since(st)
-	defer func() { <-s.limiter }()
+	defer release()
 	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: s.bucket,
 		Key:    aws.String(c.String()),
 	})
+	s.metrics.observe("delete", time.Since(st), wait)
 	log.Debug("s3 delete",
 		zap.Duration("total", time.Since(st)),
 		zap.Duration("wait", wait),
@@ -222,6 +269,10 @@ func (s *s3store) Delete(ctx context.Context, c cid.Cid) error {
 }
 
 func (s *s3store) IndexGet(ctx context.Context, key string) (value []byte, err error) {
+	st := time.Now()
+	defer func() {
+		s.metrics.observeIndex("indexGet", time.Since(st))
+	}()
 	obj, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: s.indexBucket,
 		Key:    aws.String(key),
@@ -238,6 +289,10 @@ func (s *s3store) IndexGet(ctx context.Context, key string) (value []byte, err e
 }
 
 func (s *s3store) IndexPut(ctx context.Context, key string, data []byte) (err error) {
+	st := time.Now()
+	defer func() {
+		s.metrics.observeIndex("indexPut", time.Since(st))
+	}()
 	_, err = s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Key:    aws.String(key),
 		Body:   bytes.NewReader(data),
@@ -246,6 +301,40 @@ func (s *s3store) IndexPut(ctx context.Context, key string, data []byte) (err er
 	return
 }
 
+func (s *s3store) IndexDelete(ctx context.Context, key string) (err error) {
+	st := time.Now()
+	defer func() {
+		s.metrics.observeIndex("indexDelete", time.Since(st))
+	}()
+	_, err = s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Key:    aws.String(key),
+		Bucket: s.indexBucket,
+	})
+	return
+}
+
+func (s *s3store) IndexList(ctx context.Context, prefix string, f func(key string) error) (err error) {
+	st := time.Now()
+	defer func() {
+		s.metrics.observeIndex("indexList", time.Since(st))
+	}()
+	var fErr error
+	if err = s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: s.indexBucket,
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			if fErr = f(aws.StringValue(obj.Key)); fErr != nil {
+				return false
+			}
+		}
+		return true
+	}); err != nil {
+		return
+	}
+	return fErr
+}
+
 func (s *s3store) Close(ctx context.Context) (err error) {
 	return nil
 }
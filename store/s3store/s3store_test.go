@@ -11,6 +11,8 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/anyproto/any-sync-filenode/store"
 )
 
 var ctx = context.Background()
@@ -50,6 +52,22 @@ func TestS3store_GetMany(t *testing.T) {
 	}
 }
 
+func TestS3store_PresignGet(t *testing.T) {
+	a := new(app.App)
+	s := New()
+	a.Register(&config{})
+	a.Register(s)
+	require.NoError(t, a.Start(ctx))
+	defer a.Close(ctx)
+
+	b := blocks.NewBlock([]byte("presign"))
+	url, err := s.(store.Presigner).PresignGet(ctx, b.Cid(), time.Minute*5)
+	require.NoError(t, err)
+	assert.Contains(t, url, b.Cid().String())
+	assert.Contains(t, url, "X-Amz-Expires=300")
+	assert.Contains(t, url, "X-Amz-Signature=")
+}
+
 type config struct {
 }
 
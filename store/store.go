@@ -3,6 +3,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/anyproto/any-sync/app"
 	"github.com/anyproto/any-sync/commonfile/fileblockstore"
@@ -15,5 +16,13 @@ type Store interface {
 
 	IndexGet(ctx context.Context, key string) (value []byte, err error)
 	IndexPut(ctx context.Context, key string, value []byte) (err error)
+	IndexDelete(ctx context.Context, key string) (err error)
+	// IndexList calls f for every key with the given prefix, listing stops on the first error of f
+	IndexList(ctx context.Context, prefix string, f func(key string) error) (err error)
 	app.Component
 }
+
+// Presigner is implemented by stores that are able to give clients a direct temporary link to the block
+type Presigner interface {
+	PresignGet(ctx context.Context, k cid.Cid, ttl time.Duration) (url string, err error)
+}